@@ -1,6 +1,9 @@
 package agent
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 type Agent struct {
 	mu       sync.RWMutex
@@ -20,6 +23,19 @@ func (a *Agent) State() State {
 	return a.state
 }
 
+func (a *Agent) SetTools(tools []Tool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state.Tools = tools
+}
+
+// SetMessages replaces the conversation, for example to rewind it.
+func (a *Agent) SetMessages(messages []any) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state.Messages = messages
+}
+
 func (a *Agent) Subscribe(handler func(Event)) (unsubscribe func()) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -35,6 +51,9 @@ func (a *Agent) Subscribe(handler func(Event)) (unsubscribe func()) {
 }
 
 func (a *Agent) emit(event Event) {
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixMilli()
+	}
 	a.mu.RLock()
 	handlers := append([]func(Event){}, a.handlers...)
 	a.mu.RUnlock()
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/zahlmann/phi/ai/model"
+)
+
+type ToolCallAction string
+
+const (
+	ToolCallAllow ToolCallAction = "allow"
+	ToolCallDeny  ToolCallAction = "deny"
+	ToolCallAsk   ToolCallAction = "ask"
+)
+
+// ToolCallDecision is returned by a BeforeToolCallHook. Message is sent back to
+// the model when the call is denied; non-nil Arguments replace the call arguments.
+type ToolCallDecision struct {
+	Action    ToolCallAction `json:"action"`
+	Message   string         `json:"message,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// BeforeToolCallHook runs before a tool executes and may block, e.g. while
+// waiting for a human to approve the call.
+type BeforeToolCallHook func(ctx context.Context, call model.ToolCallContent) (ToolCallDecision, error)
+
+type AfterToolCallHook func(ctx context.Context, call model.ToolCallContent, result ToolResult, isError bool) (ToolResult, error)
+
+type ApprovalRequest struct {
+	ID     string                `json:"id"`
+	Call   model.ToolCallContent `json:"call"`
+	Reason string                `json:"reason,omitempty"`
+}
+
+type pendingApproval struct {
+	request  ApprovalRequest
+	decision chan ToolCallDecision
+}
+
+// Approvals parks tool calls until Approve or Deny is called for them.
+type Approvals struct {
+	mu        sync.Mutex
+	pending   map[string]*pendingApproval
+	onRequest func(ApprovalRequest)
+}
+
+func NewApprovals(onRequest func(ApprovalRequest)) *Approvals {
+	return &Approvals{
+		pending:   map[string]*pendingApproval{},
+		onRequest: onRequest,
+	}
+}
+
+func (a *Approvals) Request(ctx context.Context, call model.ToolCallContent, reason string) (ToolCallDecision, error) {
+	id := call.ID
+	if id == "" {
+		return ToolCallDecision{}, errors.New("tool call id is required for approval")
+	}
+	item := &pendingApproval{
+		request:  ApprovalRequest{ID: id, Call: call, Reason: reason},
+		decision: make(chan ToolCallDecision, 1),
+	}
+
+	a.mu.Lock()
+	if _, exists := a.pending[id]; exists {
+		a.mu.Unlock()
+		return ToolCallDecision{}, fmt.Errorf("approval already pending for tool call %s", id)
+	}
+	a.pending[id] = item
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, id)
+		a.mu.Unlock()
+	}()
+
+	if a.onRequest != nil {
+		a.onRequest(item.request)
+	}
+	select {
+	case decision := <-item.decision:
+		return decision, nil
+	case <-ctx.Done():
+		return ToolCallDecision{}, ctx.Err()
+	}
+}
+
+func (a *Approvals) Pending() []ApprovalRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]ApprovalRequest, 0, len(a.pending))
+	for _, item := range a.pending {
+		out = append(out, item.request)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (a *Approvals) Approve(id string) error {
+	return a.Resolve(id, ToolCallDecision{Action: ToolCallAllow})
+}
+
+func (a *Approvals) Deny(id, message string) error {
+	return a.Resolve(id, ToolCallDecision{Action: ToolCallDeny, Message: message})
+}
+
+func (a *Approvals) Resolve(id string, decision ToolCallDecision) error {
+	if decision.Action == ToolCallAsk {
+		return errors.New("approval decision must allow or deny")
+	}
+	a.mu.Lock()
+	item, ok := a.pending[id]
+	a.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no pending approval for tool call %s", id)
+	}
+	select {
+	case item.decision <- decision:
+		return nil
+	default:
+		return fmt.Errorf("approval for tool call %s was already resolved", id)
+	}
+}
+
+func (a *Approvals) Hook() BeforeToolCallHook {
+	return func(ctx context.Context, call model.ToolCallContent) (ToolCallDecision, error) {
+		return a.Request(ctx, call, "")
+	}
+}
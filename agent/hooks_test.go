@@ -0,0 +1,279 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zahlmann/phi/ai/model"
+	"github.com/zahlmann/phi/ai/provider"
+	"github.com/zahlmann/phi/ai/stream"
+)
+
+func toolCallClient(name string, args map[string]any) provider.MockClient {
+	return provider.MockClient{
+		Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+			if !conversationHasRole(conversation.Messages, model.RoleToolResult) {
+				return toolCallStream("call_1", name, args, m), nil
+			}
+			return textStream("done", m), nil
+		},
+	}
+}
+
+func lastToolResultText(t *testing.T, a *Agent) string {
+	t.Helper()
+	for _, message := range toModelMessages(a.State().Messages) {
+		if message.Role == model.RoleToolResult {
+			return extractTextFromContent(message.ContentRaw)
+		}
+	}
+	t.Fatal("missing tool result message")
+	return ""
+}
+
+func TestRunTurnBeforeToolCallDenies(t *testing.T) {
+	tool := &testTool{name: "bash", resultText: "ran"}
+	a := newTestAgent([]Tool{tool})
+	var denied []Event
+	a.Subscribe(func(ev Event) {
+		if ev.Type == EventToolCallDenied {
+			denied = append(denied, ev)
+		}
+	})
+
+	_, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client: toolCallClient("bash", map[string]any{"command": "rm -rf /"}),
+		BeforeToolCall: func(ctx context.Context, call model.ToolCallContent) (ToolCallDecision, error) {
+			return ToolCallDecision{Action: ToolCallDeny, Message: "destructive command"}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if tool.calls != 0 {
+		t.Fatalf("denied tool must not run, got %d calls", tool.calls)
+	}
+	if len(denied) != 1 || denied[0].ToolCallID != "call_1" || !denied[0].IsError {
+		t.Fatalf("unexpected denied events: %#v", denied)
+	}
+	if got := lastToolResultText(t, a); got != "destructive command" {
+		t.Fatalf("unexpected tool result: %q", got)
+	}
+}
+
+func TestRunTurnBeforeToolCallErrorBlocksCall(t *testing.T) {
+	tool := &testTool{name: "bash", resultText: "ran"}
+	a := newTestAgent([]Tool{tool})
+	_, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client: toolCallClient("bash", map[string]any{"command": "ls"}),
+		BeforeToolCall: func(ctx context.Context, call model.ToolCallContent) (ToolCallDecision, error) {
+			return ToolCallDecision{}, errors.New("approval unavailable")
+		},
+	})
+	if err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if tool.calls != 0 {
+		t.Fatalf("blocked tool must not run, got %d calls", tool.calls)
+	}
+	if got := lastToolResultText(t, a); !strings.Contains(got, "approval unavailable") {
+		t.Fatalf("unexpected tool result: %q", got)
+	}
+}
+
+func TestRunTurnBeforeToolCallAsk(t *testing.T) {
+	ask := func(ctx context.Context, call model.ToolCallContent) (ToolCallDecision, error) {
+		return ToolCallDecision{Action: ToolCallAsk, Message: "runs a command"}, nil
+	}
+
+	tool := &testTool{name: "bash", resultText: "ran"}
+	a := newTestAgent([]Tool{tool})
+	if _, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client:         toolCallClient("bash", map[string]any{"command": "ls"}),
+		BeforeToolCall: ask,
+	}); err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if tool.calls != 0 {
+		t.Fatalf("unapproved tool must not run, got %d calls", tool.calls)
+	}
+	if got := lastToolResultText(t, a); !strings.Contains(got, "no approver is configured") {
+		t.Fatalf("unexpected tool result: %q", got)
+	}
+
+	tool = &testTool{name: "bash", resultText: "ran"}
+	a = newTestAgent([]Tool{tool})
+	var approvals *Approvals
+	approvals = NewApprovals(func(request ApprovalRequest) {
+		if request.Reason != "runs a command" {
+			t.Errorf("unexpected approval reason: %q", request.Reason)
+		}
+		go approvals.Approve(request.ID)
+	})
+	if _, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client:         toolCallClient("bash", map[string]any{"command": "ls"}),
+		BeforeToolCall: ask,
+		Approvals:      approvals,
+	}); err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if tool.calls != 1 {
+		t.Fatalf("approved tool should run once, got %d calls", tool.calls)
+	}
+}
+
+func TestRunTurnToolCallHooksRewriteArgumentsAndResult(t *testing.T) {
+	tool := &testTool{name: "write", resultText: "written"}
+	a := newTestAgent([]Tool{tool})
+	_, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client: toolCallClient("write", map[string]any{"path": "/etc/passwd"}),
+		BeforeToolCall: func(ctx context.Context, call model.ToolCallContent) (ToolCallDecision, error) {
+			return ToolCallDecision{Action: ToolCallAllow, Arguments: map[string]any{"path": "sandbox/passwd"}}, nil
+		},
+		AfterToolCall: func(ctx context.Context, call model.ToolCallContent, result ToolResult, isError bool) (ToolResult, error) {
+			if call.Arguments["path"] != "sandbox/passwd" {
+				t.Fatalf("after hook saw original arguments: %#v", call.Arguments)
+			}
+			result.Content = append(result.Content, model.TextContent{Type: model.ContentText, Text: "audited"})
+			return result, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if tool.lastArgs["path"] != "sandbox/passwd" {
+		t.Fatalf("expected rewritten arguments, got %#v", tool.lastArgs)
+	}
+	if got := lastToolResultText(t, a); got != "written\naudited" {
+		t.Fatalf("unexpected tool result: %q", got)
+	}
+}
+
+func TestApprovalsHookWaitsForDecision(t *testing.T) {
+	tool := &testTool{name: "bash", resultText: "ran"}
+	a := newTestAgent([]Tool{tool})
+	var approvals *Approvals
+	approvals = NewApprovals(func(req ApprovalRequest) {
+		go func() {
+			if err := approvals.Approve(req.ID); err != nil {
+				t.Errorf("approve failed: %v", err)
+			}
+		}()
+	})
+
+	_, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client:         toolCallClient("bash", map[string]any{"command": "ls"}),
+		BeforeToolCall: approvals.Hook(),
+	})
+	if err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if tool.calls != 1 {
+		t.Fatalf("approved tool should run once, got %d", tool.calls)
+	}
+	if len(approvals.Pending()) != 0 {
+		t.Fatalf("expected no pending approvals, got %#v", approvals.Pending())
+	}
+}
+
+func TestApprovalsDenyAndCancel(t *testing.T) {
+	approvals := NewApprovals(nil)
+	call := model.ToolCallContent{ID: "call_1", Name: "bash"}
+
+	done := make(chan ToolCallDecision, 1)
+	go func() {
+		decision, _ := approvals.Request(context.Background(), call, "")
+		done <- decision
+	}()
+	waitUntil(t, time.Second, func() bool { return len(approvals.Pending()) == 1 })
+	if err := approvals.Deny("call_1", "no"); err != nil {
+		t.Fatalf("deny failed: %v", err)
+	}
+	if decision := <-done; decision.Action != ToolCallDeny || decision.Message != "no" {
+		t.Fatalf("unexpected decision: %#v", decision)
+	}
+	if err := approvals.Approve("call_1"); err == nil {
+		t.Fatal("expected error for resolved approval")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := approvals.Request(ctx, call, ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+}
+
+func TestToolPolicyEvaluate(t *testing.T) {
+	policy := &ToolPolicy{
+		Default: ToolCallAsk,
+		Rules: []ToolPolicyRule{
+			{Tool: "bash", Argument: "command", Regex: `\brm\s+-rf\b`, Action: ToolCallDeny},
+			{Tool: "write", Argument: "path", Glob: "src/*.go", Action: ToolCallAllow},
+			{Tool: "read", Action: ToolCallAllow},
+		},
+	}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want ToolCallAction
+	}{
+		{name: "bash", args: map[string]any{"command": "rm -rf build"}, want: ToolCallDeny},
+		{name: "bash", args: map[string]any{"command": "ls"}, want: ToolCallAsk},
+		{name: "write", args: map[string]any{"path": "src/pkg/main.go"}, want: ToolCallAllow},
+		{name: "write", args: map[string]any{"path": "README.md"}, want: ToolCallAsk},
+		{name: "read", args: map[string]any{"path": "/etc/hosts"}, want: ToolCallAllow},
+	}
+	for _, tc := range tests {
+		decision, err := policy.Evaluate(model.ToolCallContent{Name: tc.name, Arguments: tc.args})
+		if err != nil {
+			t.Fatalf("evaluate failed: %v", err)
+		}
+		if decision.Action != tc.want {
+			t.Fatalf("%s %#v: expected %s, got %s", tc.name, tc.args, tc.want, decision.Action)
+		}
+		if decision.Action == ToolCallDeny && decision.Message == "" {
+			t.Fatal("expected deny message")
+		}
+	}
+
+	hook := policy.Hook(nil)
+	decision, err := hook(context.Background(), model.ToolCallContent{Name: "bash", Arguments: map[string]any{"command": "ls"}})
+	if err != nil {
+		t.Fatalf("hook failed: %v", err)
+	}
+	if decision.Action != ToolCallAsk {
+		t.Fatalf("ask without approvals should be passed on, got %#v", decision)
+	}
+}
+
+func TestLoadToolPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(path, []byte(`{"default":"deny","rules":[{"tool":"read","action":"allow"}]}`), 0o644); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+	policy, err := LoadToolPolicy(path)
+	if err != nil {
+		t.Fatalf("load policy failed: %v", err)
+	}
+	if policy.Default != ToolCallDeny || len(policy.Rules) != 1 {
+		t.Fatalf("unexpected policy: %#v", policy)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"rules":[{"tool":"bash","action":"maybe"}]}`), 0o644); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+	if _, err := LoadToolPolicy(invalid); err == nil {
+		t.Fatal("expected invalid action error")
+	}
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/zahlmann/phi/ai/model"
+)
+
+// ToolPolicy decides tool calls from declarative rules; the first matching
+// rule wins and Default (allow when empty) applies otherwise. Validate
+// compiles the rules; call it again after changing them.
+type ToolPolicy struct {
+	Default ToolCallAction   `json:"default,omitempty"`
+	Rules   []ToolPolicyRule `json:"rules"`
+
+	matchers []ruleMatcher
+}
+
+// ToolPolicyRule matches a tool name glob and, optionally, one argument by
+// glob or regex. In globs `*` matches any run of characters, including `/`.
+type ToolPolicyRule struct {
+	Tool     string         `json:"tool,omitempty"`
+	Argument string         `json:"argument,omitempty"`
+	Glob     string         `json:"glob,omitempty"`
+	Regex    string         `json:"regex,omitempty"`
+	Action   ToolCallAction `json:"action"`
+	Message  string         `json:"message,omitempty"`
+}
+
+func LoadToolPolicy(path string) (*ToolPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy ToolPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("parse tool policy %s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (p *ToolPolicy) Validate() error {
+	if err := validatePolicyAction(p.Default, true); err != nil {
+		return fmt.Errorf("tool policy default: %w", err)
+	}
+	matchers, err := p.compile()
+	if err != nil {
+		return err
+	}
+	p.matchers = matchers
+	return nil
+}
+
+func (p *ToolPolicy) compile() ([]ruleMatcher, error) {
+	matchers := make([]ruleMatcher, 0, len(p.Rules))
+	for i, rule := range p.Rules {
+		if err := validatePolicyAction(rule.Action, false); err != nil {
+			return nil, fmt.Errorf("tool policy rule %d: %w", i, err)
+		}
+		if (rule.Glob != "" || rule.Regex != "") && rule.Argument == "" {
+			return nil, fmt.Errorf("tool policy rule %d: argument is required with glob or regex", i)
+		}
+		matcher, err := rule.compile()
+		if err != nil {
+			return nil, fmt.Errorf("tool policy rule %d: %w", i, err)
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+func validatePolicyAction(action ToolCallAction, allowEmpty bool) error {
+	switch action {
+	case ToolCallAllow, ToolCallDeny, ToolCallAsk:
+		return nil
+	case "":
+		if allowEmpty {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action %q", action)
+}
+
+func (p *ToolPolicy) Evaluate(call model.ToolCallContent) (ToolCallDecision, error) {
+	matchers := p.matchers
+	if len(matchers) != len(p.Rules) {
+		// Validate was not called; compile without keeping the result.
+		var err error
+		if matchers, err = p.compile(); err != nil {
+			return ToolCallDecision{}, err
+		}
+	}
+	for i, rule := range p.Rules {
+		if !matchers[i].matches(rule.Argument, call) {
+			continue
+		}
+		message := rule.Message
+		if message == "" && rule.Action == ToolCallDeny {
+			message = fmt.Sprintf("Tool call %s denied by policy", call.Name)
+		}
+		return ToolCallDecision{Action: rule.Action, Message: message}, nil
+	}
+	action := p.Default
+	if action == "" {
+		action = ToolCallAllow
+	}
+	decision := ToolCallDecision{Action: action}
+	if action == ToolCallDeny {
+		decision.Message = fmt.Sprintf("Tool call %s denied by policy", call.Name)
+	}
+	return decision, nil
+}
+
+// Hook evaluates the policy for each call and sends "ask" decisions to
+// approvals; without approvals they are returned as they are, for
+// RunnerOptions.Approvals to decide.
+func (p *ToolPolicy) Hook(approvals *Approvals) BeforeToolCallHook {
+	return func(ctx context.Context, call model.ToolCallContent) (ToolCallDecision, error) {
+		decision, err := p.Evaluate(call)
+		if err != nil {
+			return ToolCallDecision{}, err
+		}
+		if decision.Action != ToolCallAsk || approvals == nil {
+			return decision, nil
+		}
+		return approvals.Request(ctx, call, decision.Message)
+	}
+}
+
+// ruleMatcher is the compiled form of a ToolPolicyRule's patterns.
+type ruleMatcher struct {
+	tool  *regexp.Regexp
+	glob  *regexp.Regexp
+	regex *regexp.Regexp
+}
+
+func (r ToolPolicyRule) compile() (ruleMatcher, error) {
+	var m ruleMatcher
+	if r.Tool != "" {
+		m.tool = globRegexp(r.Tool)
+	}
+	if r.Glob != "" {
+		m.glob = globRegexp(r.Glob)
+	}
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return m, fmt.Errorf("invalid regex: %w", err)
+		}
+		m.regex = re
+	}
+	return m, nil
+}
+
+func (m ruleMatcher) matches(argument string, call model.ToolCallContent) bool {
+	if m.tool != nil && !m.tool.MatchString(call.Name) {
+		return false
+	}
+	if argument == "" {
+		return true
+	}
+	raw, ok := call.Arguments[argument]
+	if !ok {
+		return false
+	}
+	value := policyArgumentString(raw)
+	if m.glob != nil && !m.glob.MatchString(value) {
+		return false
+	}
+	return m.regex == nil || m.regex.MatchString(value)
+}
+
+func policyArgumentString(raw any) string {
+	if s, ok := raw.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Sprint(raw)
+	}
+	return string(data)
+}
+
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString("(?s:.*)")
+		case '?':
+			b.WriteString("(?s:.)")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
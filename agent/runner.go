@@ -4,22 +4,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/zahlmann/phi/ai/jsonschema"
 	"github.com/zahlmann/phi/ai/model"
 	"github.com/zahlmann/phi/ai/provider"
 	"github.com/zahlmann/phi/ai/stream"
 )
 
 type RunnerOptions struct {
-	Client        provider.Client
-	AuthMode      provider.AuthMode
-	APIKey        string
-	AccessToken   string
-	AccountID     string
-	SessionID     string
-	Tools         []Tool
-	MaxToolRounds int
+	Client         provider.Client
+	AuthMode       provider.AuthMode
+	APIKey         string
+	AccessToken    string
+	AccountID      string
+	SessionID      string
+	Tools          []Tool
+	MaxToolRounds  int
+	ResponseFormat *provider.ResponseFormat
+	// ToolChoice applies until the model calls a tool; a forced choice then
+	// reverts to auto so the turn can end with a text answer.
+	ToolChoice provider.ToolChoice
+	// ToolChoiceForRound, when set, picks the tool choice of every round
+	// (starting at 1) instead, for example to force a submit_result tool on
+	// the last round. A tool forced on round MaxToolRounds ends the turn once
+	// it has run.
+	ToolChoiceForRound func(round int) provider.ToolChoice
+	BeforeToolCall     BeforeToolCallHook
+	// Approvals decides calls that BeforeToolCall answers with ask; without
+	// it they are blocked with an error.
+	Approvals     *Approvals
+	AfterToolCall AfterToolCallHook
 }
 
 func (a *Agent) RunTurn(ctx context.Context, options RunnerOptions) (*model.AssistantMessage, error) {
@@ -31,10 +47,6 @@ func (a *Agent) RunTurn(ctx context.Context, options RunnerOptions) (*model.Assi
 		return nil, errors.New("model is required")
 	}
 
-	tools := options.Tools
-	if len(tools) == 0 {
-		tools = state.Tools
-	}
 	maxRounds := options.MaxToolRounds
 	if maxRounds <= 0 {
 		maxRounds = 8
@@ -45,7 +57,17 @@ func (a *Agent) RunTurn(ctx context.Context, options RunnerOptions) (*model.Assi
 	defer a.setStreaming(false)
 
 	var lastAssistant *model.AssistantMessage
-	for round := 0; round < maxRounds; round++ {
+	toolChoice := options.ToolChoice
+	for round := 1; round <= maxRounds; round++ {
+		a.emit(Event{Type: EventRoundStart, Round: round})
+		if options.ToolChoiceForRound != nil {
+			toolChoice = options.ToolChoiceForRound(round)
+		}
+		// Tools are resolved per round so SetTools applies in the middle of a turn.
+		tools := options.Tools
+		if len(tools) == 0 {
+			tools = a.State().Tools
+		}
 		conversation := model.Context{
 			SystemPrompt: state.SystemPrompt,
 			Messages:     toModelMessages(a.State().Messages),
@@ -53,30 +75,37 @@ func (a *Agent) RunTurn(ctx context.Context, options RunnerOptions) (*model.Assi
 		}
 
 		evStream, err := options.Client.Stream(ctx, *state.Model, conversation, provider.StreamOptions{
-			AuthMode:    options.AuthMode,
-			APIKey:      options.APIKey,
-			AccessToken: options.AccessToken,
-			AccountID:   options.AccountID,
-			SessionID:   options.SessionID,
+			AuthMode:       options.AuthMode,
+			APIKey:         options.APIKey,
+			AccessToken:    options.AccessToken,
+			AccountID:      options.AccountID,
+			SessionID:      options.SessionID,
+			ResponseFormat: options.ResponseFormat,
+			ToolChoice:     toolChoice,
 		})
 		if err != nil {
+			a.emitError(round, err)
 			return nil, err
 		}
 
+		streamFailed := false
 		for {
 			ev, recvErr := evStream.Recv()
 			if recvErr != nil {
 				break
 			}
-			a.emit(Event{
-				Type:    mapStreamEventType(ev.Type),
-				Message: ev,
-			})
+			if mapped, ok := mapStreamEvent(ev, round); ok {
+				streamFailed = streamFailed || mapped.Type == EventError
+				a.emit(mapped)
+			}
 		}
 
 		result, err := evStream.Result()
 		_ = evStream.Close()
 		if err != nil {
+			if !streamFailed {
+				a.emitError(round, err)
+			}
 			return nil, err
 		}
 		if result.Timestamp == 0 {
@@ -84,74 +113,186 @@ func (a *Agent) RunTurn(ctx context.Context, options RunnerOptions) (*model.Assi
 		}
 
 		a.appendMessage(*result)
-		a.emit(Event{Type: EventMessageEnd, Message: *result})
+		usage := result.Usage
+		a.emit(Event{Type: EventMessageEnd, Round: round, Message: *result, Usage: &usage})
 		lastAssistant = result
 
 		toolCalls := extractToolCalls(result.ContentRaw)
 		if len(toolCalls) == 0 || result.StopReason != model.StopReasonToolUse {
+			a.emit(Event{Type: EventRoundEnd, Round: round, Usage: &usage})
 			a.emit(Event{Type: EventTurnEnd})
 			return result, nil
 		}
+		// A forced tool choice is satisfied once the model calls a tool; keeping it
+		// would make every following round call a tool again.
+		forced := toolChoice.IsForced()
+		if forced {
+			toolChoice = provider.ToolChoiceAuto
+		}
 
 		for _, call := range toolCalls {
-			toolResultMessage, hasError := executeToolCall(tools, call, a.emit)
+			toolResultMessage := a.runToolCall(ctx, round, tools, call, options)
 			a.appendMessage(toolResultMessage)
+		}
+		a.emit(Event{Type: EventRoundEnd, Round: round, Usage: &usage})
+		// A tool forced on the last round, such as submit_result, is the answer.
+		if forced && round == maxRounds {
+			a.emit(Event{Type: EventTurnEnd})
+			return result, nil
+		}
+	}
+
+	err := errors.New("max tool rounds reached without final assistant response")
+	if lastAssistant == nil {
+		err = errors.New("max tool rounds reached without assistant response")
+	}
+	a.emitError(maxRounds, err)
+	a.emit(Event{Type: EventTurnEnd})
+	return lastAssistant, err
+}
+
+func (a *Agent) emitError(round int, err error) {
+	a.emit(Event{
+		Type:    EventError,
+		Round:   round,
+		Error:   err.Error(),
+		IsError: true,
+	})
+}
+
+func (a *Agent) runToolCall(
+	ctx context.Context,
+	round int,
+	tools []Tool,
+	call model.ToolCallContent,
+	options RunnerOptions,
+) model.Message {
+	if options.BeforeToolCall != nil {
+		decision, err := options.BeforeToolCall(ctx, call)
+		if err == nil && decision.Action == ToolCallAsk {
+			if options.Approvals != nil {
+				decision, err = options.Approvals.Request(ctx, call, decision.Message)
+			} else {
+				err = fmt.Errorf("%s needs approval but no approver is configured", call.Name)
+			}
+		}
+		if err != nil || decision.Action == ToolCallDeny {
+			message := strings.TrimSpace(decision.Message)
+			switch {
+			case err != nil:
+				message = "Tool call blocked: " + err.Error()
+			case message == "":
+				message = "Tool call denied: " + call.Name
+			}
+			toolResultMessage, toolResult, _ := toolErrorResult(call, message)
 			a.emit(Event{
-				Type:       EventToolExecutionEnd,
+				Type:       EventToolCallDenied,
+				Round:      round,
 				ToolName:   call.Name,
 				ToolCallID: call.ID,
-				IsError:    hasError,
+				ToolArgs:   call.Arguments,
+				ToolResult: &toolResult,
+				Error:      message,
+				IsError:    true,
 				Message:    toolResultMessage,
 			})
+			return toolResultMessage
+		}
+		if decision.Arguments != nil {
+			call.Arguments = decision.Arguments
 		}
 	}
 
-	a.emit(Event{Type: EventTurnEnd})
-	if lastAssistant != nil {
-		return lastAssistant, fmt.Errorf("max tool rounds reached without final assistant response")
-	}
-	return nil, fmt.Errorf("max tool rounds reached without assistant response")
-}
-
-func executeToolCall(tools []Tool, call model.ToolCallContent, emit func(Event)) (model.Message, bool) {
-	emit(Event{
+	a.emit(Event{
 		Type:       EventToolExecutionStart,
+		Round:      round,
 		ToolName:   call.Name,
 		ToolCallID: call.ID,
+		ToolArgs:   call.Arguments,
 	})
+	onUpdate := func(partial ToolResult) {
+		a.emit(Event{
+			Type:       EventToolExecutionUpdate,
+			Round:      round,
+			ToolName:   call.Name,
+			ToolCallID: call.ID,
+			ToolArgs:   call.Arguments,
+			ToolResult: &partial,
+		})
+	}
+	toolResultMessage, toolResult, hasError := executeToolCall(ctx, tools, call, onUpdate)
+	if options.AfterToolCall != nil {
+		updated, err := options.AfterToolCall(ctx, call, toolResult, hasError)
+		if err != nil {
+			toolResultMessage, toolResult, hasError = toolErrorResult(call, "Tool hook error: "+err.Error())
+		} else {
+			toolResult = updated
+			toolResultMessage.ContentRaw = toolResultContent(updated)
+		}
+	}
+	a.emit(Event{
+		Type:       EventToolExecutionEnd,
+		Round:      round,
+		ToolName:   call.Name,
+		ToolCallID: call.ID,
+		ToolArgs:   call.Arguments,
+		ToolResult: &toolResult,
+		IsError:    hasError,
+		Message:    toolResultMessage,
+	})
+	return toolResultMessage
+}
 
+func executeToolCall(ctx context.Context, tools []Tool, call model.ToolCallContent, onUpdate ToolUpdateFunc) (model.Message, ToolResult, bool) {
 	tool := findTool(tools, call.Name)
 	if tool == nil {
-		return model.Message{
-			Role:       model.RoleToolResult,
-			ToolCallID: call.ID,
-			ToolName:   call.Name,
-			ContentRaw: []any{
-				model.TextContent{
-					Type: model.ContentText,
-					Text: "Tool not found: " + call.Name,
-				},
-			},
-			Timestamp: time.Now().UnixMilli(),
-		}, true
+		return toolErrorResult(call, "Tool not found: "+call.Name)
 	}
 
-	result, err := tool.Execute(call.ID, call.Arguments)
+	args, err := PrepareToolArguments(tool, call.Arguments)
 	if err != nil {
-		return model.Message{
-			Role:       model.RoleToolResult,
-			ToolCallID: call.ID,
-			ToolName:   call.Name,
-			ContentRaw: []any{
-				model.TextContent{
-					Type: model.ContentText,
-					Text: "Tool execution error: " + err.Error(),
-				},
-			},
-			Timestamp: time.Now().UnixMilli(),
-		}, true
+		return toolErrorResult(call, "Invalid arguments for "+call.Name+": "+err.Error())
+	}
+
+	var result ToolResult
+	if streamingTool, ok := tool.(StreamingTool); ok {
+		result, err = streamingTool.ExecuteStreaming(ctx, call.ID, args, onUpdate)
+	} else if contextTool, ok := tool.(ContextTool); ok {
+		result, err = contextTool.ExecuteContext(ctx, call.ID, args)
+	} else {
+		result, err = tool.Execute(call.ID, args)
+	}
+	if err != nil {
+		return toolErrorResult(call, "Tool execution error: "+err.Error())
 	}
 
+	return model.Message{
+		Role:       model.RoleToolResult,
+		ToolCallID: call.ID,
+		ToolName:   call.Name,
+		ContentRaw: toolResultContent(result),
+		Timestamp:  time.Now().UnixMilli(),
+	}, result, false
+}
+
+// PrepareToolArguments coerces arguments to the tool's parameter schema and
+// validates them, so tools only run with arguments that match what they declared.
+func PrepareToolArguments(tool Tool, args map[string]any) (map[string]any, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	schema := tool.Parameters()
+	coerced, ok := jsonschema.Coerce(schema, args).(map[string]any)
+	if !ok {
+		coerced = args
+	}
+	if err := jsonschema.Validate(schema, coerced); err != nil {
+		return nil, err
+	}
+	return coerced, nil
+}
+
+func toolResultContent(result ToolResult) []any {
 	content := make([]any, 0, len(result.Content))
 	for _, item := range result.Content {
 		content = append(content, item)
@@ -162,14 +303,23 @@ func executeToolCall(tools []Tool, call model.ToolCallContent, emit func(Event))
 			Text: "(tool returned no output)",
 		})
 	}
+	return content
+}
 
+func toolErrorResult(call model.ToolCallContent, text string) (model.Message, ToolResult, bool) {
+	content := []any{
+		model.TextContent{
+			Type: model.ContentText,
+			Text: text,
+		},
+	}
 	return model.Message{
 		Role:       model.RoleToolResult,
 		ToolCallID: call.ID,
 		ToolName:   call.Name,
 		ContentRaw: content,
 		Timestamp:  time.Now().UnixMilli(),
-	}, false
+	}, ToolResult{Content: content}, true
 }
 
 func findTool(tools []Tool, name string) Tool {
@@ -238,21 +388,36 @@ func (a *Agent) setStreaming(value bool) {
 	a.state.IsStreaming = value
 }
 
-func mapStreamEventType(t stream.EventType) EventType {
-	switch t {
+func mapStreamEvent(ev stream.Event, round int) (Event, bool) {
+	out := Event{Round: round, StreamEvent: &ev}
+	switch ev.Type {
 	case stream.EventStart:
-		return EventMessageStart
+		out.Type = EventMessageStart
 	case stream.EventTextDelta, stream.EventThinkingDelta:
-		return EventMessageUpdate
+		out.Type = EventMessageUpdate
+		out.Delta = ev.Delta
+	case stream.EventToolCallDelta:
+		out.Type = EventMessageUpdate
+		out.ToolArgsDelta = ev.Delta
+		out.ToolName = ev.ToolName
+		out.ToolCallID = ev.ToolCallID
+		out.ToolArgs = ev.Arguments
 	case stream.EventToolCall:
-		return EventToolExecutionStart
-	case stream.EventDone:
-		return EventMessageEnd
+		out.Type = EventToolCallRequested
+		out.ToolName = ev.ToolName
+		out.ToolCallID = ev.ToolCallID
+		out.ToolArgs = ev.Arguments
 	case stream.EventError:
-		return EventToolExecutionEnd
+		out.Type = EventError
+		out.Error = ev.Error
+		out.IsError = true
+	case stream.EventDone:
+		// The final message_end event carries the completed assistant message.
+		return Event{}, false
 	default:
-		return EventMessageUpdate
+		out.Type = EventMessageUpdate
 	}
+	return out, true
 }
 
 func toModelMessages(in []any) []model.Message {
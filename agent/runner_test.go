@@ -2,7 +2,10 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -198,6 +201,248 @@ func TestRunTurnReturnsErrorWhenToolRoundsExhausted(t *testing.T) {
 	}
 }
 
+func TestRunTurnForcedToolChoiceAppliesUntilToolIsCalled(t *testing.T) {
+	tool := &testTool{name: "submit_result", resultText: "submitted"}
+	a := newTestAgent([]Tool{tool})
+	choices := []provider.ToolChoice{}
+	client := provider.MockClient{
+		Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+			choices = append(choices, options.ToolChoice)
+			if !conversationHasRole(conversation.Messages, model.RoleToolResult) {
+				return toolCallStream("call_1", "submit_result", map[string]any{"ok": true}, m), nil
+			}
+			return textStream("done", m), nil
+		},
+	}
+
+	if _, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client:     client,
+		ToolChoice: "submit_result",
+	}); err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	want := []provider.ToolChoice{"submit_result", provider.ToolChoiceAuto}
+	if !reflect.DeepEqual(choices, want) {
+		t.Fatalf("unexpected tool choices per round: got=%v want=%v", choices, want)
+	}
+}
+
+func TestRunTurnToolChoiceForRoundForcesFinalRound(t *testing.T) {
+	read := &testTool{name: "read", resultText: "contents"}
+	submit := &testTool{name: "submit_result", resultText: "submitted"}
+	a := newTestAgent([]Tool{read, submit})
+	choices := []provider.ToolChoice{}
+	client := provider.MockClient{
+		Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+			choices = append(choices, options.ToolChoice)
+			switch options.ToolChoice {
+			case "submit_result":
+				return toolCallStream("call_"+strconv.Itoa(len(choices)), "submit_result", map[string]any{"ok": true}, m), nil
+			case provider.ToolChoiceNone:
+				return textStream("done", m), nil
+			}
+			return toolCallStream("call_"+strconv.Itoa(len(choices)), "read", map[string]any{}, m), nil
+		},
+	}
+
+	if _, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client: client,
+		ToolChoiceForRound: func(round int) provider.ToolChoice {
+			switch round {
+			case 3:
+				return "submit_result"
+			case 4:
+				return provider.ToolChoiceNone
+			}
+			return provider.ToolChoiceAuto
+		},
+	}); err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	want := []provider.ToolChoice{provider.ToolChoiceAuto, provider.ToolChoiceAuto, "submit_result", provider.ToolChoiceNone}
+	if !reflect.DeepEqual(choices, want) {
+		t.Fatalf("unexpected tool choices per round: got=%v want=%v", choices, want)
+	}
+}
+
+func TestRunTurnForcedToolOnLastRoundEndsTurn(t *testing.T) {
+	read := &testTool{name: "read", resultText: "contents"}
+	submit := &testTool{name: "submit_result", resultText: "submitted"}
+	a := newTestAgent([]Tool{read, submit})
+	client := provider.MockClient{
+		Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+			if options.ToolChoice == "submit_result" {
+				return toolCallStream("call_submit", "submit_result", map[string]any{"ok": true}, m), nil
+			}
+			return toolCallStream("call_read", "read", map[string]any{}, m), nil
+		},
+	}
+
+	result, err := a.RunTurn(context.Background(), RunnerOptions{
+		Client:        client,
+		MaxToolRounds: 2,
+		ToolChoiceForRound: func(round int) provider.ToolChoice {
+			if round == 2 {
+				return "submit_result"
+			}
+			return provider.ToolChoiceAuto
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected the forced tool call to end the turn, got %v", err)
+	}
+	if calls := extractToolCalls(result.ContentRaw); len(calls) != 1 || calls[0].Name != "submit_result" {
+		t.Fatalf("expected the submit_result call as the result, got %#v", result.ContentRaw)
+	}
+	messages := a.State().Messages
+	if last, ok := messages[len(messages)-1].(model.Message); !ok || last.ToolCallID != "call_submit" {
+		t.Fatalf("expected the submit_result output to end the conversation, got %#v", messages[len(messages)-1])
+	}
+}
+
+func TestRunTurnEmitsTypedEvents(t *testing.T) {
+	tool := &testTool{name: "write_file", resultText: "file written"}
+	a := newTestAgent([]Tool{tool})
+	events := []Event{}
+	a.Subscribe(func(ev Event) {
+		events = append(events, ev)
+	})
+	client := provider.MockClient{
+		Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+			if !conversationHasRole(conversation.Messages, model.RoleToolResult) {
+				return toolCallStream("call_1", "write_file", map[string]any{"path": "a.txt"}, m), nil
+			}
+			return textStream("done", m), nil
+		},
+	}
+	if _, err := a.RunTurn(context.Background(), RunnerOptions{Client: client}); err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+
+	types := []EventType{}
+	for _, ev := range events {
+		types = append(types, ev.Type)
+		if ev.Timestamp == 0 {
+			t.Fatalf("expected timestamp on %s event", ev.Type)
+		}
+	}
+	want := []EventType{
+		EventTurnStart,
+		EventRoundStart, EventMessageStart, EventToolCallRequested, EventMessageEnd,
+		EventToolExecutionStart, EventToolExecutionEnd, EventRoundEnd,
+		EventRoundStart, EventMessageStart, EventMessageUpdate, EventMessageEnd, EventRoundEnd,
+		EventTurnEnd,
+	}
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("unexpected event sequence:\ngot=%v\nwant=%v", types, want)
+	}
+
+	requested := events[3]
+	if requested.ToolCallID != "call_1" || requested.ToolArgs["path"] != "a.txt" || requested.Round != 1 {
+		t.Fatalf("unexpected tool call requested event: %#v", requested)
+	}
+	end := events[6]
+	if end.ToolResult == nil || end.ToolResult.Text() != "file written" || end.IsError {
+		t.Fatalf("unexpected tool execution end event: %#v", end)
+	}
+	if update := events[10]; update.Delta != "done" || update.StreamEvent == nil || update.Round != 2 {
+		t.Fatalf("unexpected message update event: %#v", update)
+	}
+
+	payload, err := json.Marshal(end)
+	if err != nil {
+		t.Fatalf("marshal event failed: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		t.Fatalf("unmarshal event failed: %v", err)
+	}
+	for _, key := range []string{"type", "timestamp", "round", "toolName", "toolCallId", "toolArgs", "toolResult", "message"} {
+		if _, ok := decoded[key]; !ok {
+			t.Fatalf("expected %q in event json: %s", key, payload)
+		}
+	}
+}
+
+func TestRunTurnEmitsErrorEvent(t *testing.T) {
+	a := newTestAgent(nil)
+	events := []Event{}
+	a.Subscribe(func(ev Event) {
+		events = append(events, ev)
+	})
+	client := provider.MockClient{
+		Handler: func(context.Context, model.Model, model.Context, provider.StreamOptions) (stream.EventStream, error) {
+			return &stream.MockStream{
+				Events:    []stream.Event{{Type: stream.EventStart}, {Type: stream.EventError, Error: "boom"}},
+				ResultErr: errors.New("boom"),
+			}, nil
+		},
+	}
+	if _, err := a.RunTurn(context.Background(), RunnerOptions{Client: client}); err == nil {
+		t.Fatal("expected run turn error")
+	}
+	errorEvents := 0
+	for _, ev := range events {
+		if ev.Type == EventToolExecutionEnd {
+			t.Fatalf("stream error must not be reported as tool execution end: %#v", ev)
+		}
+		if ev.Type == EventError {
+			errorEvents++
+			if ev.Error != "boom" || !ev.IsError {
+				t.Fatalf("unexpected error event: %#v", ev)
+			}
+		}
+	}
+	if errorEvents != 1 {
+		t.Fatalf("expected one error event, got %d", errorEvents)
+	}
+}
+
+func TestRunTurnValidatesAndCoercesToolArguments(t *testing.T) {
+	parameters := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":  map[string]any{"type": "string"},
+			"limit": map[string]any{"type": "integer", "minimum": 1},
+			"mode":  map[string]any{"type": "string", "enum": []string{"fast", "slow"}},
+		},
+		"required": []string{"path"},
+	}
+
+	t.Run("coerces", func(t *testing.T) {
+		tool := &testTool{name: "read", resultText: "ok", parameters: parameters}
+		a := newTestAgent([]Tool{tool})
+		_, err := a.RunTurn(context.Background(), RunnerOptions{
+			Client: toolCallClient("read", map[string]any{"path": "a.txt", "limit": "5"}),
+		})
+		if err != nil {
+			t.Fatalf("run turn failed: %v", err)
+		}
+		if tool.calls != 1 || tool.lastArgs["limit"] != float64(5) {
+			t.Fatalf("expected coerced limit, got calls=%d args=%#v", tool.calls, tool.lastArgs)
+		}
+	})
+
+	t.Run("rejects", func(t *testing.T) {
+		tool := &testTool{name: "read", resultText: "ok", parameters: parameters}
+		a := newTestAgent([]Tool{tool})
+		_, err := a.RunTurn(context.Background(), RunnerOptions{
+			Client: toolCallClient("read", map[string]any{"limit": 0, "mode": "medium"}),
+		})
+		if err != nil {
+			t.Fatalf("run turn failed: %v", err)
+		}
+		if tool.calls != 0 {
+			t.Fatalf("tool must not run with invalid arguments, got %d calls", tool.calls)
+		}
+		got := lastToolResultText(t, a)
+		want := `Invalid arguments for read: path: is required; limit: must be >= 1; mode: must be one of ["fast", "slow"]`
+		if got != want {
+			t.Fatalf("unexpected validation message:\n got %q\nwant %q", got, want)
+		}
+	})
+}
+
 func TestExtractToolCalls(t *testing.T) {
 	calls := extractToolCalls([]any{
 		model.TextContent{Type: model.ContentText, Text: "ignore"},
@@ -240,6 +485,8 @@ type testTool struct {
 	resultText string
 	executeErr error
 	calls      int
+	lastArgs   map[string]any
+	parameters map[string]any
 }
 
 func (t *testTool) Name() string {
@@ -251,17 +498,21 @@ func (t *testTool) Description() string {
 }
 
 func (t *testTool) Parameters() map[string]any {
+	if t.parameters != nil {
+		return t.parameters
+	}
 	return map[string]any{"type": "object"}
 }
 
 func (t *testTool) Execute(toolCallID string, args map[string]any) (ToolResult, error) {
 	t.calls++
+	t.lastArgs = args
 	if t.executeErr != nil {
 		return ToolResult{}, t.executeErr
 	}
 	return ToolResult{
-		Content: []model.TextContent{
-			{Type: model.ContentText, Text: t.resultText},
+		Content: []any{
+			model.TextContent{Type: model.ContentText, Text: t.resultText},
 		},
 	}, nil
 }
@@ -343,3 +594,19 @@ func extractTextFromContent(content []any) string {
 	}
 	return strings.Join(parts, "\n")
 }
+
+func TestMapStreamEventKeepsToolArgumentsOutOfDelta(t *testing.T) {
+	ev, ok := mapStreamEvent(stream.Event{
+		Type:       stream.EventToolCallDelta,
+		ToolName:   "write",
+		ToolCallID: "call_1",
+		Delta:      `{"path":"a`,
+		Arguments:  map[string]any{"path": "a"},
+	}, 1)
+	if !ok || ev.Type != EventMessageUpdate {
+		t.Fatalf("unexpected mapping: %#v", ev)
+	}
+	if ev.Delta != "" || ev.ToolArgsDelta != `{"path":"a` || ev.ToolArgs["path"] != "a" {
+		t.Fatalf("expected the fragment only in ToolArgsDelta: %#v", ev)
+	}
+}
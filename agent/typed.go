@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/zahlmann/phi/ai/jsonschema"
+)
+
+// ContextTool is implemented by tools that accept the turn context; the runner
+// prefers ExecuteContext over Execute when it is available.
+type ContextTool interface {
+	Tool
+	ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (ToolResult, error)
+}
+
+// ToolUpdateFunc receives partial results while a tool is running.
+type ToolUpdateFunc func(partial ToolResult)
+
+// StreamingTool is implemented by tools that report progress while they run.
+// The runner prefers it over ContextTool and emits each update as an
+// EventToolExecutionUpdate.
+type StreamingTool interface {
+	Tool
+	ExecuteStreaming(ctx context.Context, toolCallID string, args map[string]any, onUpdate ToolUpdateFunc) (ToolResult, error)
+}
+
+type TypedToolFunc[Args any] func(ctx context.Context, args Args) (ToolResult, error)
+
+type typedTool[Args any] struct {
+	name        string
+	description string
+	parameters  map[string]any
+	run         TypedToolFunc[Args]
+}
+
+// NewTypedTool derives the parameter schema from Args using its `json`,
+// `description`, `enum` and `required` struct tags and decodes call arguments
+// into Args before running fn.
+func NewTypedTool[Args any](name, description string, fn TypedToolFunc[Args]) Tool {
+	return &typedTool[Args]{
+		name:        name,
+		description: description,
+		parameters:  jsonschema.For[Args](),
+		run:         fn,
+	}
+}
+
+func (t *typedTool[Args]) Name() string {
+	return t.name
+}
+
+func (t *typedTool[Args]) Description() string {
+	return t.description
+}
+
+func (t *typedTool[Args]) Parameters() map[string]any {
+	return t.parameters
+}
+
+func (t *typedTool[Args]) Execute(toolCallID string, args map[string]any) (ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *typedTool[Args]) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (ToolResult, error) {
+	var decoded Args
+	if args == nil {
+		args = map[string]any{}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("encode arguments: %w", err)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return ToolResult{}, fmt.Errorf("decode arguments: %w", err)
+	}
+	return t.run(ctx, decoded)
+}
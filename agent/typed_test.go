@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zahlmann/phi/ai/model"
+)
+
+type searchArgs struct {
+	Query string `json:"query" description:"Text to search for"`
+	Limit int    `json:"limit,omitempty" description:"Maximum results"`
+	Mode  string `json:"mode,omitempty" enum:"exact,fuzzy"`
+}
+
+func TestNewTypedToolSchema(t *testing.T) {
+	tool := NewTypedTool("search", "Search files", func(ctx context.Context, args searchArgs) (ToolResult, error) {
+		return ToolResult{}, nil
+	})
+	if tool.Name() != "search" || tool.Description() != "Search files" {
+		t.Fatalf("unexpected tool metadata: %s %s", tool.Name(), tool.Description())
+	}
+	schema := tool.Parameters()
+	props := schema["properties"].(map[string]any)
+	if props["query"].(map[string]any)["description"] != "Text to search for" {
+		t.Fatalf("unexpected query schema: %#v", props["query"])
+	}
+	if got := schema["required"].([]string); len(got) != 1 || got[0] != "query" {
+		t.Fatalf("unexpected required list: %#v", got)
+	}
+}
+
+func TestRunTurnExecutesTypedTool(t *testing.T) {
+	type ctxKey struct{}
+	var got searchArgs
+	var ctxValue any
+	tool := NewTypedTool("search", "Search files", func(ctx context.Context, args searchArgs) (ToolResult, error) {
+		got = args
+		ctxValue = ctx.Value(ctxKey{})
+		return ToolResult{Content: []any{model.TextContent{Type: model.ContentText, Text: "found"}}}, nil
+	})
+	a := newTestAgent([]Tool{tool})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "turn")
+	_, err := a.RunTurn(ctx, RunnerOptions{
+		Client: toolCallClient("search", map[string]any{"query": "TODO", "limit": "3", "mode": "exact"}),
+	})
+	if err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if got != (searchArgs{Query: "TODO", Limit: 3, Mode: "exact"}) {
+		t.Fatalf("unexpected decoded args: %#v", got)
+	}
+	if ctxValue != "turn" {
+		t.Fatalf("expected turn context to reach the tool, got %v", ctxValue)
+	}
+	if text := lastToolResultText(t, a); text != "found" {
+		t.Fatalf("unexpected tool result: %q", text)
+	}
+
+	a = newTestAgent([]Tool{tool})
+	_, err = a.RunTurn(context.Background(), RunnerOptions{
+		Client: toolCallClient("search", map[string]any{"query": "TODO", "mode": "regex"}),
+	})
+	if err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if text := lastToolResultText(t, a); text != `Invalid arguments for search: mode: must be one of ["exact", "fuzzy"]` {
+		t.Fatalf("unexpected validation result: %q", text)
+	}
+}
+
+type progressTool struct {
+	testTool
+}
+
+func (t *progressTool) ExecuteStreaming(ctx context.Context, toolCallID string, args map[string]any, onUpdate ToolUpdateFunc) (ToolResult, error) {
+	for _, step := range []string{"1/2", "2/2"} {
+		onUpdate(ToolResult{Content: []any{model.TextContent{Type: model.ContentText, Text: step}}})
+	}
+	return ToolResult{Content: []any{model.TextContent{Type: model.ContentText, Text: "finished"}}}, nil
+}
+
+func TestRunTurnEmitsToolExecutionUpdates(t *testing.T) {
+	tool := &progressTool{testTool: testTool{name: "build"}}
+	a := newTestAgent([]Tool{tool})
+	var types []EventType
+	var updates []string
+	a.Subscribe(func(ev Event) {
+		switch ev.Type {
+		case EventToolExecutionStart, EventToolExecutionEnd:
+			types = append(types, ev.Type)
+		case EventToolExecutionUpdate:
+			types = append(types, ev.Type)
+			if ev.ToolCallID != "call_1" || ev.ToolName != "build" {
+				t.Errorf("unexpected update event: %#v", ev)
+			}
+			updates = append(updates, ev.ToolResult.Text())
+		}
+	})
+
+	if _, err := a.RunTurn(context.Background(), RunnerOptions{Client: toolCallClient("build", map[string]any{})}); err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	want := []EventType{EventToolExecutionStart, EventToolExecutionUpdate, EventToolExecutionUpdate, EventToolExecutionEnd}
+	if len(types) != len(want) {
+		t.Fatalf("unexpected events: %v", types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("unexpected events: %v", types)
+		}
+	}
+	if len(updates) != 2 || updates[0] != "1/2" || updates[1] != "2/2" {
+		t.Fatalf("unexpected updates: %v", updates)
+	}
+	if text := lastToolResultText(t, a); text != "finished" {
+		t.Fatalf("unexpected tool result: %q", text)
+	}
+	if tool.calls != 0 {
+		t.Fatalf("expected ExecuteStreaming to be preferred over Execute")
+	}
+}
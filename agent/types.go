@@ -1,6 +1,11 @@
 package agent
 
-import "github.com/zahlmann/phi/ai/model"
+import (
+	"strings"
+
+	"github.com/zahlmann/phi/ai/model"
+	"github.com/zahlmann/phi/ai/stream"
+)
 
 type ThinkingLevel string
 
@@ -16,28 +21,57 @@ const (
 type EventType string
 
 const (
-	EventAgentStart         EventType = "agent_start"
-	EventAgentEnd           EventType = "agent_end"
-	EventTurnStart          EventType = "turn_start"
-	EventTurnEnd            EventType = "turn_end"
-	EventMessageStart       EventType = "message_start"
-	EventMessageUpdate      EventType = "message_update"
-	EventMessageEnd         EventType = "message_end"
-	EventToolExecutionStart EventType = "tool_execution_start"
-	EventToolExecutionEnd   EventType = "tool_execution_end"
+	EventAgentStart          EventType = "agent_start"
+	EventAgentEnd            EventType = "agent_end"
+	EventTurnStart           EventType = "turn_start"
+	EventTurnEnd             EventType = "turn_end"
+	EventRoundStart          EventType = "round_start"
+	EventRoundEnd            EventType = "round_end"
+	EventMessageStart        EventType = "message_start"
+	EventMessageUpdate       EventType = "message_update"
+	EventMessageEnd          EventType = "message_end"
+	EventToolCallRequested   EventType = "tool_call_requested"
+	EventToolCallDenied      EventType = "tool_call_denied"
+	EventToolExecutionStart  EventType = "tool_execution_start"
+	EventToolExecutionUpdate EventType = "tool_execution_update"
+	EventToolExecutionEnd    EventType = "tool_execution_end"
+	EventError               EventType = "error"
 )
 
+// Event is emitted to subscribers. Message carries model.Message or
+// model.AssistantMessage values only; stream payloads live in StreamEvent.
+// Delta carries assistant text and thinking, while the JSON fragments of a
+// streamed tool call go to ToolArgsDelta.
 type Event struct {
-	Type       EventType `json:"type"`
-	Message    any       `json:"message,omitempty"`
-	ToolName   string    `json:"toolName,omitempty"`
-	ToolCallID string    `json:"toolCallId,omitempty"`
-	IsError    bool      `json:"isError,omitempty"`
+	Type          EventType      `json:"type"`
+	Timestamp     int64          `json:"timestamp"`
+	Round         int            `json:"round,omitempty"`
+	Message       any            `json:"message,omitempty"`
+	Delta         string         `json:"delta,omitempty"`
+	StreamEvent   *stream.Event  `json:"streamEvent,omitempty"`
+	ToolName      string         `json:"toolName,omitempty"`
+	ToolCallID    string         `json:"toolCallId,omitempty"`
+	ToolArgs      map[string]any `json:"toolArgs,omitempty"`
+	ToolArgsDelta string         `json:"toolArgsDelta,omitempty"`
+	ToolResult    *ToolResult    `json:"toolResult,omitempty"`
+	Usage         *model.Usage   `json:"usage,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	IsError       bool           `json:"isError,omitempty"`
 }
 
 type ToolResult struct {
-	Content []model.TextContent `json:"content"`
-	Details map[string]any      `json:"details,omitempty"`
+	Content []any          `json:"content"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+func (r ToolResult) Text() string {
+	parts := []string{}
+	for _, item := range r.Content {
+		if text, ok := item.(model.TextContent); ok && text.Text != "" {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
 }
 
 type Tool interface {
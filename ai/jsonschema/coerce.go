@@ -0,0 +1,100 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Coerce converts obvious mismatches in value to the types the schema asks
+// for, e.g. "5" for an integer or "true" for a boolean. Values that cannot be
+// converted are returned unchanged so Validate can report them.
+func Coerce(schema map[string]any, value any) any {
+	if len(schema) == 0 {
+		return value
+	}
+	types := schemaTypes(schema)
+	if len(types) > 0 && !typeAllowed(types, valueType(value)) {
+		for _, t := range types {
+			if converted, ok := coerceTo(t, value); ok {
+				value = converted
+				break
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(map[string]any)
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			prop, ok := properties[key].(map[string]any)
+			if !ok {
+				prop = additional
+			}
+			out[key] = Coerce(prop, item)
+		}
+		return out
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = Coerce(items, item)
+		}
+		return out
+	}
+	return value
+}
+
+func coerceTo(kind string, value any) (any, bool) {
+	switch v := value.(type) {
+	case string:
+		text := strings.TrimSpace(v)
+		switch kind {
+		case "integer":
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return float64(n), true
+			}
+			if f, err := strconv.ParseFloat(text, 64); err == nil && valueType(f) == "integer" {
+				return f, true
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(text, 64); err == nil {
+				return f, true
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(text); err == nil {
+				return b, true
+			}
+		case "null":
+			if text == "null" {
+				return nil, true
+			}
+		case "object":
+			var out map[string]any
+			if strings.HasPrefix(text, "{") && json.Unmarshal([]byte(text), &out) == nil {
+				return out, true
+			}
+		case "array":
+			var out []any
+			if strings.HasPrefix(text, "[") && json.Unmarshal([]byte(text), &out) == nil {
+				return out, true
+			}
+		}
+	case float64:
+		switch kind {
+		case "string":
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case "boolean":
+			if v == 0 || v == 1 {
+				return v == 1, true
+			}
+		}
+	case bool:
+		if kind == "string" {
+			return strconv.FormatBool(v), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoerce(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"limit":   map[string]any{"type": "integer"},
+			"ratio":   map[string]any{"type": "number"},
+			"force":   map[string]any{"type": "boolean"},
+			"name":    map[string]any{"type": "string"},
+			"ids":     map[string]any{"type": "array", "items": map[string]any{"type": "integer"}},
+			"options": map[string]any{"type": "object", "properties": map[string]any{"depth": map[string]any{"type": "integer"}}},
+		},
+	}
+
+	got := Coerce(schema, map[string]any{
+		"limit":   "5",
+		"ratio":   " 0.25 ",
+		"force":   "true",
+		"name":    float64(42),
+		"ids":     `["1", 2]`,
+		"options": map[string]any{"depth": "3"},
+		"extra":   "7",
+	})
+	want := map[string]any{
+		"limit":   float64(5),
+		"ratio":   0.25,
+		"force":   true,
+		"name":    "42",
+		"ids":     []any{float64(1), float64(2)},
+		"options": map[string]any{"depth": float64(3)},
+		"extra":   "7",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected coercion:\n got %#v\nwant %#v", got, want)
+	}
+	if err := Validate(schema, got); err != nil {
+		t.Fatalf("expected coerced value to validate, got %v", err)
+	}
+}
+
+func TestCoerceLeavesUnconvertibleValues(t *testing.T) {
+	schema := map[string]any{"type": "integer"}
+	for _, value := range []any{"five", "1.5", true} {
+		if got := Coerce(schema, value); got != value {
+			t.Fatalf("expected %#v unchanged, got %#v", value, got)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func For[T any]() map[string]any {
+	return FromType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func FromType(t reflect.Type) map[string]any {
+	return schemaForType(t, map[reflect.Type]bool{})
+}
+
+func schemaForType(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{
+			"type":  "array",
+			"items": schemaForType(t.Elem(), seen),
+		}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": schemaForType(t.Elem(), seen),
+		}
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]any{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		return structSchema(t, seen)
+	default:
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	collectStructFields(t, seen, properties, &required)
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func collectStructFields(t reflect.Type, seen map[reflect.Type]bool, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitEmpty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && !hasJSONName(field) {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectStructFields(embedded, seen, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		prop := schemaForType(field.Type, seen)
+		applyFieldTags(prop, field)
+		properties[name] = prop
+		isRequired := !omitEmpty
+		if value, err := strconv.ParseBool(field.Tag.Get("required")); err == nil {
+			isRequired = value
+		}
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// applyFieldTags adds the `description` and comma separated `enum` struct tags
+// to a property schema; enums on slice fields constrain the items.
+func applyFieldTags(prop map[string]any, field reflect.StructField) {
+	if description := field.Tag.Get("description"); description != "" {
+		prop["description"] = description
+	}
+	raw, ok := field.Tag.Lookup("enum")
+	if !ok {
+		return
+	}
+	target := prop
+	if items, ok := prop["items"].(map[string]any); ok {
+		target = items
+	}
+	values := []any{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		switch schemaType(target) {
+		case "integer", "number":
+			if number, err := strconv.ParseFloat(item, 64); err == nil {
+				values = append(values, number)
+				continue
+			}
+		case "boolean":
+			if value, err := strconv.ParseBool(item); err == nil {
+				values = append(values, value)
+				continue
+			}
+		}
+		values = append(values, item)
+	}
+	target["enum"] = values
+}
+
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+func hasJSONName(field reflect.StructField) bool {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name != ""
+}
+
+// IsStrict reports whether schema can be sent in OpenAI strict mode, which
+// needs an object root, every property required, no additional properties
+// and items for every array.
+func IsStrict(schema map[string]any) bool {
+	return schemaType(schema) == "object" && strictSchema(schema)
+}
+
+func strictSchema(schema map[string]any) bool {
+	switch schemaType(schema) {
+	case "object":
+		properties, _ := schema["properties"].(map[string]any)
+		if additional, ok := schema["additionalProperties"].(bool); !ok || additional {
+			return false
+		}
+		required := map[string]bool{}
+		for _, name := range stringList(schema["required"]) {
+			required[name] = true
+		}
+		for name, raw := range properties {
+			prop, _ := raw.(map[string]any)
+			if !required[name] || !strictSchema(prop) {
+				return false
+			}
+		}
+		return true
+	case "array":
+		items, ok := schema["items"].(map[string]any)
+		return ok && strictSchema(items)
+	default:
+		return schemaType(schema) != ""
+	}
+}
@@ -0,0 +1,110 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type reflectInner struct {
+	Label string `json:"label"`
+}
+
+type reflectSample struct {
+	Name     string            `json:"name"`
+	Count    int               `json:"count"`
+	Ratio    float64           `json:"ratio,omitempty"`
+	Enabled  *bool             `json:"enabled"`
+	Items    []reflectInner    `json:"items"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Created  time.Time         `json:"created"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+func TestFromTypeStruct(t *testing.T) {
+	schema := For[reflectSample]()
+	if schema["type"] != "object" || schema["additionalProperties"] != false {
+		t.Fatalf("unexpected object schema: %#v", schema)
+	}
+	props := schema["properties"].(map[string]any)
+	if len(props) != 7 {
+		t.Fatalf("expected 7 properties, got %d: %#v", len(props), props)
+	}
+	if got := props["count"].(map[string]any)["type"]; got != "integer" {
+		t.Fatalf("unexpected count type: %v", got)
+	}
+	if got := props["enabled"].(map[string]any)["type"]; got != "boolean" {
+		t.Fatalf("unexpected pointer field type: %v", got)
+	}
+	items := props["items"].(map[string]any)
+	if items["type"] != "array" || items["items"].(map[string]any)["type"] != "object" {
+		t.Fatalf("unexpected array schema: %#v", items)
+	}
+	if got := props["created"].(map[string]any)["format"]; got != "date-time" {
+		t.Fatalf("unexpected time format: %v", got)
+	}
+	want := []string{"name", "count", "enabled", "items", "created"}
+	if !reflect.DeepEqual(schema["required"], want) {
+		t.Fatalf("unexpected required list: %#v", schema["required"])
+	}
+}
+
+type taggedSample struct {
+	Path  string   `json:"path" description:"File path"`
+	Mode  string   `json:"mode,omitempty" enum:"fast, slow"`
+	Level int      `json:"level" enum:"1,2,3" required:"false"`
+	Tags  []string `json:"tags,omitempty" enum:"a,b" required:"true"`
+}
+
+func TestFromTypeFieldTags(t *testing.T) {
+	schema := For[taggedSample]()
+	props := schema["properties"].(map[string]any)
+	if got := props["path"].(map[string]any)["description"]; got != "File path" {
+		t.Fatalf("unexpected description: %v", got)
+	}
+	if got := props["mode"].(map[string]any)["enum"]; !reflect.DeepEqual(got, []any{"fast", "slow"}) {
+		t.Fatalf("unexpected string enum: %#v", got)
+	}
+	if got := props["level"].(map[string]any)["enum"]; !reflect.DeepEqual(got, []any{1.0, 2.0, 3.0}) {
+		t.Fatalf("unexpected integer enum: %#v", got)
+	}
+	tags := props["tags"].(map[string]any)["items"].(map[string]any)
+	if !reflect.DeepEqual(tags["enum"], []any{"a", "b"}) {
+		t.Fatalf("unexpected items enum: %#v", tags)
+	}
+	if want := []string{"path", "tags"}; !reflect.DeepEqual(schema["required"], want) {
+		t.Fatalf("unexpected required list: %#v", schema["required"])
+	}
+}
+
+func TestIsStrict(t *testing.T) {
+	type strict struct {
+		A string   `json:"a"`
+		B []string `json:"b"`
+	}
+	type loose struct {
+		A string `json:"a,omitempty"`
+	}
+	if !IsStrict(For[strict]()) {
+		t.Fatal("expected all-required schema to be strict")
+	}
+	if IsStrict(For[loose]()) {
+		t.Fatal("expected optional property to disable strict mode")
+	}
+	if IsStrict(For[map[string]int]()) {
+		t.Fatal("expected map schema to disable strict mode")
+	}
+	if IsStrict(For[[]strict]()) {
+		t.Fatal("expected array root to disable strict mode")
+	}
+	untyped := map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{"list": map[string]any{"type": "array"}},
+		"required":             []string{"list"},
+		"additionalProperties": false,
+	}
+	if IsStrict(untyped) {
+		t.Fatal("expected array without items to disable strict mode")
+	}
+}
@@ -0,0 +1,326 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, item := range e {
+		parts = append(parts, item.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func Validate(schema map[string]any, value any) error {
+	var errs ValidationErrors
+	validateValue(schema, value, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateValue(schema map[string]any, value any, path string, errs *ValidationErrors) {
+	if len(schema) == 0 {
+		return
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		if !enumContains(enum, value) {
+			fail("must be one of %s", formatEnum(enum))
+			return
+		}
+	}
+
+	types := schemaTypes(schema)
+	if len(types) == 0 {
+		return
+	}
+	kind := valueType(value)
+	if !typeAllowed(types, kind) {
+		fail("expected %s, got %s", strings.Join(types, " or "), kind)
+		return
+	}
+
+	switch kind {
+	case "object":
+		if object, ok := value.(map[string]any); ok {
+			validateObject(schema, object, path, errs)
+		}
+	case "array":
+		values := arrayValues(value)
+		if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(values)) < min {
+			fail("must contain at least %s items", formatNumber(min))
+		}
+		if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(values)) > max {
+			fail("must contain at most %s items", formatNumber(max))
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range values {
+			validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case "string":
+		text := value.(string)
+		length := float64(utf8.RuneCountInString(text))
+		if min, ok := schemaNumber(schema, "minLength"); ok && length < min {
+			fail("must be at least %s characters", formatNumber(min))
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && length > max {
+			fail("must be at most %s characters", formatNumber(max))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(text) {
+				fail("must match pattern %q", pattern)
+			}
+		}
+	case "integer", "number":
+		number, _ := toNumber(value)
+		if min, ok := schemaNumber(schema, "minimum"); ok && number < min {
+			fail("must be >= %s", formatNumber(min))
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && number > max {
+			fail("must be <= %s", formatNumber(max))
+		}
+		if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && number <= min {
+			fail("must be > %s", formatNumber(min))
+		}
+		if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && number >= max {
+			fail("must be < %s", formatNumber(max))
+		}
+	}
+}
+
+func schemaNumber(schema map[string]any, key string) (float64, bool) {
+	raw, ok := schema[key]
+	if !ok {
+		return 0, false
+	}
+	return toNumber(raw)
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func validateObject(schema map[string]any, value map[string]any, path string, errs *ValidationErrors) {
+	properties, _ := schema["properties"].(map[string]any)
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := value[name]; !ok {
+			*errs = append(*errs, ValidationError{
+				Path:    joinPath(path, name),
+				Message: "is required",
+			})
+		}
+	}
+
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if raw, ok := properties[key]; ok {
+			prop, _ := raw.(map[string]any)
+			validateValue(prop, value[key], joinPath(path, key), errs)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*errs = append(*errs, ValidationError{
+					Path:    joinPath(path, key),
+					Message: "is not an allowed property",
+				})
+			}
+		case map[string]any:
+			validateValue(additional, value[key], joinPath(path, key), errs)
+		}
+	}
+}
+
+func schemaType(schema map[string]any) string {
+	types := schemaTypes(schema)
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
+
+func schemaTypes(schema map[string]any) []string {
+	switch v := schema["type"].(type) {
+	case string:
+		return []string{v}
+	default:
+		return stringList(v)
+	}
+}
+
+func typeAllowed(types []string, kind string) bool {
+	for _, t := range types {
+		if t == kind || (t == "number" && kind == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func valueType(value any) string {
+	if value == nil {
+		return "null"
+	}
+	switch v := value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case float32:
+		if float64(v) == math.Trunc(float64(v)) {
+			return "integer"
+		}
+		return "number"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func arrayValues(value any) []any {
+	if items, ok := value.([]any); ok {
+		return items
+	}
+	rv := reflect.ValueOf(value)
+	out := make([]any, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		out = append(out, rv.Index(i).Interface())
+	}
+	return out
+}
+
+func enumContains(enum any, value any) bool {
+	for _, candidate := range anyList(enum) {
+		if candidate == value {
+			return true
+		}
+		if cf, ok := toNumber(candidate); ok {
+			if vf, ok := toNumber(value); ok && cf == vf {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func formatEnum(enum any) string {
+	parts := []string{}
+	for _, item := range anyList(enum) {
+		parts = append(parts, fmt.Sprintf("%q", fmt.Sprint(item)))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func anyList(raw any) []any {
+	switch v := raw.(type) {
+	case []any:
+		return v
+	case nil:
+		return nil
+	}
+	rv := reflect.ValueOf(raw)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	out := make([]any, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		out = append(out, rv.Index(i).Interface())
+	}
+	return out
+}
+
+func stringList(raw any) []string {
+	if v, ok := raw.([]string); ok {
+		return v
+	}
+	out := []string{}
+	for _, item := range anyList(raw) {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func joinPath(base, key string) string {
+	if base == "" {
+		return key
+	}
+	return base + "." + key
+}
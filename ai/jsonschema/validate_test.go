@@ -0,0 +1,90 @@
+package jsonschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":  map[string]any{"type": "string"},
+			"count": map[string]any{"type": "integer"},
+			"mode":  map[string]any{"type": "string", "enum": []string{"fast", "slow"}},
+			"tags": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "string"},
+			},
+		},
+		"required":             []string{"path"},
+		"additionalProperties": false,
+	}
+
+	if err := Validate(schema, map[string]any{"path": "a", "count": 2, "tags": []any{"x"}}); err != nil {
+		t.Fatalf("expected valid value, got %v", err)
+	}
+	if err := Validate(schema, map[string]any{"path": "a", "count": float64(3)}); err != nil {
+		t.Fatalf("expected integral float to be an integer, got %v", err)
+	}
+
+	err := Validate(schema, map[string]any{
+		"count": 1.5,
+		"mode":  "medium",
+		"tags":  []any{"ok", 7},
+		"extra": true,
+	})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"path: is required",
+		"count: expected integer, got number",
+		`mode: must be one of ["fast", "slow"]`,
+		"tags[1]: expected string, got integer",
+		"extra: is not an allowed property",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in %q", want, msg)
+		}
+	}
+}
+
+func TestValidateEmptySchemaAcceptsAnything(t *testing.T) {
+	if err := Validate(map[string]any{}, []any{1, "two"}); err != nil {
+		t.Fatalf("expected empty schema to accept value, got %v", err)
+	}
+}
+
+func TestValidateConstraints(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"limit": map[string]any{"type": "integer", "minimum": 1, "maximum": 100},
+			"ratio": map[string]any{"type": "number", "exclusiveMinimum": 0},
+			"name":  map[string]any{"type": "string", "minLength": 1, "maxLength": 3, "pattern": "^[a-z]+$"},
+			"ids":   map[string]any{"type": "array", "minItems": 1, "maxItems": 2},
+		},
+	}
+
+	if err := Validate(schema, map[string]any{"limit": 10, "ratio": 0.5, "name": "abc", "ids": []any{1}}); err != nil {
+		t.Fatalf("expected valid value, got %v", err)
+	}
+
+	err := Validate(schema, map[string]any{"limit": 0, "ratio": 0, "name": "ABCD", "ids": []any{}})
+	got := err.Error()
+	for _, want := range []string{
+		"limit: must be >= 1",
+		"ratio: must be > 0",
+		"name: must be at most 3 characters",
+		`name: must match pattern "^[a-z]+$"`,
+		"ids: must contain at least 1 items",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in %q", want, got)
+		}
+	}
+}
@@ -132,7 +132,7 @@ func (c *OpenAIClient) streamChatGPTBackend(
 		return nil, err
 	}
 
-	request := buildChatGPTResponsesRequest(m, conversation)
+	request := buildChatGPTResponsesRequest(m, conversation, options)
 	payload, err := json.Marshal(request)
 	if err != nil {
 		return nil, err
@@ -292,11 +292,12 @@ type openAIChatRequest struct {
 	Model               string               `json:"model"`
 	Messages            []openAIChatMessage  `json:"messages"`
 	Tools               []openAIChatTool     `json:"tools,omitempty"`
-	ToolChoice          string               `json:"tool_choice,omitempty"`
+	ToolChoice          any                  `json:"tool_choice,omitempty"`
 	Stream              bool                 `json:"stream"`
 	StreamOptions       *openAIStreamOptions `json:"stream_options,omitempty"`
 	Temperature         *float64             `json:"temperature,omitempty"`
 	MaxCompletionTokens int                  `json:"max_completion_tokens,omitempty"`
+	ResponseFormat      map[string]any       `json:"response_format,omitempty"`
 }
 
 type openAIStreamOptions struct {
@@ -348,11 +349,87 @@ func buildOpenAIChatRequest(m model.Model, conversation model.Context, options S
 	}
 	if len(conversation.Tools) > 0 {
 		req.Tools = convertOpenAITools(conversation.Tools)
-		req.ToolChoice = "auto"
+		req.ToolChoice = convertToolChoice(options.ToolChoice, chatNamedTool)
 	}
+	req.ResponseFormat = convertChatResponseFormat(options.ResponseFormat)
 	return req
 }
 
+// convertToolChoice returns the tool_choice value for a mode, or namedTool's
+// object for a specific tool; the chat and responses APIs shape it differently.
+func convertToolChoice(choice ToolChoice, namedTool func(name string) map[string]any) any {
+	name := strings.TrimSpace(string(choice))
+	switch ToolChoice(name) {
+	case "":
+		return string(ToolChoiceAuto)
+	case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+		return name
+	default:
+		return namedTool(name)
+	}
+}
+
+func chatNamedTool(name string) map[string]any {
+	return map[string]any{"type": "function", "function": map[string]any{"name": name}}
+}
+
+func responsesNamedTool(name string) map[string]any {
+	return map[string]any{"type": "function", "name": name}
+}
+
+func convertChatResponseFormat(format *ResponseFormat) map[string]any {
+	if format == nil {
+		return nil
+	}
+	switch format.Type {
+	case ResponseFormatJSONObject:
+		return map[string]any{"type": "json_object"}
+	case ResponseFormatJSONSchema:
+		return map[string]any{
+			"type":        "json_schema",
+			"json_schema": responseFormatSchema(format),
+		}
+	default:
+		return nil
+	}
+}
+
+func convertResponsesTextFormat(format *ResponseFormat) map[string]any {
+	if format == nil {
+		return nil
+	}
+	switch format.Type {
+	case ResponseFormatJSONObject:
+		return map[string]any{"format": map[string]any{"type": "json_object"}}
+	case ResponseFormatJSONSchema:
+		out := responseFormatSchema(format)
+		out["type"] = "json_schema"
+		return map[string]any{"format": out}
+	default:
+		return nil
+	}
+}
+
+func responseFormatSchema(format *ResponseFormat) map[string]any {
+	name := strings.TrimSpace(format.Name)
+	if name == "" {
+		name = "response"
+	}
+	schema := format.Schema
+	if schema == nil {
+		schema = map[string]any{"type": "object"}
+	}
+	out := map[string]any{
+		"name":   name,
+		"schema": schema,
+		"strict": format.Strict,
+	}
+	if strings.TrimSpace(format.Description) != "" {
+		out["description"] = format.Description
+	}
+	return out
+}
+
 func convertOpenAITools(tools []model.Tool) []openAIChatTool {
 	out := make([]openAIChatTool, 0, len(tools))
 	for _, tool := range tools {
@@ -377,7 +454,24 @@ func toOpenAIMessages(conversation model.Context) []openAIChatMessage {
 		})
 	}
 
+	// Chat Completions tool messages are text-only, so images returned by tools
+	// are forwarded in a user message after the run of tool results.
+	pendingImages := []map[string]any{}
+	flushImages := func() {
+		if len(pendingImages) == 0 {
+			return
+		}
+		out = append(out, openAIChatMessage{
+			Role:    "user",
+			Content: pendingImages,
+		})
+		pendingImages = []map[string]any{}
+	}
+
 	for _, msg := range conversation.Messages {
+		if msg.Role != model.RoleToolResult {
+			flushImages()
+		}
 		switch msg.Role {
 		case model.RoleUser:
 			content := extractOpenAIUserContent(msg.ContentRaw)
@@ -416,12 +510,58 @@ func toOpenAIMessages(conversation model.Context) []openAIChatMessage {
 				Name:       msg.ToolName,
 				Content:    text,
 			})
+			if images := extractImages(msg.ContentRaw); len(images) > 0 {
+				pendingImages = append(pendingImages, map[string]any{
+					"type": "text",
+					"text": fmt.Sprintf("Image output from tool %s (call %s):", msg.ToolName, msg.ToolCallID),
+				})
+				for _, image := range images {
+					pendingImages = append(pendingImages, map[string]any{
+						"type": "image_url",
+						"image_url": map[string]any{
+							"url": imageDataURL(image),
+						},
+					})
+				}
+			}
 		}
 	}
+	flushImages()
+
+	return out
+}
 
+func extractImages(content []any) []model.ImageContent {
+	out := []model.ImageContent{}
+	for _, item := range content {
+		switch v := item.(type) {
+		case model.ImageContent:
+			if strings.TrimSpace(v.Data) != "" {
+				out = append(out, v)
+			}
+		case map[string]any:
+			kind, _ := v["type"].(string)
+			if kind != string(model.ContentImage) {
+				continue
+			}
+			mime, _ := v["mimeType"].(string)
+			data, _ := v["data"].(string)
+			if strings.TrimSpace(data) != "" {
+				out = append(out, model.ImageContent{
+					Type:     model.ContentImage,
+					MIMEType: mime,
+					Data:     data,
+				})
+			}
+		}
+	}
 	return out
 }
 
+func imageDataURL(image model.ImageContent) string {
+	return "data:" + image.MIMEType + ";base64," + image.Data
+}
+
 func extractOpenAIUserContent(content []any) any {
 	hasImage := false
 	parts := []map[string]any{}
@@ -748,9 +888,10 @@ type openAIStreamToolCallRaw struct {
 }
 
 type openAIToolCallState struct {
-	ID   string
-	Name string
-	Args strings.Builder
+	ID      string
+	Name    string
+	Args    strings.Builder
+	Partial stream.PartialArguments
 }
 
 type openAIAggregation struct {
@@ -802,6 +943,15 @@ func (a *openAIAggregation) applyChunk(chunk openAIChatStreamChunk, emit func(st
 			}
 			if tc.Function.Arguments != "" {
 				call.Args.WriteString(tc.Function.Arguments)
+				argsJSON := call.Args.String()
+				emit(stream.Event{
+					Type:          stream.EventToolCallDelta,
+					ToolName:      call.Name,
+					ToolCallID:    call.ID,
+					Delta:         tc.Function.Arguments,
+					ArgumentsJSON: argsJSON,
+					Arguments:     call.Partial.Update(argsJSON),
+				})
 			}
 		}
 
@@ -1026,13 +1176,14 @@ type chatGPTResponsesRequest struct {
 	Instructions      string           `json:"instructions,omitempty"`
 	Input             []any            `json:"input"`
 	Tools             []map[string]any `json:"tools,omitempty"`
-	ToolChoice        string           `json:"tool_choice,omitempty"`
+	ToolChoice        any              `json:"tool_choice,omitempty"`
 	ParallelToolCalls bool             `json:"parallel_tool_calls,omitempty"`
+	Text              map[string]any   `json:"text,omitempty"`
 	Store             bool             `json:"store"`
 	Stream            bool             `json:"stream"`
 }
 
-func buildChatGPTResponsesRequest(m model.Model, conversation model.Context) chatGPTResponsesRequest {
+func buildChatGPTResponsesRequest(m model.Model, conversation model.Context, options StreamOptions) chatGPTResponsesRequest {
 	req := chatGPTResponsesRequest{
 		Model:        m.ID,
 		Instructions: strings.TrimSpace(conversation.SystemPrompt),
@@ -1042,9 +1193,10 @@ func buildChatGPTResponsesRequest(m model.Model, conversation model.Context) cha
 	}
 	if len(conversation.Tools) > 0 {
 		req.Tools = convertResponsesTools(conversation.Tools)
-		req.ToolChoice = "auto"
+		req.ToolChoice = convertToolChoice(options.ToolChoice, responsesNamedTool)
 		req.ParallelToolCalls = true
 	}
+	req.Text = convertResponsesTextFormat(options.ResponseFormat)
 	return req
 }
 
@@ -1157,10 +1309,26 @@ func toResponsesInput(messages []model.Message) []any {
 			if strings.TrimSpace(text) == "" {
 				text = "(no content)"
 			}
+			var output any = text
+			if images := extractImages(msg.ContentRaw); len(images) > 0 {
+				parts := []map[string]any{
+					{
+						"type": "input_text",
+						"text": text,
+					},
+				}
+				for _, image := range images {
+					parts = append(parts, map[string]any{
+						"type":      "input_image",
+						"image_url": imageDataURL(image),
+					})
+				}
+				output = parts
+			}
 			out = append(out, map[string]any{
 				"type":    "function_call_output",
 				"call_id": msg.ToolCallID,
-				"output":  text,
+				"output":  output,
 			})
 		}
 	}
@@ -1177,19 +1345,21 @@ type chatGPTResponsesEventStream struct {
 type chatGPTResponsesSSEEvent struct {
 	Type     string         `json:"type"`
 	Delta    string         `json:"delta"`
+	ItemID   string         `json:"item_id"`
 	Item     map[string]any `json:"item"`
 	Response map[string]any `json:"response"`
 }
 
 type chatGPTResponsesAggregation struct {
-	requestModel  model.Model
-	responseModel string
-	text          strings.Builder
-	toolCalls     []model.ToolCallContent
-	seenToolCall  map[string]bool
-	usage         model.Usage
-	stopReason    model.StopReason
-	completed     bool
+	requestModel    model.Model
+	responseModel   string
+	text            strings.Builder
+	toolCalls       []model.ToolCallContent
+	seenToolCall    map[string]bool
+	pendingToolArgs map[string]*openAIToolCallState
+	usage           model.Usage
+	stopReason      model.StopReason
+	completed       bool
 }
 
 func (a *chatGPTResponsesAggregation) hasOutput() bool {
@@ -1328,6 +1498,10 @@ func (a *chatGPTResponsesAggregation) applyEvent(
 				Delta: event.Delta,
 			})
 		}
+	case "response.output_item.added":
+		a.handleOutputItemAdded(event.Item)
+	case "response.function_call_arguments.delta":
+		a.handleFunctionCallArgumentsDelta(event, emit)
 	case "response.output_item.done":
 		a.handleOutputItemDone(event.Item, emit)
 	case "response.failed":
@@ -1339,6 +1513,51 @@ func (a *chatGPTResponsesAggregation) applyEvent(
 	return nil
 }
 
+func (a *chatGPTResponsesAggregation) handleOutputItemAdded(item map[string]any) {
+	itemType, _ := item["type"].(string)
+	if itemType != "function_call" {
+		return
+	}
+	itemID, _ := item["id"].(string)
+	if strings.TrimSpace(itemID) == "" {
+		return
+	}
+	call := &openAIToolCallState{}
+	call.ID, _ = item["call_id"].(string)
+	call.Name, _ = item["name"].(string)
+	if a.pendingToolArgs == nil {
+		a.pendingToolArgs = map[string]*openAIToolCallState{}
+	}
+	a.pendingToolArgs[itemID] = call
+}
+
+func (a *chatGPTResponsesAggregation) handleFunctionCallArgumentsDelta(
+	event chatGPTResponsesSSEEvent,
+	emit func(stream.Event),
+) {
+	if event.Delta == "" {
+		return
+	}
+	if a.pendingToolArgs == nil {
+		a.pendingToolArgs = map[string]*openAIToolCallState{}
+	}
+	call, ok := a.pendingToolArgs[event.ItemID]
+	if !ok {
+		call = &openAIToolCallState{}
+		a.pendingToolArgs[event.ItemID] = call
+	}
+	call.Args.WriteString(event.Delta)
+	argsJSON := call.Args.String()
+	emit(stream.Event{
+		Type:          stream.EventToolCallDelta,
+		ToolName:      call.Name,
+		ToolCallID:    call.ID,
+		Delta:         event.Delta,
+		ArgumentsJSON: argsJSON,
+		Arguments:     call.Partial.Update(argsJSON),
+	})
+}
+
 func (a *chatGPTResponsesAggregation) handleOutputItemDone(
 	item map[string]any,
 	emit func(stream.Event),
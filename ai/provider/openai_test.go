@@ -123,6 +123,7 @@ func TestOpenAIClientStreamToolCall(t *testing.T) {
 	}
 
 	toolEventSeen := false
+	deltas := []stream.Event{}
 	for {
 		ev, recvErr := evStream.Recv()
 		if recvErr != nil {
@@ -131,10 +132,25 @@ func TestOpenAIClientStreamToolCall(t *testing.T) {
 		if ev.Type == stream.EventToolCall {
 			toolEventSeen = true
 		}
+		if ev.Type == stream.EventToolCallDelta {
+			deltas = append(deltas, ev)
+		}
 	}
 	if !toolEventSeen {
 		t.Fatal("expected tool call event")
 	}
+	if len(deltas) != 2 {
+		t.Fatalf("expected 2 tool call delta events, got %d", len(deltas))
+	}
+	if deltas[0].ToolCallID != "call_1" || deltas[0].ToolName != "read_file" {
+		t.Fatalf("unexpected first delta: %#v", deltas[0])
+	}
+	if deltas[0].ArgumentsJSON != `{"path":"` || deltas[0].Arguments["path"] != "" {
+		t.Fatalf("unexpected partial arguments: %#v", deltas[0])
+	}
+	if deltas[1].Delta != `README.md"}` || deltas[1].Arguments["path"] != "README.md" {
+		t.Fatalf("unexpected second delta: %#v", deltas[1])
+	}
 
 	assistant, err := evStream.Result()
 	if err != nil {
@@ -313,6 +329,63 @@ func TestOpenAIClientStreamChatGPTBackendToolCall(t *testing.T) {
 	}
 }
 
+func TestOpenAIClientStreamChatGPTBackendToolCallDeltas(t *testing.T) {
+	client := newHTTPTestClient(func(r *http.Request) (*http.Response, error) {
+		sse := strings.Join([]string{
+			"data: {\"type\":\"response.output_item.added\",\"item\":{\"type\":\"function_call\",\"id\":\"fc_1\",\"call_id\":\"call_1\",\"name\":\"write\",\"arguments\":\"\"}}",
+			"",
+			"data: {\"type\":\"response.function_call_arguments.delta\",\"item_id\":\"fc_1\",\"delta\":\"{\\\"path\\\":\\\"a.txt\\\",\\\"content\\\":\\\"hel\"}",
+			"",
+			"data: {\"type\":\"response.function_call_arguments.delta\",\"item_id\":\"fc_1\",\"delta\":\"lo\\\"}\"}",
+			"",
+			"data: {\"type\":\"response.output_item.done\",\"item\":{\"type\":\"function_call\",\"id\":\"fc_1\",\"call_id\":\"call_1\",\"name\":\"write\",\"arguments\":\"{\\\"path\\\":\\\"a.txt\\\",\\\"content\\\":\\\"hello\\\"}\"}}",
+			"",
+			"data: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_4\"}}",
+			"",
+		}, "\n")
+		return sseResponse(sse), nil
+	})
+
+	evStream, err := client.Stream(context.Background(), model.Model{
+		Provider: "openai",
+		ID:       "gpt-4o-mini",
+	}, model.Context{}, StreamOptions{
+		AuthMode:    AuthModeChatGPT,
+		AccessToken: "chatgpt-token",
+		AccountID:   "acc_123",
+	})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	deltas := []stream.Event{}
+	for {
+		ev, recvErr := evStream.Recv()
+		if recvErr != nil {
+			break
+		}
+		if ev.Type == stream.EventToolCallDelta {
+			deltas = append(deltas, ev)
+		}
+	}
+	if len(deltas) != 2 {
+		t.Fatalf("expected 2 tool call deltas, got %d", len(deltas))
+	}
+	first := deltas[0]
+	if first.ToolCallID != "call_1" || first.ToolName != "write" {
+		t.Fatalf("unexpected delta identity: %#v", first)
+	}
+	if first.Arguments["path"] != "a.txt" || first.Arguments["content"] != "hel" {
+		t.Fatalf("unexpected partial arguments: %#v", first.Arguments)
+	}
+	if deltas[1].ArgumentsJSON != `{"path":"a.txt","content":"hello"}` {
+		t.Fatalf("unexpected accumulated arguments: %q", deltas[1].ArgumentsJSON)
+	}
+	if _, err := evStream.Result(); err != nil {
+		t.Fatalf("result failed: %v", err)
+	}
+}
+
 func TestOpenAIClientStreamChatGPTBackendTreatsTextPlainAsSSE(t *testing.T) {
 	client := newHTTPTestClient(func(*http.Request) (*http.Response, error) {
 		sse := strings.Join([]string{
@@ -526,6 +599,150 @@ func TestOpenAIClientStreamParsesNonStreamingResponse(t *testing.T) {
 	}
 }
 
+func TestResponseFormatMapping(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"answer": map[string]any{"type": "string"}},
+	}
+	format := &ResponseFormat{
+		Type:   ResponseFormatJSONSchema,
+		Name:   "answer",
+		Schema: schema,
+		Strict: true,
+	}
+
+	chatReq := buildOpenAIChatRequest(model.Model{ID: "gpt-4o-mini"}, model.Context{}, StreamOptions{ResponseFormat: format})
+	if chatReq.ResponseFormat["type"] != "json_schema" {
+		t.Fatalf("unexpected chat response_format: %#v", chatReq.ResponseFormat)
+	}
+	jsonSchema, _ := chatReq.ResponseFormat["json_schema"].(map[string]any)
+	if jsonSchema["name"] != "answer" || jsonSchema["strict"] != true || !reflect.DeepEqual(jsonSchema["schema"], schema) {
+		t.Fatalf("unexpected chat json_schema: %#v", jsonSchema)
+	}
+
+	respReq := buildChatGPTResponsesRequest(model.Model{ID: "gpt-4o-mini"}, model.Context{}, StreamOptions{ResponseFormat: format})
+	textFormat, _ := respReq.Text["format"].(map[string]any)
+	if textFormat["type"] != "json_schema" || textFormat["name"] != "answer" || textFormat["strict"] != true {
+		t.Fatalf("unexpected responses text.format: %#v", respReq.Text)
+	}
+
+	objectReq := buildChatGPTResponsesRequest(model.Model{ID: "gpt-4o-mini"}, model.Context{}, StreamOptions{
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONObject},
+	})
+	if got := objectReq.Text["format"].(map[string]any)["type"]; got != "json_object" {
+		t.Fatalf("unexpected json_object format: %v", got)
+	}
+
+	plain := buildOpenAIChatRequest(model.Model{ID: "gpt-4o-mini"}, model.Context{}, StreamOptions{})
+	payload, _ := json.Marshal(plain)
+	if strings.Contains(string(payload), "response_format") {
+		t.Fatalf("expected response_format to be omitted, got %s", payload)
+	}
+}
+
+func TestToolChoiceMapping(t *testing.T) {
+	conversation := model.Context{
+		Tools: []model.Tool{{Name: "submit_result", Parameters: map[string]any{"type": "object"}}},
+	}
+	tests := []struct {
+		choice        ToolChoice
+		wantChat      any
+		wantResponses any
+	}{
+		{choice: "", wantChat: "auto", wantResponses: "auto"},
+		{choice: ToolChoiceNone, wantChat: "none", wantResponses: "none"},
+		{choice: ToolChoiceRequired, wantChat: "required", wantResponses: "required"},
+		{
+			choice: "submit_result",
+			wantChat: map[string]any{
+				"type":     "function",
+				"function": map[string]any{"name": "submit_result"},
+			},
+			wantResponses: map[string]any{"type": "function", "name": "submit_result"},
+		},
+	}
+	for _, tc := range tests {
+		chatReq := buildOpenAIChatRequest(model.Model{ID: "m"}, conversation, StreamOptions{ToolChoice: tc.choice})
+		if !reflect.DeepEqual(chatReq.ToolChoice, tc.wantChat) {
+			t.Fatalf("chat tool_choice for %q: got=%#v want=%#v", tc.choice, chatReq.ToolChoice, tc.wantChat)
+		}
+		respReq := buildChatGPTResponsesRequest(model.Model{ID: "m"}, conversation, StreamOptions{ToolChoice: tc.choice})
+		if !reflect.DeepEqual(respReq.ToolChoice, tc.wantResponses) {
+			t.Fatalf("responses tool_choice for %q: got=%#v want=%#v", tc.choice, respReq.ToolChoice, tc.wantResponses)
+		}
+	}
+
+	noTools := buildOpenAIChatRequest(model.Model{ID: "m"}, model.Context{}, StreamOptions{ToolChoice: ToolChoiceRequired})
+	if noTools.ToolChoice != nil {
+		t.Fatalf("expected tool_choice to be omitted without tools, got %#v", noTools.ToolChoice)
+	}
+}
+
+func TestToolResultImagesAreForwarded(t *testing.T) {
+	image := model.ImageContent{Type: model.ContentImage, MIMEType: "image/png", Data: "aW1n"}
+	messages := []model.Message{
+		{
+			Role: model.RoleAssistant,
+			ContentRaw: []any{
+				model.ToolCallContent{Type: model.ContentToolCall, ID: "call_1", Name: "read", Arguments: map[string]any{"path": "a.png"}},
+				model.ToolCallContent{Type: model.ContentToolCall, ID: "call_2", Name: "read", Arguments: map[string]any{"path": "b.txt"}},
+			},
+		},
+		{
+			Role:       model.RoleToolResult,
+			ToolCallID: "call_1",
+			ToolName:   "read",
+			ContentRaw: []any{model.TextContent{Type: model.ContentText, Text: "Read image file [image/png]"}, image},
+		},
+		{
+			Role:       model.RoleToolResult,
+			ToolCallID: "call_2",
+			ToolName:   "read",
+			ContentRaw: []any{model.TextContent{Type: model.ContentText, Text: "hello"}},
+		},
+		{
+			Role:       model.RoleUser,
+			ContentRaw: []any{model.TextContent{Type: model.ContentText, Text: "describe it"}},
+		},
+	}
+
+	chat := toOpenAIMessages(model.Context{Messages: messages})
+	roles := []string{}
+	for _, msg := range chat {
+		roles = append(roles, msg.Role)
+	}
+	if want := []string{"assistant", "tool", "tool", "user", "user"}; !reflect.DeepEqual(roles, want) {
+		t.Fatalf("unexpected chat roles: got=%v want=%v", roles, want)
+	}
+	parts, ok := chat[3].Content.([]map[string]any)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("expected text + image parts in follow-up user message, got %#v", chat[3].Content)
+	}
+	imageURL, _ := parts[1]["image_url"].(map[string]any)
+	if parts[1]["type"] != "image_url" || imageURL["url"] != "data:image/png;base64,aW1n" {
+		t.Fatalf("unexpected image part: %#v", parts[1])
+	}
+
+	input := toResponsesInput(messages)
+	var output any
+	for _, item := range input {
+		entry, _ := item.(map[string]any)
+		if entry["type"] == "function_call_output" && entry["call_id"] == "call_1" {
+			output = entry["output"]
+		}
+	}
+	outputParts, ok := output.([]map[string]any)
+	if !ok || len(outputParts) != 2 {
+		t.Fatalf("expected structured function_call_output, got %#v", output)
+	}
+	if outputParts[0]["type"] != "input_text" || outputParts[1]["type"] != "input_image" {
+		t.Fatalf("unexpected function_call_output parts: %#v", outputParts)
+	}
+	if outputParts[1]["image_url"] != "data:image/png;base64,aW1n" {
+		t.Fatalf("unexpected input_image url: %#v", outputParts[1])
+	}
+}
+
 func TestConsumeSSE(t *testing.T) {
 	body := "data: first\ndata: line\n\n: keep-alive\ndata: second\n\n"
 	payloads := []string{}
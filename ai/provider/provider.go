@@ -15,15 +15,51 @@ const (
 )
 
 type StreamOptions struct {
-	AuthMode    AuthMode
-	APIKey      string
-	AccessToken string
-	AccountID   string
-	SessionID   string
-	BaseURL     string
-	Headers     map[string]string
-	Temperature *float64
-	MaxTokens   int
+	AuthMode       AuthMode
+	APIKey         string
+	AccessToken    string
+	AccountID      string
+	SessionID      string
+	BaseURL        string
+	Headers        map[string]string
+	Temperature    *float64
+	MaxTokens      int
+	ResponseFormat *ResponseFormat
+	ToolChoice     ToolChoice
+}
+
+// ToolChoice is one of the modes below or the name of a tool the model must call.
+type ToolChoice string
+
+const (
+	ToolChoiceAuto     ToolChoice = "auto"
+	ToolChoiceNone     ToolChoice = "none"
+	ToolChoiceRequired ToolChoice = "required"
+)
+
+func (c ToolChoice) IsForced() bool {
+	switch c {
+	case "", ToolChoiceAuto, ToolChoiceNone:
+		return false
+	default:
+		return true
+	}
+}
+
+type ResponseFormatType string
+
+const (
+	ResponseFormatText       ResponseFormatType = "text"
+	ResponseFormatJSONObject ResponseFormatType = "json_object"
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+)
+
+type ResponseFormat struct {
+	Type        ResponseFormatType
+	Name        string
+	Description string
+	Schema      map[string]any
+	Strict      bool
 }
 
 type Client interface {
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	// partialParseEvery is the buffer size below which PartialArguments
+	// parses on every update.
+	partialParseEvery = 4096
+	// maxPartialAttempts bounds how many cut points ParsePartialJSON tries;
+	// the longest usable prefix is almost always among the last few.
+	maxPartialAttempts = 16
+)
+
+// PartialArguments parses tool call arguments as they stream in. Past a few
+// kilobytes it only re-parses once the buffer has grown by half, so a long
+// argument stream costs linear time overall; in between, Update returns the
+// last parse.
+type PartialArguments struct {
+	parsed    map[string]any
+	parsedLen int
+}
+
+func (p *PartialArguments) Update(raw string) map[string]any {
+	if len(raw) < partialParseEvery || len(raw) >= p.parsedLen+p.parsedLen/2 {
+		p.parsed = ParsePartialJSON(raw)
+		p.parsedLen = len(raw)
+	}
+	return p.parsed
+}
+
+// ParsePartialJSON parses an incomplete JSON object as far as it can by closing
+// open strings, arrays and objects. It returns nil when nothing usable is parsed.
+func ParsePartialJSON(raw string) map[string]any {
+	trimmed := strings.TrimSpace(raw)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil
+	}
+
+	var out map[string]any
+	if err := json.Unmarshal([]byte(trimmed), &out); err == nil {
+		return out
+	}
+
+	cuts := partialCuts(trimmed)
+	for i := len(cuts) - 1; i >= 0 && i >= len(cuts)-maxPartialAttempts; i-- {
+		out = nil
+		if err := json.Unmarshal([]byte(trimmed[:cuts[i].end]+cuts[i].closing), &out); err == nil {
+			return out
+		}
+	}
+	return nil
+}
+
+// partialCut is a prefix length after which closing makes the prefix
+// well-formed, unless the prefix ends in an object key.
+type partialCut struct {
+	end     int
+	closing string
+}
+
+// partialCuts scans raw once and returns its cut points in increasing order:
+// after each opening bracket and complete value, and within an unfinished
+// string, number or literal at the end.
+func partialCuts(raw string) []partialCut {
+	stack := []byte{}
+	cuts := []partialCut{}
+	cut := func(end int, open string) {
+		closing := []byte(open)
+		for i := len(stack) - 1; i >= 0; i-- {
+			closing = append(closing, stack[i])
+		}
+		cuts = append(cuts, partialCut{end: end, closing: string(closing)})
+	}
+
+	inString, escaped := false, false
+	lastEscape, tokenStart := -1, -1
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+				lastEscape = i
+			case c == '"':
+				inString = false
+				cut(i+1, "")
+			}
+			continue
+		}
+		if strings.IndexByte("{}[]\",: \t\r\n", c) < 0 {
+			if tokenStart < 0 {
+				tokenStart = i
+			}
+			continue
+		}
+		if tokenStart >= 0 {
+			cut(i, "")
+			tokenStart = -1
+		}
+		switch c {
+		case '"':
+			inString = true
+			lastEscape = -1
+		case '{':
+			stack = append(stack, '}')
+			cut(i+1, "")
+		case '[':
+			stack = append(stack, ']')
+			cut(i+1, "")
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return cuts
+			}
+			stack = stack[:len(stack)-1]
+			cut(i+1, "")
+		}
+	}
+
+	switch {
+	case inString:
+		// Drop an escape sequence that is cut off, such as \ or \u00.
+		end := len(raw)
+		if escaped || (lastEscape >= 0 && raw[lastEscape+1] == 'u' && len(raw)-lastEscape < 6) {
+			end = lastEscape
+		}
+		cut(end, `"`)
+	case tokenStart >= 0:
+		// A number or literal may be valid before its last bytes, as in 1.
+		for end := tokenStart + 1; end <= len(raw); end++ {
+			cut(end, "")
+		}
+	}
+	return cuts
+}
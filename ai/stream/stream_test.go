@@ -2,6 +2,8 @@ package stream
 
 import (
 	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/zahlmann/phi/ai/model"
@@ -88,3 +90,51 @@ func TestStaticEventStreamResultErrors(t *testing.T) {
 		t.Fatal("expected missing result error")
 	}
 }
+
+func TestParsePartialJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]any
+	}{
+		{name: "complete", raw: `{"path":"a.go"}`, want: map[string]any{"path": "a.go"}},
+		{name: "open string", raw: `{"path":"a.go","content":"package ma`, want: map[string]any{"path": "a.go", "content": "package ma"}},
+		{name: "partial key", raw: `{"path":"a.go","con`, want: map[string]any{"path": "a.go"}},
+		{name: "dangling colon", raw: `{"path":`, want: map[string]any{}},
+		{name: "nested array", raw: `{"edits":[{"old":"a","new":"b"},{"old":"c`, want: map[string]any{
+			"edits": []any{
+				map[string]any{"old": "a", "new": "b"},
+				map[string]any{"old": "c"},
+			},
+		}},
+		{name: "partial literal", raw: `{"force":tr`, want: map[string]any{}},
+		{name: "trailing escape", raw: `{"content":"line\`, want: map[string]any{"content": "line"}},
+		{name: "partial unicode escape", raw: `{"content":"caf\u00`, want: map[string]any{"content": "caf"}},
+		{name: "partial number", raw: `{"offset":1.`, want: map[string]any{"offset": float64(1)}},
+		{name: "trailing comma", raw: `{"offset": 12, `, want: map[string]any{"offset": float64(12)}},
+		{name: "not an object", raw: `[1, 2`, want: nil},
+		{name: "empty", raw: "", want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParsePartialJSON(tc.raw)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("ParsePartialJSON(%q): got=%#v want=%#v", tc.raw, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPartialArgumentsLargeStream(t *testing.T) {
+	content := strings.Repeat("0123456789", 50000)
+	raw := `{"path":"big.txt","content":"` + content + `"}`
+	var partial PartialArguments
+	var args map[string]any
+	for end := 10; end <= len(raw); end += 10 {
+		args = partial.Update(raw[:end])
+	}
+	got, _ := args["content"].(string)
+	if args["path"] != "big.txt" || len(got) < len(content)*2/3 || !strings.HasPrefix(content, got) {
+		t.Fatalf("unexpected partial arguments: path=%v content=%d bytes", args["path"], len(got))
+	}
+}
@@ -0,0 +1,57 @@
+// Command phi-mcp serves phi's coding tools (read, write, edit, apply_patch,
+// bash, grep, find, ls, process_output, process_kill) to MCP clients over
+// stdio.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/zahlmann/phi/coding/mcp"
+	"github.com/zahlmann/phi/coding/tools"
+)
+
+func main() {
+	cwd := flag.String("cwd", ".", "working directory the tools operate in")
+	flag.Parse()
+
+	root, err := filepath.Abs(*cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid cwd: %v\n", err)
+		os.Exit(1)
+	}
+	if err := serve(root); err != nil {
+		fmt.Fprintf(os.Stderr, "phi-mcp: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func serve(root string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	codingTools := tools.NewCodingTools(root)
+	// Closing stops background processes and removes spilled output.
+	defer func() {
+		for _, tool := range codingTools {
+			if closer, ok := tool.(io.Closer); ok {
+				_ = closer.Close()
+			}
+		}
+	}()
+
+	server := mcp.NewServer(codingTools, mcp.ServerOptions{
+		Info:         mcp.Implementation{Name: "phi", Version: "0.1.0"},
+		Instructions: "Tools operate on files inside " + root + ".",
+	})
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
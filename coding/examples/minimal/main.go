@@ -10,7 +10,6 @@ import (
 	openaiauth "github.com/zahlmann/phi/ai/auth/openai"
 	"github.com/zahlmann/phi/ai/model"
 	"github.com/zahlmann/phi/ai/provider"
-	"github.com/zahlmann/phi/ai/stream"
 	"github.com/zahlmann/phi/coding/sdk"
 	"github.com/zahlmann/phi/coding/session"
 	"github.com/zahlmann/phi/coding/tools"
@@ -84,23 +83,19 @@ func main() {
 		}
 		fmt.Println()
 
-		if se, ok := ev.Message.(stream.Event); ok {
-			switch se.Type {
-			case stream.EventTextDelta:
-				if se.Delta != "" {
-					fmt.Printf("[text_delta] %s\n", se.Delta)
-				}
-			case stream.EventThinkingDelta:
-				if se.Delta != "" {
-					fmt.Printf("[thinking_delta] %s\n", se.Delta)
-				}
-			case stream.EventToolCall:
-				fmt.Printf("[tool_call] name=%s id=%s args=%v\n", se.ToolName, se.ToolCallID, se.Arguments)
-			case stream.EventDone:
-				fmt.Printf("[stream_done] reason=%s\n", se.Reason)
-			case stream.EventError:
-				fmt.Printf("[stream_error] %s\n", se.Error)
+		switch ev.Type {
+		case agent.EventMessageUpdate:
+			if ev.StreamEvent != nil && ev.Delta != "" {
+				fmt.Printf("[%s] %s\n", ev.StreamEvent.Type, ev.Delta)
+			}
+		case agent.EventToolCallRequested:
+			fmt.Printf("[tool_call] name=%s id=%s args=%v\n", ev.ToolName, ev.ToolCallID, ev.ToolArgs)
+		case agent.EventRoundEnd:
+			if ev.Usage != nil {
+				fmt.Printf("[round_end] round=%d tokens=%d\n", ev.Round, ev.Usage.Total)
 			}
+		case agent.EventError:
+			fmt.Printf("[error] %s\n", ev.Error)
 		}
 
 		switch msg := ev.Message.(type) {
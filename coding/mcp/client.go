@@ -0,0 +1,302 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/zahlmann/phi/agent"
+)
+
+type ClientOptions struct {
+	ClientInfo Implementation
+	// ToolPrefix is prepended to tool names so tools from several servers
+	// cannot collide, e.g. "github_".
+	ToolPrefix string
+	// OnToolsChanged receives the refreshed tool list after the server sends
+	// notifications/tools/list_changed.
+	OnToolsChanged func([]agent.Tool)
+	// OnError receives errors from background work such as tool refreshes.
+	OnError func(error)
+}
+
+type Client struct {
+	transport Transport
+	options   ClientOptions
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  map[int64]chan rpcMessage
+	closed   bool
+	closeErr error
+
+	serverInfo   Implementation
+	capabilities ServerCapabilities
+	instructions string
+}
+
+func ConnectStdio(ctx context.Context, stdio StdioOptions, options ClientOptions) (*Client, error) {
+	return Connect(ctx, NewStdioTransport(stdio), options)
+}
+
+func ConnectHTTP(ctx context.Context, httpOptions HTTPOptions, options ClientOptions) (*Client, error) {
+	return Connect(ctx, NewHTTPTransport(httpOptions), options)
+}
+
+// Connect starts the transport and performs the initialize handshake.
+func Connect(ctx context.Context, transport Transport, options ClientOptions) (*Client, error) {
+	if transport == nil {
+		return nil, errors.New("mcp transport is required")
+	}
+	if options.ClientInfo.Name == "" {
+		options.ClientInfo = Implementation{Name: "phi", Version: "0.1.0"}
+	}
+	c := &Client{
+		transport: transport,
+		options:   options,
+		pending:   map[int64]chan rpcMessage{},
+	}
+	if err := transport.Start(ctx, c.handleMessage, c.handleClose); err != nil {
+		return nil, err
+	}
+
+	var result initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      options.ClientInfo,
+	}, &result)
+	if err != nil {
+		_ = transport.Close()
+		return nil, fmt.Errorf("mcp initialize: %w", err)
+	}
+	c.serverInfo = result.ServerInfo
+	c.capabilities = result.Capabilities
+	c.instructions = result.Instructions
+
+	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
+		_ = transport.Close()
+		return nil, fmt.Errorf("mcp initialized: %w", err)
+	}
+	return c, nil
+}
+
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+func (c *Client) Capabilities() ServerCapabilities {
+	return c.capabilities
+}
+
+func (c *Client) Instructions() string {
+	return c.instructions
+}
+
+// ListTools follows nextCursor until the server has returned every tool.
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	out := []ToolInfo{}
+	cursor := ""
+	for {
+		var page listToolsResult
+		if err := c.call(ctx, "tools/list", listToolsParams{Cursor: cursor}, &page); err != nil {
+			return nil, err
+		}
+		out = append(out, page.Tools...)
+		if page.NextCursor == "" {
+			return out, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Tools lists the server tools and wraps them as agent tools.
+func (c *Client) Tools(ctx context.Context) ([]agent.Tool, error) {
+	infos, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tools := make([]agent.Tool, 0, len(infos))
+	for _, info := range infos {
+		tools = append(tools, newTool(c, info))
+	}
+	return tools, nil
+}
+
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	c.mu.Lock()
+	if c.closed {
+		err := c.closeErr
+		c.mu.Unlock()
+		return fmt.Errorf("mcp connection closed: %w", err)
+	}
+	c.nextID++
+	id := c.nextID
+	reply := make(chan rpcMessage, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	message, err := encodeMessage(rpcMessage{
+		JSONRPC: jsonRPCVersion,
+		ID:      json.RawMessage(strconv.FormatInt(id, 10)),
+		Method:  method,
+	}, params)
+	if err != nil {
+		return err
+	}
+	if err := c.transport.Send(ctx, message); err != nil {
+		return err
+	}
+
+	select {
+	case response, ok := <-reply:
+		if !ok {
+			c.mu.Lock()
+			err := c.closeErr
+			c.mu.Unlock()
+			return fmt.Errorf("mcp connection closed: %w", err)
+		}
+		if response.Error != nil {
+			return response.Error
+		}
+		if result == nil || len(response.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(response.Result, result)
+	case <-ctx.Done():
+		c.cancelRequest(id, ctx.Err())
+		return ctx.Err()
+	}
+}
+
+// cancelRequest tells the server to stop work on an abandoned request.
+func (c *Client) cancelRequest(id int64, reason error) {
+	_ = c.notify(context.Background(), "notifications/cancelled", map[string]any{
+		"requestId": id,
+		"reason":    reason.Error(),
+	})
+}
+
+func (c *Client) notify(ctx context.Context, method string, params any) error {
+	message, err := encodeMessage(rpcMessage{JSONRPC: jsonRPCVersion, Method: method}, params)
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(ctx, message)
+}
+
+func (c *Client) respond(id json.RawMessage, result any, rpcErr *RPCError) {
+	message := rpcMessage{JSONRPC: jsonRPCVersion, ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return
+		}
+		message.Result = data
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	_ = c.transport.Send(context.Background(), data)
+}
+
+func encodeMessage(message rpcMessage, params any) (json.RawMessage, error) {
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		message.Params = data
+	}
+	return json.Marshal(message)
+}
+
+func (c *Client) handleMessage(raw json.RawMessage) {
+	var message rpcMessage
+	if err := json.Unmarshal(raw, &message); err != nil {
+		c.reportError(fmt.Errorf("mcp: invalid message: %w", err))
+		return
+	}
+	switch {
+	case message.isResponse():
+		id, err := strconv.ParseInt(string(message.ID), 10, 64)
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		if reply, ok := c.pending[id]; ok {
+			delete(c.pending, id)
+			reply <- message
+		}
+		c.mu.Unlock()
+	case message.isRequest():
+		if message.Method == "ping" {
+			go c.respond(message.ID, map[string]any{}, nil)
+			return
+		}
+		go c.respond(message.ID, nil, &RPCError{
+			Code:    codeMethodNotFound,
+			Message: "method not found: " + message.Method,
+		})
+	case message.isNotification():
+		if message.Method == "notifications/tools/list_changed" {
+			go c.refreshTools()
+		}
+	}
+}
+
+func (c *Client) refreshTools() {
+	tools, err := c.Tools(context.Background())
+	if err != nil {
+		c.reportError(fmt.Errorf("mcp: refresh tools: %w", err))
+		return
+	}
+	if c.options.OnToolsChanged != nil {
+		c.options.OnToolsChanged(tools)
+	}
+}
+
+func (c *Client) handleClose(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeErr = err
+	for id, reply := range c.pending {
+		close(reply)
+		delete(c.pending, id)
+	}
+}
+
+func (c *Client) reportError(err error) {
+	if c.options.OnError != nil {
+		c.options.OnError(err)
+	}
+}
@@ -0,0 +1,343 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("PHI_MCP_TEST_SERVER") == "1" {
+		serveTestMCP(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testMCPServer is a tiny MCP server used by the tests over stdio and HTTP.
+type testMCPServer struct {
+	mu    sync.Mutex
+	tools []ToolInfo
+}
+
+func newTestMCPServer() *testMCPServer {
+	schema := func(props map[string]any, required ...string) map[string]any {
+		return map[string]any{"type": "object", "properties": props, "required": required}
+	}
+	return &testMCPServer{tools: []ToolInfo{
+		{Name: "echo", Description: "Echo text", InputSchema: schema(map[string]any{"text": map[string]any{"type": "string"}}, "text")},
+		{Name: "image", Description: "Return an image", InputSchema: schema(map[string]any{})},
+		{Name: "fail", Description: "Always fails", InputSchema: schema(map[string]any{})},
+		{Name: "add_tool", Description: "Adds a tool", InputSchema: schema(map[string]any{})},
+	}}
+}
+
+// handle returns the messages to send for one incoming message: any
+// notifications first, then the response.
+func (s *testMCPServer) handle(message rpcMessage) []any {
+	if message.isNotification() {
+		return nil
+	}
+	respond := func(result any) []any {
+		return []any{map[string]any{"jsonrpc": "2.0", "id": message.ID, "result": result}}
+	}
+	switch message.Method {
+	case "initialize":
+		return respond(map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": true}},
+			"serverInfo":      map[string]any{"name": "test-server", "version": "1.0.0"},
+		})
+	case "tools/list":
+		var params listToolsParams
+		_ = json.Unmarshal(message.Params, &params)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		start := 0
+		fmt.Sscanf(params.Cursor, "page-%d", &start)
+		end := start + 2
+		result := map[string]any{}
+		if end < len(s.tools) {
+			result["nextCursor"] = fmt.Sprintf("page-%d", end)
+		} else {
+			end = len(s.tools)
+		}
+		result["tools"] = s.tools[start:end]
+		return respond(result)
+	case "tools/call":
+		var params callToolParams
+		_ = json.Unmarshal(message.Params, &params)
+		switch params.Name {
+		case "echo":
+			return respond(map[string]any{"content": []any{map[string]any{"type": "text", "text": fmt.Sprint(params.Arguments["text"])}}})
+		case "image":
+			return respond(map[string]any{
+				"content": []any{
+					map[string]any{"type": "text", "text": "chart"},
+					map[string]any{"type": "image", "data": "aGk=", "mimeType": "image/png"},
+				},
+				"structuredContent": map[string]any{"points": 3},
+			})
+		case "fail":
+			return respond(map[string]any{"content": []any{map[string]any{"type": "text", "text": "boom"}}, "isError": true})
+		case "add_tool":
+			s.mu.Lock()
+			s.tools = append(s.tools, ToolInfo{Name: "extra", InputSchema: map[string]any{"type": "object"}})
+			s.mu.Unlock()
+			return append([]any{map[string]any{"jsonrpc": "2.0", "method": "notifications/tools/list_changed"}},
+				respond(map[string]any{"content": []any{map[string]any{"type": "text", "text": "added"}}})...)
+		}
+	}
+	return []any{map[string]any{"jsonrpc": "2.0", "id": message.ID, "error": map[string]any{"code": codeMethodNotFound, "message": "unknown"}}}
+}
+
+func serveTestMCP(r io.Reader, w io.Writer) {
+	server := newTestMCPServer()
+	encoder := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var message rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			continue
+		}
+		for _, out := range server.handle(message) {
+			_ = encoder.Encode(out)
+		}
+	}
+}
+
+func connectStdioTestServer(t *testing.T, options ClientOptions) *Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := ConnectStdio(ctx, StdioOptions{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^$"},
+		Env:     []string{"PHI_MCP_TEST_SERVER=1"},
+	}, options)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestStdioClientTools(t *testing.T) {
+	client := connectStdioTestServer(t, ClientOptions{ToolPrefix: "test_"})
+	if got := client.ServerInfo().Name; got != "test-server" {
+		t.Fatalf("unexpected server info: %q", got)
+	}
+
+	tools, err := client.Tools(context.Background())
+	if err != nil {
+		t.Fatalf("list tools failed: %v", err)
+	}
+	names := []string{}
+	for _, tool := range tools {
+		names = append(names, tool.Name())
+	}
+	if want := []string{"test_echo", "test_image", "test_fail", "test_add_tool"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("unexpected tool names: %v", names)
+	}
+
+	echo := tools[0].(agent.ContextTool)
+	result, err := echo.ExecuteContext(context.Background(), "call_1", map[string]any{"text": "hello"})
+	if err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	if result.Text() != "hello" || result.Details["server"] != "test-server" {
+		t.Fatalf("unexpected echo result: %#v", result)
+	}
+
+	result, err = tools[1].Execute("call_2", nil)
+	if err != nil {
+		t.Fatalf("image failed: %v", err)
+	}
+	image, ok := result.Content[1].(model.ImageContent)
+	if !ok || image.MIMEType != "image/png" || image.Data != "aGk=" {
+		t.Fatalf("unexpected image content: %#v", result.Content)
+	}
+	if !reflect.DeepEqual(result.Details["structuredContent"], map[string]any{"points": float64(3)}) {
+		t.Fatalf("unexpected structured content: %#v", result.Details)
+	}
+
+	if _, err := tools[2].Execute("call_3", nil); err == nil || err.Error() != "boom" {
+		t.Fatalf("expected tool error boom, got %v", err)
+	}
+}
+
+func TestClientRefreshesToolsOnListChanged(t *testing.T) {
+	changed := make(chan []agent.Tool, 1)
+	client := connectStdioTestServer(t, ClientOptions{
+		OnToolsChanged: func(tools []agent.Tool) { changed <- tools },
+	})
+	if _, err := client.CallTool(context.Background(), "add_tool", nil); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	select {
+	case tools := <-changed:
+		if len(tools) != 5 || tools[4].Name() != "extra" {
+			t.Fatalf("unexpected refreshed tools: %d", len(tools))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tool refresh")
+	}
+}
+
+func TestClientFailsPendingCallsWhenServerExits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := ConnectStdio(ctx, StdioOptions{Command: "sh", Args: []string{"-c", "exit 0"}}, ClientOptions{})
+	if err == nil || !strings.Contains(err.Error(), "mcp initialize") {
+		t.Fatalf("expected initialize error, got %v", err)
+	}
+}
+
+func TestHTTPClientTools(t *testing.T) {
+	server := newTestMCPServer()
+	var mu sync.Mutex
+	deleted := false
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case http.MethodDelete:
+			mu.Lock()
+			deleted = r.Header.Get(sessionIDHeader) == "session-1"
+			mu.Unlock()
+			return
+		}
+		var message rpcMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if message.Method == "initialize" {
+			w.Header().Set(sessionIDHeader, "session-1")
+		} else if r.Header.Get(sessionIDHeader) != "session-1" {
+			http.Error(w, "missing session", http.StatusBadRequest)
+			return
+		}
+		out := server.handle(message)
+		if len(out) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if len(out) == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(out[0])
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, item := range out {
+			data, _ := json.Marshal(item)
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		}
+	}))
+	defer httpServer.Close()
+
+	changed := make(chan []agent.Tool, 1)
+	client, err := ConnectHTTP(context.Background(), HTTPOptions{URL: httpServer.URL}, ClientOptions{
+		OnToolsChanged: func(tools []agent.Tool) { changed <- tools },
+	})
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+
+	tools, err := client.Tools(context.Background())
+	if err != nil {
+		t.Fatalf("list tools failed: %v", err)
+	}
+	if len(tools) != 4 {
+		t.Fatalf("expected 4 tools, got %d", len(tools))
+	}
+	result, err := tools[0].Execute("call_1", map[string]any{"text": "over http"})
+	if err != nil || result.Text() != "over http" {
+		t.Fatalf("unexpected echo result: %#v, %v", result, err)
+	}
+
+	result, err = tools[3].Execute("call_2", nil)
+	if err != nil || result.Text() != "added" {
+		t.Fatalf("unexpected streamed result: %#v, %v", result, err)
+	}
+	select {
+	case tools := <-changed:
+		if len(tools) != 5 {
+			t.Fatalf("expected 5 tools after refresh, got %d", len(tools))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tool refresh")
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !deleted {
+		t.Fatal("expected session to be deleted on close")
+	}
+}
+
+func TestHTTPClientFailsCallWhenStreamEndsEarly(t *testing.T) {
+	server := newTestMCPServer()
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var message rpcMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if message.Method != "tools/list" {
+			out := server.handle(message)
+			if len(out) == 0 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(out[0])
+			return
+		}
+		// The stream closes after a notification, before the response.
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\"}\n\n")
+	}))
+	defer httpServer.Close()
+
+	client, err := ConnectHTTP(context.Background(), HTTPOptions{URL: httpServer.URL}, ClientOptions{})
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.ListTools(context.Background())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "stream ended without a response") {
+			t.Fatalf("expected stream error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not return after the stream ended")
+	}
+}
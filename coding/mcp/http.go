@@ -0,0 +1,266 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const sessionIDHeader = "Mcp-Session-Id"
+
+type HTTPOptions struct {
+	URL        string
+	Headers    map[string]string
+	HTTPClient *http.Client
+}
+
+// httpTransport implements the streamable HTTP transport: every message is a
+// POST whose response is either a single JSON message or an SSE stream, and a
+// GET stream carries server notifications when the server offers one.
+type httpTransport struct {
+	options HTTPOptions
+	client  *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+	onMessage func(json.RawMessage)
+	onClose   func(error)
+	ctx       context.Context
+	cancel    context.CancelFunc
+	listening bool
+	closed    bool
+}
+
+func NewHTTPTransport(options HTTPOptions) Transport {
+	client := options.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpTransport{options: options, client: client}
+}
+
+func (t *httpTransport) Start(ctx context.Context, onMessage func(json.RawMessage), onClose func(error)) error {
+	if strings.TrimSpace(t.options.URL) == "" {
+		return errors.New("mcp http url is required")
+	}
+	streamCtx, cancel := context.WithCancel(context.Background())
+	t.mu.Lock()
+	t.onMessage = onMessage
+	t.onClose = onClose
+	t.ctx = streamCtx
+	t.cancel = cancel
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *httpTransport) Send(ctx context.Context, message json.RawMessage) error {
+	req, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if id := resp.Header.Get(sessionIDHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent {
+		resp.Body.Close()
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("mcp http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	t.listen()
+	id := requestID(message)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		go func() {
+			defer resp.Body.Close()
+			answered := false
+			err := readSSE(resp.Body, func(m json.RawMessage) {
+				if !answered && id != nil && respondsTo(m, id) {
+					answered = true
+				}
+				t.deliver(m)
+			})
+			if !answered && id != nil {
+				if err == nil {
+					err = errors.New("stream ended without a response")
+				}
+				t.failRequest(id, err)
+			}
+		}()
+		return nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 {
+		t.deliver(trimmed)
+	}
+	if id != nil && !respondsTo(trimmed, id) {
+		return errors.New("mcp http response did not answer the request")
+	}
+	return nil
+}
+
+// failRequest answers a request whose response can no longer arrive with an
+// error, so the pending call returns.
+func (t *httpTransport) failRequest(id json.RawMessage, err error) {
+	data, marshalErr := json.Marshal(rpcMessage{
+		JSONRPC: jsonRPCVersion,
+		ID:      id,
+		Error:   &RPCError{Code: codeInternalError, Message: "mcp http response stream: " + err.Error()},
+	})
+	if marshalErr == nil {
+		t.deliver(data)
+	}
+}
+
+// requestID returns the id of a request, or nil for notifications and
+// responses, which get no reply.
+func requestID(message json.RawMessage) json.RawMessage {
+	var m rpcMessage
+	if json.Unmarshal(message, &m) != nil || !m.isRequest() {
+		return nil
+	}
+	return m.ID
+}
+
+func respondsTo(message json.RawMessage, id json.RawMessage) bool {
+	var m rpcMessage
+	return json.Unmarshal(message, &m) == nil && m.isResponse() && bytes.Equal(m.ID, id)
+}
+
+// listen opens the optional GET stream once a session exists. Servers that do
+// not offer one answer 405, which is not an error.
+func (t *httpTransport) listen() {
+	t.mu.Lock()
+	if t.listening || t.closed || t.sessionID == "" {
+		t.mu.Unlock()
+		return
+	}
+	t.listening = true
+	ctx := t.ctx
+	t.mu.Unlock()
+
+	go func() {
+		req, err := t.newRequest(ctx, http.MethodGet, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := t.client.Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return
+		}
+		_ = readSSE(resp.Body, t.deliver)
+	}()
+}
+
+func (t *httpTransport) deliver(message json.RawMessage) {
+	t.mu.Lock()
+	onMessage := t.onMessage
+	t.mu.Unlock()
+	if onMessage != nil {
+		onMessage(message)
+	}
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.options.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range t.options.Headers {
+		req.Header.Set(key, value)
+	}
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID != "" {
+		req.Header.Set(sessionIDHeader, sessionID)
+		req.Header.Set("MCP-Protocol-Version", ProtocolVersion)
+	}
+	return req, nil
+}
+
+func (t *httpTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	cancel, onClose, sessionID := t.cancel, t.onClose, t.sessionID
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if sessionID != "" {
+		if req, err := t.newRequest(context.Background(), http.MethodDelete, nil); err == nil {
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+	if onClose != nil {
+		onClose(io.EOF)
+	}
+	return nil
+}
+
+func readSSE(body io.Reader, onData func(json.RawMessage)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+
+	var dataLines []string
+	flush := func() {
+		if len(dataLines) == 0 {
+			return
+		}
+		payload := strings.TrimSpace(strings.Join(dataLines, "\n"))
+		dataLines = dataLines[:0]
+		if payload != "" {
+			onData(json.RawMessage(payload))
+		}
+	}
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			flush()
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			dataLines = append(dataLines, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	flush()
+	return scanner.Err()
+}
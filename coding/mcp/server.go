@@ -0,0 +1,255 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+type ServerOptions struct {
+	Info         Implementation
+	Instructions string
+}
+
+// Server exposes agent tools to MCP clients over newline-delimited JSON-RPC.
+type Server struct {
+	tools   []agent.Tool
+	options ServerOptions
+
+	writeMu sync.Mutex
+	encoder *json.Encoder
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+func NewServer(tools []agent.Tool, options ServerOptions) *Server {
+	if options.Info.Name == "" {
+		options.Info = Implementation{Name: "phi", Version: "0.1.0"}
+	}
+	return &Server{
+		tools:    tools,
+		options:  options,
+		inflight: map[string]context.CancelFunc{},
+	}
+}
+
+// Serve handles messages from r until it reaches EOF or ctx is done and
+// waits for in-flight tool calls before returning.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.encoder = json.NewEncoder(w)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan json.RawMessage)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readLines(r, func(line json.RawMessage) {
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+			}
+		})
+		close(lines)
+	}()
+
+	var wg sync.WaitGroup
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				// Let calls that were already sent finish before returning.
+				wg.Wait()
+				return <-readErr
+			}
+			s.handle(ctx, line, &wg)
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, raw json.RawMessage, wg *sync.WaitGroup) {
+	var message rpcMessage
+	if err := json.Unmarshal(raw, &message); err != nil {
+		s.writeError(json.RawMessage("null"), codeParseError, "parse error: "+err.Error())
+		return
+	}
+	if message.isNotification() {
+		if message.Method == "notifications/cancelled" {
+			var params struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			if json.Unmarshal(message.Params, &params) == nil {
+				s.cancel(string(params.RequestID))
+			}
+		}
+		return
+	}
+	if !message.isRequest() {
+		return
+	}
+
+	switch message.Method {
+	case "initialize":
+		var params initializeParams
+		_ = json.Unmarshal(message.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" {
+			version = ProtocolVersion
+		}
+		s.writeResult(message.ID, map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      s.options.Info,
+			"instructions":    s.options.Instructions,
+		})
+	case "ping":
+		s.writeResult(message.ID, map[string]any{})
+	case "tools/list":
+		tools := make([]ToolInfo, 0, len(s.tools))
+		for _, tool := range s.tools {
+			tools = append(tools, ToolInfo{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				InputSchema: tool.Parameters(),
+			})
+		}
+		s.writeResult(message.ID, listToolsResult{Tools: tools})
+	case "tools/call":
+		var params callToolParams
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			s.writeError(message.ID, codeInvalidParams, "invalid params: "+err.Error())
+			return
+		}
+		tool := s.findTool(params.Name)
+		if tool == nil {
+			s.writeError(message.ID, codeInvalidParams, "unknown tool: "+params.Name)
+			return
+		}
+		callCtx, cancel := context.WithCancel(ctx)
+		key := string(message.ID)
+		s.mu.Lock()
+		s.inflight[key] = cancel
+		s.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.cancel(key)
+			result := s.callTool(callCtx, tool, key, params.Arguments)
+			if callCtx.Err() != nil {
+				// Cancelled requests get no response.
+				return
+			}
+			s.writeResult(message.ID, result)
+		}()
+	default:
+		s.writeError(message.ID, codeMethodNotFound, "method not found: "+message.Method)
+	}
+}
+
+func (s *Server) callTool(ctx context.Context, tool agent.Tool, callID string, args map[string]any) CallToolResult {
+	args, err := agent.PrepareToolArguments(tool, args)
+	if err != nil {
+		return errorToolResult("Invalid arguments for " + tool.Name() + ": " + err.Error())
+	}
+	var result agent.ToolResult
+	if contextTool, ok := tool.(agent.ContextTool); ok {
+		result, err = contextTool.ExecuteContext(ctx, callID, args)
+	} else {
+		result, err = tool.Execute(callID, args)
+	}
+	if err != nil {
+		return errorToolResult(err.Error())
+	}
+	return fromToolResult(result)
+}
+
+func fromToolResult(result agent.ToolResult) CallToolResult {
+	content := []Content{}
+	for _, item := range result.Content {
+		switch v := item.(type) {
+		case model.TextContent:
+			content = append(content, Content{Type: "text", Text: v.Text})
+		case model.ImageContent:
+			content = append(content, Content{Type: "image", Data: v.Data, MIMEType: v.MIMEType})
+		}
+	}
+	if len(content) == 0 {
+		content = append(content, Content{Type: "text", Text: "(tool returned no output)"})
+	}
+	out := CallToolResult{Content: content}
+	if details := nonEmptyDetails(result.Details); len(details) > 0 {
+		out.StructuredContent = details
+	}
+	return out
+}
+
+// nonEmptyDetails drops nil values so optional details such as a missing
+// truncation report do not show up as nulls in structured content.
+func nonEmptyDetails(details map[string]any) map[string]any {
+	out := map[string]any{}
+	for key, value := range details {
+		if value == nil {
+			continue
+		}
+		if text, ok := value.(string); ok && text == "" {
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
+
+func errorToolResult(text string) CallToolResult {
+	return CallToolResult{
+		Content: []Content{{Type: "text", Text: strings.TrimSpace(text)}},
+		IsError: true,
+	}
+}
+
+func (s *Server) findTool(name string) agent.Tool {
+	for _, tool := range s.tools {
+		if tool != nil && tool.Name() == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+func (s *Server) cancel(key string) {
+	s.mu.Lock()
+	cancel, ok := s.inflight[key]
+	delete(s.inflight, key)
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (s *Server) writeResult(id json.RawMessage, result any) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		s.writeError(id, codeInternalError, fmt.Sprintf("encode result: %v", err))
+		return
+	}
+	s.write(rpcMessage{JSONRPC: jsonRPCVersion, ID: id, Result: data})
+}
+
+func (s *Server) writeError(id json.RawMessage, code int, message string) {
+	s.write(rpcMessage{JSONRPC: jsonRPCVersion, ID: id, Error: &RPCError{Code: code, Message: message}})
+}
+
+func (s *Server) write(message rpcMessage) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = s.encoder.Encode(message)
+}
@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zahlmann/phi/coding/tools"
+)
+
+// pipeTransport connects a client to an in-process server.
+type pipeTransport struct {
+	r io.ReadCloser
+	w io.WriteCloser
+
+	mu sync.Mutex
+}
+
+func (t *pipeTransport) Start(ctx context.Context, onMessage func(json.RawMessage), onClose func(error)) error {
+	go func() {
+		err := readLines(t.r, onMessage)
+		if err == nil {
+			err = io.EOF
+		}
+		onClose(err)
+	}()
+	return nil
+}
+
+func (t *pipeTransport) Send(ctx context.Context, message json.RawMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.w.Write(append(append([]byte{}, message...), '\n'))
+	return err
+}
+
+func (t *pipeTransport) Close() error {
+	_ = t.w.Close()
+	return t.r.Close()
+}
+
+func startTestServer(t *testing.T, dir string) (*Server, *Client) {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	server := NewServer(tools.NewCodingTools(dir), ServerOptions{})
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(context.Background(), serverR, serverW)
+		_ = serverW.Close()
+	}()
+
+	client, err := Connect(context.Background(), &pipeTransport{r: clientR, w: clientW}, ClientOptions{})
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Error("server did not stop")
+		}
+	})
+	return server, client
+}
+
+func TestServerServesCodingTools(t *testing.T) {
+	dir := t.TempDir()
+	_, client := startTestServer(t, dir)
+	if got := client.ServerInfo().Name; got != "phi" {
+		t.Fatalf("unexpected server name: %q", got)
+	}
+
+	infos, err := client.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("list tools failed: %v", err)
+	}
+	names := []string{}
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	if got := strings.Join(names, ","); got != "write,read,edit,apply_patch,bash,grep,find,ls,process_output,process_kill" {
+		t.Fatalf("unexpected tools: %s", got)
+	}
+
+	result, err := client.CallTool(context.Background(), "write", map[string]any{"path": "notes.txt", "content": "hello"})
+	if err != nil || result.IsError {
+		t.Fatalf("write failed: %#v, %v", result, err)
+	}
+	structured, _ := result.StructuredContent.(map[string]any)
+	if structured["path"] != "notes.txt" || structured["size"] != float64(5) {
+		t.Fatalf("unexpected structured content: %#v", result.StructuredContent)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("unexpected file content: %q, %v", data, err)
+	}
+
+	result, err = client.CallTool(context.Background(), "read", map[string]any{"path": "../outside.txt"})
+	if err != nil {
+		t.Fatalf("read call failed: %v", err)
+	}
+	if !result.IsError {
+		t.Fatalf("expected path outside cwd to fail, got %#v", result)
+	}
+
+	result, err = client.CallTool(context.Background(), "read", map[string]any{})
+	if err != nil {
+		t.Fatalf("read call failed: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "path: is required") {
+		t.Fatalf("expected validation error, got %#v", result)
+	}
+
+	var rpcErr *RPCError
+	if _, err := client.CallTool(context.Background(), "missing", nil); !errors.As(err, &rpcErr) || rpcErr.Code != codeInvalidParams {
+		t.Fatalf("expected invalid params error, got %v", err)
+	}
+}
+
+func TestServerCancelsToolCalls(t *testing.T) {
+	_, client := startTestServer(t, t.TempDir())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.CallTool(ctx, "bash", map[string]any{"command": "sleep 30"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("cancellation took too long: %s", elapsed)
+	}
+
+	if _, err := client.ListTools(context.Background()); err != nil {
+		t.Fatalf("server stopped responding after cancellation: %v", err)
+	}
+	// The cleanup waits for Serve, which only returns once the cancelled bash
+	// command has been killed.
+}
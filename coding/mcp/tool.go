@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+type tool struct {
+	client *Client
+	info   ToolInfo
+	name   string
+}
+
+func newTool(client *Client, info ToolInfo) agent.Tool {
+	return &tool{
+		client: client,
+		info:   info,
+		name:   client.options.ToolPrefix + info.Name,
+	}
+}
+
+func (t *tool) Name() string {
+	return t.name
+}
+
+func (t *tool) Description() string {
+	if t.info.Description != "" {
+		return t.info.Description
+	}
+	return t.info.Title
+}
+
+func (t *tool) Parameters() map[string]any {
+	if len(t.info.InputSchema) == 0 {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return t.info.InputSchema
+}
+
+func (t *tool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *tool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	result, err := t.client.CallTool(ctx, t.info.Name, args)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	converted := toToolResult(result)
+	if result.IsError {
+		text := strings.TrimSpace(converted.Text())
+		if text == "" {
+			text = "tool " + t.info.Name + " failed"
+		}
+		return agent.ToolResult{}, errors.New(text)
+	}
+	converted.Details["server"] = t.client.serverInfo.Name
+	return converted, nil
+}
+
+func toToolResult(result *CallToolResult) agent.ToolResult {
+	content := make([]any, 0, len(result.Content))
+	for _, item := range result.Content {
+		switch item.Type {
+		case "text":
+			content = append(content, model.TextContent{Type: model.ContentText, Text: item.Text})
+		case "image":
+			content = append(content, model.ImageContent{
+				Type:     model.ContentImage,
+				MIMEType: item.MIMEType,
+				Data:     item.Data,
+			})
+		case "resource":
+			if item.Resource == nil {
+				continue
+			}
+			if item.Resource.Text != "" {
+				content = append(content, model.TextContent{Type: model.ContentText, Text: item.Resource.Text})
+			} else if strings.HasPrefix(item.Resource.MIMEType, "image/") && item.Resource.Blob != "" {
+				content = append(content, model.ImageContent{
+					Type:     model.ContentImage,
+					MIMEType: item.Resource.MIMEType,
+					Data:     item.Resource.Blob,
+				})
+			} else {
+				content = append(content, model.TextContent{Type: model.ContentText, Text: "[Resource " + item.Resource.URI + "]"})
+			}
+		case "resource_link":
+			content = append(content, model.TextContent{Type: model.ContentText, Text: "[Resource " + item.URI + "]"})
+		default:
+			content = append(content, model.TextContent{Type: model.ContentText, Text: "[Unsupported " + item.Type + " content]"})
+		}
+	}
+	details := map[string]any{}
+	if result.StructuredContent != nil {
+		details["structuredContent"] = result.StructuredContent
+	}
+	return agent.ToolResult{Content: content, Details: details}
+}
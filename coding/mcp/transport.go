@@ -0,0 +1,136 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stdioCloseGrace is how long Close waits for the server to exit after its
+// stdin is closed before killing it.
+const stdioCloseGrace = 2 * time.Second
+
+// Transport carries JSON-RPC messages between the client and one server.
+// Start begins delivering incoming messages to onMessage and reports the end
+// of the connection to onClose exactly once.
+type Transport interface {
+	Start(ctx context.Context, onMessage func(json.RawMessage), onClose func(error)) error
+	Send(ctx context.Context, message json.RawMessage) error
+	Close() error
+}
+
+type StdioOptions struct {
+	Command string
+	Args    []string
+	Env     []string
+	Dir     string
+	// Stderr receives the server's stderr; it is discarded when nil.
+	Stderr io.Writer
+}
+
+type stdioTransport struct {
+	options StdioOptions
+
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  chan struct{}
+}
+
+func NewStdioTransport(options StdioOptions) Transport {
+	return &stdioTransport{options: options}
+}
+
+func (t *stdioTransport) Start(ctx context.Context, onMessage func(json.RawMessage), onClose func(error)) error {
+	if t.options.Command == "" {
+		return errors.New("mcp stdio command is required")
+	}
+	cmd := exec.Command(t.options.Command, t.options.Args...)
+	cmd.Dir = t.options.Dir
+	cmd.Env = append(os.Environ(), t.options.Env...)
+	cmd.Stderr = t.options.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start mcp server %s: %w", t.options.Command, err)
+	}
+
+	t.mu.Lock()
+	t.cmd = cmd
+	t.stdin = stdin
+	t.done = make(chan struct{})
+	t.mu.Unlock()
+
+	go func() {
+		defer close(t.done)
+		err := readLines(stdout, onMessage)
+		waitErr := cmd.Wait()
+		if err == nil && waitErr != nil {
+			err = fmt.Errorf("mcp server exited: %w", waitErr)
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		onClose(err)
+	}()
+	return nil
+}
+
+func readLines(r io.Reader, onMessage func(json.RawMessage)) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			onMessage(json.RawMessage(trimmed))
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (t *stdioTransport) Send(ctx context.Context, message json.RawMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stdin == nil {
+		return errors.New("mcp stdio transport is not started")
+	}
+	data := append(append([]byte{}, message...), '\n')
+	_, err := t.stdin.Write(data)
+	return err
+}
+
+func (t *stdioTransport) Close() error {
+	t.mu.Lock()
+	cmd, stdin, done := t.cmd, t.stdin, t.done
+	t.stdin = nil
+	t.mu.Unlock()
+	if cmd == nil || stdin == nil {
+		return nil
+	}
+	_ = stdin.Close()
+	select {
+	case <-done:
+	case <-time.After(stdioCloseGrace):
+		_ = cmd.Process.Kill()
+		<-done
+	}
+	return nil
+}
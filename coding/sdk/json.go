@@ -0,0 +1,130 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/zahlmann/phi/ai/jsonschema"
+	"github.com/zahlmann/phi/ai/model"
+	"github.com/zahlmann/phi/ai/provider"
+)
+
+const defaultJSONPromptAttempts = 3
+
+var schemaNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+type JSONPromptOptions struct {
+	Name        string
+	Description string
+	Images      []model.ImageContent
+	MaxAttempts int
+}
+
+func PromptJSON[T any](s *AgentSession, text string) (T, error) {
+	return PromptJSONWithOptions[T](s, text, JSONPromptOptions{})
+}
+
+func PromptJSONWithOptions[T any](s *AgentSession, text string, options JSONPromptOptions) (T, error) {
+	var zero T
+	if s.providerClient == nil {
+		return zero, errors.New("provider client is required")
+	}
+
+	schema := jsonschema.For[T]()
+	name := strings.TrimSpace(options.Name)
+	if name == "" {
+		name = schemaName(reflect.TypeOf((*T)(nil)).Elem())
+	}
+	format := &provider.ResponseFormat{
+		Type:        provider.ResponseFormatJSONSchema,
+		Name:        name,
+		Description: options.Description,
+		Schema:      schema,
+		Strict:      jsonschema.IsStrict(schema),
+	}
+	maxAttempts := options.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultJSONPromptAttempts
+	}
+
+	prompt := text
+	images := options.Images
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if err := s.Prompt(prompt, PromptOptions{
+			Images:         images,
+			ResponseFormat: format,
+		}); err != nil {
+			return zero, err
+		}
+		value, err := decodeJSONResponse[T](lastAssistantText(s.State().Messages), schema)
+		if err == nil {
+			return value, nil
+		}
+		lastErr = err
+		images = nil
+		prompt = fmt.Sprintf(
+			"Your previous response did not match the required JSON schema: %v\nRespond again with only a JSON value that matches the schema.",
+			err,
+		)
+	}
+	return zero, fmt.Errorf("structured response invalid after %d attempts: %w", maxAttempts, lastErr)
+}
+
+func decodeJSONResponse[T any](text string, schema map[string]any) (T, error) {
+	var out T
+	raw := strings.TrimSpace(text)
+	if strings.HasPrefix(raw, "```") {
+		raw = strings.TrimPrefix(raw, "```json")
+		raw = strings.TrimPrefix(raw, "```")
+		raw = strings.TrimSuffix(strings.TrimSpace(raw), "```")
+		raw = strings.TrimSpace(raw)
+	}
+	if raw == "" {
+		return out, errors.New("response is empty")
+	}
+
+	var generic any
+	if err := json.Unmarshal([]byte(raw), &generic); err != nil {
+		return out, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if err := jsonschema.Validate(schema, generic); err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+func lastAssistantText(messages []any) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg, ok := messages[i].(model.AssistantMessage)
+		if !ok {
+			continue
+		}
+		parts := []string{}
+		for _, item := range msg.ContentRaw {
+			if text, ok := item.(model.TextContent); ok {
+				parts = append(parts, text.Text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+func schemaName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	name := schemaNameSanitizer.ReplaceAllString(t.Name(), "_")
+	if name == "" {
+		return "response"
+	}
+	return name
+}
@@ -0,0 +1,105 @@
+package sdk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zahlmann/phi/ai/model"
+	"github.com/zahlmann/phi/ai/provider"
+	"github.com/zahlmann/phi/ai/stream"
+)
+
+type weatherReport struct {
+	City        string   `json:"city"`
+	Temperature float64  `json:"temperature"`
+	Tags        []string `json:"tags"`
+}
+
+func TestPromptJSONRepromptsOnValidationFailure(t *testing.T) {
+	calls := 0
+	client := provider.MockClient{
+		Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+			calls++
+			format := options.ResponseFormat
+			if format == nil || format.Type != provider.ResponseFormatJSONSchema {
+				t.Fatalf("expected json schema response format, got %#v", format)
+			}
+			if format.Name != "weatherReport" || !format.Strict {
+				t.Fatalf("unexpected response format: %#v", format)
+			}
+			if calls == 1 {
+				return textStream(`{"city":"Berlin"}`, m), nil
+			}
+			last := conversation.Messages[len(conversation.Messages)-1]
+			if !strings.Contains(extractUserText(last), "temperature: is required") {
+				t.Fatalf("expected validation feedback in reprompt, got %#v", last)
+			}
+			return textStream(`{"city":"Berlin","temperature":21.5,"tags":["sunny"]}`, m), nil
+		},
+	}
+	s := CreateAgentSession(CreateSessionOptions{
+		Model:          &model.Model{Provider: "mock", ID: "m1"},
+		SessionManager: &recordingManager{id: "s1"},
+		ProviderClient: client,
+	})
+
+	report, err := PromptJSON[weatherReport](s, "weather in Berlin?")
+	if err != nil {
+		t.Fatalf("prompt json failed: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected one reprompt, got %d calls", calls)
+	}
+	if report.City != "Berlin" || report.Temperature != 21.5 || len(report.Tags) != 1 {
+		t.Fatalf("unexpected report: %#v", report)
+	}
+}
+
+func TestPromptJSONGivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	client := provider.MockClient{
+		Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+			calls++
+			return textStream("not json", m), nil
+		},
+	}
+	s := CreateAgentSession(CreateSessionOptions{
+		Model:          &model.Model{Provider: "mock", ID: "m1"},
+		SessionManager: &recordingManager{id: "s1"},
+		ProviderClient: client,
+	})
+
+	_, err := PromptJSONWithOptions[weatherReport](s, "weather?", JSONPromptOptions{MaxAttempts: 2})
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("expected max attempts error, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 provider calls, got %d", calls)
+	}
+}
+
+func TestDecodeJSONResponseStripsCodeFence(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"ok": map[string]any{"type": "boolean"}},
+		"required":   []string{"ok"},
+	}
+	out, err := decodeJSONResponse[map[string]bool]("```json\n{\"ok\":true}\n```", schema)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if !out["ok"] {
+		t.Fatalf("unexpected decoded value: %#v", out)
+	}
+}
+
+func extractUserText(message model.Message) string {
+	parts := []string{}
+	for _, item := range message.ContentRaw {
+		if text, ok := item.(model.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -2,17 +2,27 @@ package sdk
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/zahlmann/phi/agent"
 	"github.com/zahlmann/phi/ai/model"
 	"github.com/zahlmann/phi/ai/provider"
 	"github.com/zahlmann/phi/coding/session"
+	"github.com/zahlmann/phi/coding/tools"
 )
 
 type PromptOptions struct {
 	Images            []model.ImageContent
 	StreamingBehavior string
+	ResponseFormat    *provider.ResponseFormat
+	ToolChoice        provider.ToolChoice
+	// ToolChoiceForRound overrides ToolChoice per round; see
+	// agent.RunnerOptions.
+	ToolChoiceForRound func(round int) provider.ToolChoice
 }
 
 type CreateSessionOptions struct {
@@ -26,6 +36,16 @@ type CreateSessionOptions struct {
 	APIKey         string
 	AccessToken    string
 	AccountID      string
+	BeforeToolCall agent.BeforeToolCallHook
+	// Approvals decides calls that BeforeToolCall answers with ask.
+	Approvals     *agent.Approvals
+	AfterToolCall agent.AfterToolCallHook
+	// Checkpoints is the store given to tools.Options.Checkpoints; Revert
+	// uses it to restore files.
+	Checkpoints *tools.CheckpointStore
+	// GitContextDir, when set, appends the branch, changed files and recent
+	// commits of the repository containing it to the system prompt.
+	GitContextDir string
 }
 
 type AgentSession struct {
@@ -36,6 +56,10 @@ type AgentSession struct {
 	apiKey         string
 	accessToken    string
 	accountID      string
+	beforeToolCall agent.BeforeToolCallHook
+	approvals      *agent.Approvals
+	afterToolCall  agent.AfterToolCallHook
+	checkpoints    *tools.CheckpointStore
 }
 
 func CreateAgentSession(options CreateSessionOptions) *AgentSession {
@@ -43,8 +67,14 @@ func CreateAgentSession(options CreateSessionOptions) *AgentSession {
 	if manager == nil {
 		manager = session.NewInMemoryManager("session")
 	}
+	systemPrompt := options.SystemPrompt
+	if options.GitContextDir != "" {
+		if block := tools.GitContext(options.GitContextDir); block != "" {
+			systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + block)
+		}
+	}
 	initial := agent.State{
-		SystemPrompt: options.SystemPrompt,
+		SystemPrompt: systemPrompt,
 		Model:        options.Model,
 		Thinking:     options.ThinkingLevel,
 		Messages:     []any{},
@@ -58,6 +88,10 @@ func CreateAgentSession(options CreateSessionOptions) *AgentSession {
 		apiKey:         options.APIKey,
 		accessToken:    options.AccessToken,
 		accountID:      options.AccountID,
+		beforeToolCall: options.BeforeToolCall,
+		approvals:      options.Approvals,
+		afterToolCall:  options.AfterToolCall,
+		checkpoints:    options.Checkpoints,
 	}
 }
 
@@ -84,24 +118,160 @@ func (s *AgentSession) Prompt(text string, options PromptOptions) error {
 	}
 
 	beforeCount := len(s.agent.State().Messages)
-	if _, err := s.agent.RunTurn(context.Background(), agent.RunnerOptions{
-		Client:      s.providerClient,
-		AuthMode:    s.authMode,
-		APIKey:      s.apiKey,
-		AccessToken: s.accessToken,
-		AccountID:   s.accountID,
-		SessionID:   s.manager.SessionID(),
-	}); err != nil {
-		return err
-	}
+	_, turnErr := s.agent.RunTurn(context.Background(), agent.RunnerOptions{
+		Client:             s.providerClient,
+		AuthMode:           s.authMode,
+		APIKey:             s.apiKey,
+		AccessToken:        s.accessToken,
+		AccountID:          s.accountID,
+		SessionID:          s.manager.SessionID(),
+		ResponseFormat:     options.ResponseFormat,
+		ToolChoice:         options.ToolChoice,
+		ToolChoiceForRound: options.ToolChoiceForRound,
+		BeforeToolCall:     s.beforeToolCall,
+		Approvals:          s.approvals,
+		AfterToolCall:      s.afterToolCall,
+	})
 
+	// Messages of a turn that failed partway are kept too, so the session
+	// log matches the conversation.
 	after := s.agent.State().Messages
 	for i := beforeCount; i < len(after); i++ {
 		if _, err := s.manager.AppendMessage(after[i]); err != nil {
 			return err
 		}
 	}
-	return nil
+	return turnErr
+}
+
+// Revert rewinds the session to the entry with toEntryID, which must be on
+// the current branch of a session manager that implements session.Brancher.
+// When that entry is an assistant message calling tools, the target moves
+// forward to the last of its tool results. The conversation is rebuilt from
+// the entries up to the target, files changed by later tool calls are
+// restored from the checkpoint store and new entries continue from the
+// target. It returns the restored paths. When a file cannot be restored the
+// others still are and the conversation is still rewound; the error names
+// the failures and their snapshots stay in the store.
+func (s *AgentSession) Revert(toEntryID string) ([]string, error) {
+	if s.agent.State().IsStreaming {
+		return nil, errors.New("cannot revert while a turn is running")
+	}
+	brancher, ok := s.manager.(session.Brancher)
+	if !ok {
+		return nil, errors.New("session manager does not support revert")
+	}
+	path := brancher.Path()
+	index := -1
+	for i, entry := range path {
+		if entry.ID == toEntryID {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("unknown session entry: %s", toEntryID)
+	}
+	// A round that called tools ends with their results; stopping before
+	// them would leave calls the API refuses to continue from.
+	pending := map[string]bool{}
+	if message, ok := entryMessage(path[index].Entry); ok {
+		if call, ok := message.(model.AssistantMessage); ok {
+			for _, id := range toolCallIDs(call.ContentRaw) {
+				pending[id] = true
+			}
+		}
+	}
+	for next := index + 1; next < len(path) && len(pending) > 0; next++ {
+		message, ok := entryMessage(path[next].Entry)
+		if !ok {
+			continue
+		}
+		result, ok := message.(model.Message)
+		if !ok || result.Role != model.RoleToolResult || !pending[result.ToolCallID] {
+			break
+		}
+		delete(pending, result.ToolCallID)
+		index = next
+	}
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("session entry %s has tool calls without results", toEntryID)
+	}
+
+	messages := []any{}
+	keep := []string{}
+	for _, entry := range path[:index+1] {
+		message, ok := entryMessage(entry.Entry)
+		if !ok {
+			continue
+		}
+		messages = append(messages, message)
+		if result, ok := message.(model.Message); ok && result.Role == model.RoleToolResult {
+			keep = append(keep, result.ToolCallID)
+		}
+	}
+	if err := brancher.Branch(path[index].ID); err != nil {
+		return nil, err
+	}
+	var restored []string
+	var err error
+	if s.checkpoints != nil {
+		restored, err = s.checkpoints.Revert(keep)
+	}
+	s.agent.SetMessages(messages)
+	return restored, err
+}
+
+// toolCallIDs returns the IDs of the tool calls in assistant content, which
+// is decoded into maps when it was loaded from a session file.
+func toolCallIDs(content []any) []string {
+	ids := []string{}
+	for _, item := range content {
+		switch v := item.(type) {
+		case model.ToolCallContent:
+			ids = append(ids, v.ID)
+		case map[string]any:
+			if id, ok := v["id"].(string); ok && v["type"] == string(model.ContentToolCall) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// entryMessage returns the conversation message stored in a session entry.
+// Entries loaded from a session file are decoded back into model messages.
+func entryMessage(entry any) (any, bool) {
+	switch e := entry.(type) {
+	case model.Message, model.AssistantMessage:
+		return e, true
+	case session.MessageEntry:
+		return entryMessage(e.Message)
+	case map[string]any:
+		if e["type"] != "message" {
+			return nil, false
+		}
+		raw, ok := e["message"].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, false
+		}
+		if raw["role"] == string(model.RoleAssistant) {
+			var message model.AssistantMessage
+			if err := json.Unmarshal(data, &message); err != nil {
+				return nil, false
+			}
+			return message, true
+		}
+		var message model.Message
+		if err := json.Unmarshal(data, &message); err != nil {
+			return nil, false
+		}
+		return message, true
+	}
+	return nil, false
 }
 
 func (s *AgentSession) Steer(text string) {
@@ -116,6 +286,23 @@ func (s *AgentSession) Subscribe(handler func(agent.Event)) (unsubscribe func())
 	return s.agent.Subscribe(handler)
 }
 
+// SetTools replaces the session tools; it takes effect on the next model round.
+func (s *AgentSession) SetTools(tools []agent.Tool) {
+	s.agent.SetTools(tools)
+}
+
+// Close releases tools that hold resources, such as a persistent shell. Tools
+// implementing io.Closer are closed.
+func (s *AgentSession) Close() error {
+	var errs []error
+	for _, tool := range s.agent.State().Tools {
+		if closer, ok := tool.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (s *AgentSession) State() agent.State {
 	return s.agent.State()
 }
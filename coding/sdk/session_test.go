@@ -3,6 +3,9 @@ package sdk
 import (
 	"context"
 	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -10,6 +13,8 @@ import (
 	"github.com/zahlmann/phi/ai/model"
 	"github.com/zahlmann/phi/ai/provider"
 	"github.com/zahlmann/phi/ai/stream"
+	"github.com/zahlmann/phi/coding/session"
+	"github.com/zahlmann/phi/coding/tools"
 )
 
 func TestSessionPromptWithoutProviderAppendsUserMessage(t *testing.T) {
@@ -158,6 +163,28 @@ func TestSessionPromptErrorPaths(t *testing.T) {
 			t.Fatalf("expected user message to be persisted before provider failure, got %d", len(manager.appended))
 		}
 	})
+
+	t.Run("turn error keeps messages", func(t *testing.T) {
+		manager := &recordingManager{id: "s1"}
+		client := provider.MockClient{
+			Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+				return toolCallStream("call_1", "write_file", map[string]any{"path": "a.py"}, m), nil
+			},
+		}
+		s := CreateAgentSession(CreateSessionOptions{
+			Model:          &model.Model{Provider: "mock", ID: "m1"},
+			Tools:          []agent.Tool{&testWriteTool{}},
+			SessionManager: manager,
+			ProviderClient: client,
+		})
+		err := s.Prompt("hello", PromptOptions{})
+		if err == nil || !strings.Contains(err.Error(), "max tool rounds") {
+			t.Fatalf("expected max rounds error, got %v", err)
+		}
+		if got, want := len(manager.appended), len(s.State().Messages); got != want {
+			t.Fatalf("expected all %d messages to be persisted, got %d", want, got)
+		}
+	})
 }
 
 func TestSessionSteerAndFollowUpQueue(t *testing.T) {
@@ -177,6 +204,270 @@ func TestSessionSteerAndFollowUpQueue(t *testing.T) {
 	}
 }
 
+func TestSessionCloseClosesTools(t *testing.T) {
+	closable := &closableTool{}
+	s := CreateAgentSession(CreateSessionOptions{
+		Tools: []agent.Tool{&testWriteTool{}, closable},
+	})
+	if err := s.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if closable.closed != 1 {
+		t.Fatalf("expected tool to be closed once, got %d", closable.closed)
+	}
+}
+
+func TestSessionRevertRestoresFilesAndBranches(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := session.NewFileManager("s1", filepath.Join(dir, "session.jsonl"))
+	if err != nil {
+		t.Fatalf("new file manager failed: %v", err)
+	}
+	client := writingClient(nil)
+	checkpoints := tools.NewCheckpointStore()
+	s := CreateAgentSession(CreateSessionOptions{
+		Model:          &model.Model{Provider: "mock", ID: "m1"},
+		Tools:          tools.NewCodingToolsWithOptions(dir, tools.Options{Checkpoints: checkpoints}),
+		SessionManager: manager,
+		ProviderClient: client,
+		Checkpoints:    checkpoints,
+	})
+	defer s.Close()
+
+	for _, text := range []string{"first", "second"} {
+		if err := s.Prompt(text, PromptOptions{}); err != nil {
+			t.Fatalf("prompt failed: %v", err)
+		}
+	}
+	assertFileContent(t, filepath.Join(dir, "a.txt"), "second")
+
+	entries, _, _, _ := manager.BuildContext()
+	if len(entries) != 8 {
+		t.Fatalf("expected 8 session entries, got %d", len(entries))
+	}
+	endOfFirst := entries[3].(session.MessageEntry).ID
+	restored, err := s.Revert(endOfFirst)
+	if err != nil {
+		t.Fatalf("revert failed: %v", err)
+	}
+	if len(restored) != 1 {
+		t.Fatalf("expected a.txt to be restored, got %v", restored)
+	}
+	assertFileContent(t, filepath.Join(dir, "a.txt"), "first")
+	if got := len(s.State().Messages); got != 4 {
+		t.Fatalf("expected conversation rewound to 4 messages, got %d", got)
+	}
+
+	if err := s.Prompt("third", PromptOptions{}); err != nil {
+		t.Fatalf("prompt after revert failed: %v", err)
+	}
+	entries, _, _, _ = manager.BuildContext()
+	if len(entries) != 8 {
+		t.Fatalf("expected the new branch to hold 8 entries, got %d", len(entries))
+	}
+	if parent := entries[4].(session.MessageEntry).ParentID; parent == nil || *parent != endOfFirst {
+		t.Fatalf("expected new branch to continue from %s, got %v", endOfFirst, parent)
+	}
+
+	first := entries[0].(session.MessageEntry).ID
+	if _, err := s.Revert(first); err != nil {
+		t.Fatalf("revert to start failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected a.txt to be removed, got %v", err)
+	}
+	if _, err := s.Revert("missing"); err == nil || !strings.Contains(err.Error(), "unknown session entry") {
+		t.Fatalf("expected unknown entry error, got %v", err)
+	}
+}
+
+func TestSessionRevertReloadedSession(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.jsonl")
+	manager, err := session.NewFileManager("s1", path)
+	if err != nil {
+		t.Fatalf("new file manager failed: %v", err)
+	}
+	var conversations []model.Context
+	client := writingClient(&conversations)
+	checkpoints := tools.NewCheckpointStore()
+	options := CreateSessionOptions{
+		Model:          &model.Model{Provider: "mock", ID: "m1"},
+		Tools:          tools.NewCodingToolsWithOptions(dir, tools.Options{Checkpoints: checkpoints}),
+		SessionManager: manager,
+		ProviderClient: client,
+		Checkpoints:    checkpoints,
+	}
+	s := CreateAgentSession(options)
+	for _, text := range []string{"first", "second"} {
+		if err := s.Prompt(text, PromptOptions{}); err != nil {
+			t.Fatalf("prompt failed: %v", err)
+		}
+	}
+	s.Close()
+
+	reloaded, err := session.NewFileManager("s1", path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	options.SessionManager = reloaded
+	options.Tools = tools.NewCodingToolsWithOptions(dir, tools.Options{Checkpoints: checkpoints})
+	s = CreateAgentSession(options)
+	defer s.Close()
+
+	entries, _, _, _ := reloaded.BuildContext()
+	endOfFirst, _ := entries[3].(map[string]any)["id"].(string)
+	restored, err := s.Revert(endOfFirst)
+	if err != nil {
+		t.Fatalf("revert failed: %v", err)
+	}
+	if len(restored) != 1 {
+		t.Fatalf("expected a.txt to be restored, got %v", restored)
+	}
+	assertFileContent(t, filepath.Join(dir, "a.txt"), "first")
+	messages := s.State().Messages
+	if len(messages) != 4 {
+		t.Fatalf("expected conversation rebuilt with 4 messages, got %d", len(messages))
+	}
+	if call, ok := messages[1].(model.AssistantMessage); !ok || call.StopReason != model.StopReasonToolUse {
+		t.Fatalf("expected the tool call message, got %#v", messages[1])
+	}
+	if result, ok := messages[2].(model.Message); !ok || result.ToolCallID != "call_first" {
+		t.Fatalf("expected the tool result, got %#v", messages[2])
+	}
+
+	conversations = nil
+	if err := s.Prompt("third", PromptOptions{}); err != nil {
+		t.Fatalf("prompt after revert failed: %v", err)
+	}
+	if got := len(conversations[0].Messages); got != 5 {
+		t.Fatalf("expected the model to see 5 messages, got %d", got)
+	}
+	entries, _, _, _ = reloaded.BuildContext()
+	if parent := entries[4].(session.MessageEntry).ParentID; parent == nil || *parent != endOfFirst {
+		t.Fatalf("expected new branch to continue from %s, got %v", endOfFirst, parent)
+	}
+	// Tool calls loaded from the file are decoded into maps.
+	if _, err := s.Revert(entries[1].(map[string]any)["id"].(string)); err != nil {
+		t.Fatalf("revert to tool call failed: %v", err)
+	}
+	if got := len(s.State().Messages); got != 3 {
+		t.Fatalf("expected revert to the tool call to keep its result, got %d messages", got)
+	}
+	// The revert is kept across another reload without a new message.
+	again, err := session.NewFileManager("s1", path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if entries, _, _, _ = again.BuildContext(); len(entries) != 3 {
+		t.Fatalf("expected reload to continue from the reverted entry, got %d entries", len(entries))
+	}
+}
+
+func TestSessionRevertToToolCallKeepsItsResults(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := session.NewFileManager("s1", filepath.Join(dir, "session.jsonl"))
+	if err != nil {
+		t.Fatalf("new file manager failed: %v", err)
+	}
+	checkpoints := tools.NewCheckpointStore()
+	s := CreateAgentSession(CreateSessionOptions{
+		Model:          &model.Model{Provider: "mock", ID: "m1"},
+		Tools:          tools.NewCodingToolsWithOptions(dir, tools.Options{Checkpoints: checkpoints}),
+		SessionManager: manager,
+		ProviderClient: writingClient(nil),
+		Checkpoints:    checkpoints,
+	})
+	defer s.Close()
+	for _, text := range []string{"first", "second"} {
+		if err := s.Prompt(text, PromptOptions{}); err != nil {
+			t.Fatalf("prompt failed: %v", err)
+		}
+	}
+
+	entries, _, _, _ := manager.BuildContext()
+	call := entries[5].(session.MessageEntry).ID
+	restored, err := s.Revert(call)
+	if err != nil {
+		t.Fatalf("revert failed: %v", err)
+	}
+	if len(restored) != 0 {
+		t.Fatalf("expected the call's own write to be kept, got %v", restored)
+	}
+	assertFileContent(t, filepath.Join(dir, "a.txt"), "second")
+	messages := s.State().Messages
+	if result, ok := messages[len(messages)-1].(model.Message); len(messages) != 7 || !ok || result.ToolCallID != "call_second" {
+		t.Fatalf("expected conversation to end with the tool result, got %#v", messages)
+	}
+
+	unanswered := session.NewInMemoryManager("s2")
+	if _, err := unanswered.AppendMessage(userMessage("go", nil)); err != nil {
+		t.Fatal(err)
+	}
+	id, err := unanswered.AppendMessage(model.AssistantMessage{
+		Role:       model.RoleAssistant,
+		ContentRaw: []any{model.ToolCallContent{Type: model.ContentToolCall, ID: "call_1", Name: "write"}},
+		StopReason: model.StopReasonToolUse,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = CreateAgentSession(CreateSessionOptions{SessionManager: unanswered})
+	if _, err := s.Revert(id); err == nil || !strings.Contains(err.Error(), "tool calls without results") {
+		t.Fatalf("expected unanswered tool call error, got %v", err)
+	}
+}
+
+// writingClient answers each prompt with a write of its text to a.txt, then
+// finishes. Conversations seen by the model are recorded when seen is set.
+func writingClient(seen *[]model.Context) provider.MockClient {
+	return provider.MockClient{
+		Handler: func(ctx context.Context, m model.Model, conversation model.Context, options provider.StreamOptions) (stream.EventStream, error) {
+			if seen != nil {
+				*seen = append(*seen, conversation)
+			}
+			last := conversation.Messages[len(conversation.Messages)-1]
+			if last.Role == model.RoleToolResult {
+				return textStream("done", m), nil
+			}
+			text := last.ContentRaw[0].(model.TextContent).Text
+			return toolCallStream("call_"+text, "write", map[string]any{"path": "a.txt", "content": text}, m), nil
+		},
+	}
+}
+
+func TestSessionGitContextInSystemPrompt(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	cmd := exec.Command("git", "init", "--quiet", "--initial-branch=trunk")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	s := CreateAgentSession(CreateSessionOptions{SystemPrompt: "help", GitContextDir: dir})
+	if prompt := s.State().SystemPrompt; !strings.HasPrefix(prompt, "help\n\nGit repository: ") || !strings.Contains(prompt, "Branch: trunk") {
+		t.Fatalf("expected git context in system prompt, got %q", prompt)
+	}
+	s = CreateAgentSession(CreateSessionOptions{SystemPrompt: "help", GitContextDir: t.TempDir()})
+	if prompt := s.State().SystemPrompt; prompt != "help" {
+		t.Fatalf("expected unchanged prompt outside a repository, got %q", prompt)
+	}
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s failed: %v", path, err)
+	}
+	if string(data) != want {
+		t.Fatalf("expected %s to contain %q, got %q", path, want, data)
+	}
+}
+
 type testWriteTool struct {
 	calls int
 }
@@ -196,12 +487,22 @@ func (t *testWriteTool) Parameters() map[string]any {
 func (t *testWriteTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
 	t.calls++
 	return agent.ToolResult{
-		Content: []model.TextContent{
-			{Type: model.ContentText, Text: "ok"},
+		Content: []any{
+			model.TextContent{Type: model.ContentText, Text: "ok"},
 		},
 	}, nil
 }
 
+type closableTool struct {
+	testWriteTool
+	closed int
+}
+
+func (t *closableTool) Close() error {
+	t.closed++
+	return nil
+}
+
 type recordingManager struct {
 	id        string
 	appended  []any
@@ -21,6 +21,13 @@ type MessageEntry struct {
 	Message any `json:"message"`
 }
 
+// BranchEntry records that the session continues from the entry named by
+// ParentID. It moves the leaf when the log is loaded and is not part of the
+// tree itself.
+type BranchEntry struct {
+	EntryBase
+}
+
 type ThinkingLevelChangeEntry struct {
 	EntryBase
 	ThinkingLevel string `json:"thinkingLevel"`
@@ -3,10 +3,12 @@ package session
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,6 +24,7 @@ type Manager interface {
 type InMemoryManager struct {
 	sessionID string
 	entries   []any
+	tree      entryTree
 }
 
 func NewInMemoryManager(sessionID string) *InMemoryManager {
@@ -40,27 +43,43 @@ func (m *InMemoryManager) AppendMessage(message any) (string, error) {
 	if message == nil {
 		return "", errors.New("message is nil")
 	}
-	m.entries = append(m.entries, message)
-	return "in-memory-entry", nil
+	return m.append("in-memory-entry", message), nil
 }
 
 func (m *InMemoryManager) AppendModelChange(provider, modelID string) (string, error) {
-	m.entries = append(m.entries, ModelChangeEntry{
+	return m.append("in-memory-model-change", ModelChangeEntry{
 		ModelID:  modelID,
 		Provider: provider,
-	})
-	return "in-memory-model-change", nil
+	}), nil
 }
 
 func (m *InMemoryManager) AppendThinkingLevelChange(level string) (string, error) {
-	m.entries = append(m.entries, ThinkingLevelChangeEntry{
+	return m.append("in-memory-thinking-change", ThinkingLevelChangeEntry{
 		ThinkingLevel: level,
-	})
-	return "in-memory-thinking-change", nil
+	}), nil
+}
+
+func (m *InMemoryManager) Branch(entryID string) error {
+	return m.tree.branch(entryID)
+}
+
+func (m *InMemoryManager) Path() []PathEntry {
+	return m.tree.entries(m.entries)
 }
 
 func (m *InMemoryManager) BuildContext() ([]any, string, string, string) {
-	return append([]any{}, m.entries...), "off", "", ""
+	out := []any{}
+	for _, i := range m.tree.path() {
+		out = append(out, m.entries[i])
+	}
+	return out, "off", "", ""
+}
+
+func (m *InMemoryManager) append(prefix string, entry any) string {
+	id := fmt.Sprintf("%s-%d", prefix, len(m.entries)+1)
+	m.entries = append(m.entries, entry)
+	m.tree.add(id)
+	return id
 }
 
 type FileManager struct {
@@ -68,6 +87,7 @@ type FileManager struct {
 	sessionID string
 	filePath  string
 	entries   []any
+	tree      entryTree
 }
 
 func NewFileManager(sessionID, filePath string) (*FileManager, error) {
@@ -96,7 +116,22 @@ func NewFileManager(sessionID, filePath string) (*FileManager, error) {
 			}
 			var raw map[string]any
 			if err := json.Unmarshal([]byte(line), &raw); err == nil {
+				id, _ := raw["id"].(string)
+				if id == "" {
+					id = fmt.Sprintf("line-%d", len(mgr.entries)+1)
+				}
+				var parent *string
+				if value, ok := raw["parentId"].(string); ok {
+					parent = &value
+				}
+				if raw["type"] == "branch" {
+					if parent != nil {
+						_ = mgr.tree.branch(*parent)
+					}
+					continue
+				}
 				mgr.entries = append(mgr.entries, raw)
+				mgr.tree.load(id, parent)
 			}
 		}
 	}
@@ -116,42 +151,78 @@ func (m *FileManager) AppendMessage(message any) (string, error) {
 		return "", errors.New("message is nil")
 	}
 	entryID := entryID("msg")
-	entry := MessageEntry{
-		EntryBase: newEntryBase("message", entryID),
-		Message:   message,
-	}
-	return entryID, m.append(entry)
+	return entryID, m.append(newEntryBase("message", entryID), func(base EntryBase) any {
+		return MessageEntry{EntryBase: base, Message: message}
+	})
 }
 
 func (m *FileManager) AppendModelChange(provider, modelID string) (string, error) {
 	entryID := entryID("model")
-	entry := ModelChangeEntry{
-		EntryBase: newEntryBase("model_change", entryID),
-		Provider:  provider,
-		ModelID:   modelID,
-	}
-	return entryID, m.append(entry)
+	return entryID, m.append(newEntryBase("model_change", entryID), func(base EntryBase) any {
+		return ModelChangeEntry{EntryBase: base, Provider: provider, ModelID: modelID}
+	})
 }
 
 func (m *FileManager) AppendThinkingLevelChange(level string) (string, error) {
 	entryID := entryID("thinking")
-	entry := ThinkingLevelChangeEntry{
-		EntryBase:     newEntryBase("thinking_level_change", entryID),
-		ThinkingLevel: level,
+	return entryID, m.append(newEntryBase("thinking_level_change", entryID), func(base EntryBase) any {
+		return ThinkingLevelChangeEntry{EntryBase: base, ThinkingLevel: level}
+	})
+}
+
+// Branch makes id the parent of the next entry. The log stays append
+// only: the move is written as a branch entry so that a reload continues
+// from the same entry, and BuildContext returns the entries on the current
+// branch.
+func (m *FileManager) Branch(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tree.index(id) < 0 {
+		return fmt.Errorf("unknown session entry: %s", id)
+	}
+	base := newEntryBase("branch", entryID("branch"))
+	base.ParentID = &id
+	if err := m.write(BranchEntry{EntryBase: base}); err != nil {
+		return err
 	}
-	return entryID, m.append(entry)
+	return m.tree.branch(id)
+}
+
+func (m *FileManager) Path() []PathEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tree.entries(m.entries)
 }
 
 func (m *FileManager) BuildContext() ([]any, string, string, string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	out := append([]any{}, m.entries...)
+	out := []any{}
+	for _, i := range m.tree.path() {
+		out = append(out, m.entries[i])
+	}
 	return out, "off", "", ""
 }
 
-func (m *FileManager) append(entry any) error {
+// append links base to the current leaf, builds the entry and writes it.
+func (m *FileManager) append(base EntryBase, build func(EntryBase) any) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.tree.leaf != "" {
+		parent := m.tree.leaf
+		base.ParentID = &parent
+	}
+	entry := build(base)
+	if err := m.write(entry); err != nil {
+		return err
+	}
+	m.entries = append(m.entries, entry)
+	m.tree.add(base.ID)
+	return nil
+}
+
+// write appends entry to the log as one JSON line. The caller holds m.mu.
+func (m *FileManager) write(entry any) error {
 	payload, err := json.Marshal(entry)
 	if err != nil {
 		return err
@@ -161,15 +232,16 @@ func (m *FileManager) append(entry any) error {
 		return err
 	}
 	defer f.Close()
-	if _, err := f.Write(append(payload, '\n')); err != nil {
-		return err
-	}
-	m.entries = append(m.entries, entry)
-	return nil
+	_, err = f.Write(append(payload, '\n'))
+	return err
 }
 
+var entrySeq atomic.Uint64
+
+// entryID is unique within the process even when the clock does not advance
+// between entries; branches refer to entries by ID.
 func entryID(prefix string) string {
-	return prefix + "-" + time.Now().UTC().Format("20060102T150405.000000000")
+	return fmt.Sprintf("%s-%s-%d", prefix, time.Now().UTC().Format("20060102T150405.000000000"), entrySeq.Add(1))
 }
 
 func newEntryBase(kind, id string) EntryBase {
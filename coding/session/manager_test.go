@@ -1,6 +1,7 @@
 package session
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -83,3 +84,127 @@ func TestFileManagerAppendAndReload(t *testing.T) {
 		t.Fatalf("expected at least 3 entries, got %d", len(entries))
 	}
 }
+
+func TestFileManagerBranch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "s1.jsonl")
+	mgr, err := NewFileManager("s1", file)
+	if err != nil {
+		t.Fatalf("new file manager failed: %v", err)
+	}
+	first, err := mgr.AppendMessage(map[string]any{"role": "user", "content": "one"})
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if _, err := mgr.AppendMessage(map[string]any{"role": "user", "content": "two"}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if err := mgr.Branch("missing"); err == nil || !strings.Contains(err.Error(), "unknown session entry") {
+		t.Fatalf("expected unknown entry error, got %v", err)
+	}
+	if err := mgr.Branch(first); err != nil {
+		t.Fatalf("branch failed: %v", err)
+	}
+	if _, err := mgr.AppendMessage(map[string]any{"role": "user", "content": "three"}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	for _, m := range []*FileManager{mgr, mustReload(t, file)} {
+		entries, _, _, _ := m.BuildContext()
+		if got := messageContents(entries); got != "one,three" {
+			t.Fatalf("expected branch one,three, got %s", got)
+		}
+		if path := m.Path(); len(path) != 2 || path[0].ID != first {
+			t.Fatalf("expected path to start at %s, got %+v", first, path)
+		}
+	}
+}
+
+func TestFileManagerBranchSurvivesReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "s1.jsonl")
+	mgr, err := NewFileManager("s1", file)
+	if err != nil {
+		t.Fatalf("new file manager failed: %v", err)
+	}
+	first, _ := mgr.AppendMessage(map[string]any{"role": "user", "content": "one"})
+	if _, err := mgr.AppendMessage(map[string]any{"role": "user", "content": "two"}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if err := mgr.Branch(first); err != nil {
+		t.Fatalf("branch failed: %v", err)
+	}
+
+	reloaded := mustReload(t, file)
+	entries, _, _, _ := reloaded.BuildContext()
+	if got := messageContents(entries); got != "one" {
+		t.Fatalf("expected reload to continue from the branch point, got %s", got)
+	}
+	next, err := reloaded.AppendMessage(map[string]any{"role": "user", "content": "three"})
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	entries, _, _, _ = mustReload(t, file).BuildContext()
+	if got := messageContents(entries); got != "one,three" {
+		t.Fatalf("expected branch one,three, got %s", got)
+	}
+	if path := reloaded.Path(); path[len(path)-1].ID != next {
+		t.Fatalf("expected %s as leaf, got %+v", next, path)
+	}
+}
+
+func TestFileManagerLoadsLinearLegacyLog(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "s1.jsonl")
+	legacy := `{"type":"message","id":"a","parentId":null,"message":{"content":"one"}}
+{"type":"message","id":"b","parentId":null,"message":{"content":"two"}}
+`
+	if err := os.WriteFile(file, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, _, _ := mustReload(t, file).BuildContext()
+	if got := messageContents(entries); got != "one,two" {
+		t.Fatalf("expected linear history, got %s", got)
+	}
+}
+
+func TestInMemoryManagerBranch(t *testing.T) {
+	mgr := NewInMemoryManager("s1")
+	first, _ := mgr.AppendMessage(map[string]any{"content": "one"})
+	second, _ := mgr.AppendMessage(map[string]any{"content": "two"})
+	if first == second {
+		t.Fatalf("expected unique entry ids, got %q twice", first)
+	}
+	if err := mgr.Branch(first); err != nil {
+		t.Fatalf("branch failed: %v", err)
+	}
+	entries, _, _, _ := mgr.BuildContext()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry on the branch, got %d", len(entries))
+	}
+}
+
+func mustReload(t *testing.T, file string) *FileManager {
+	t.Helper()
+	mgr, err := NewFileManager("s1", file)
+	if err != nil {
+		t.Fatalf("reload manager failed: %v", err)
+	}
+	return mgr
+}
+
+// messageContents joins the "content" field of each message entry, whether
+// it was appended in this process or loaded from disk.
+func messageContents(entries []any) string {
+	parts := []string{}
+	for _, entry := range entries {
+		var message any
+		switch e := entry.(type) {
+		case MessageEntry:
+			message = e.Message
+		case map[string]any:
+			message = e["message"]
+		}
+		if fields, ok := message.(map[string]any); ok {
+			parts = append(parts, fmt.Sprint(fields["content"]))
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,95 @@
+package session
+
+import "fmt"
+
+// Brancher is implemented by managers that keep the session as a tree of
+// entries. Branch moves the leaf back to an earlier entry; entries appended
+// afterwards become its children and BuildContext follows the new branch.
+// Path returns the entries on the current branch from the root to the leaf.
+type Brancher interface {
+	Branch(entryID string) error
+	Path() []PathEntry
+}
+
+// PathEntry is an entry on the current branch with its ID. Entries appended
+// by this process keep their type; entries loaded from a session file are
+// map[string]any.
+type PathEntry struct {
+	ID    string
+	Entry any
+}
+
+// entryTree links entries to their parents. An entry without a parent ID
+// follows the entry before it in the log, which keeps sessions written
+// before branching existed linear.
+type entryTree struct {
+	ids     []string
+	parents []string
+	leaf    string
+}
+
+// add records a new entry under the current leaf and returns its parent ID.
+func (t *entryTree) add(id string) string {
+	parent := t.leaf
+	t.ids = append(t.ids, id)
+	t.parents = append(t.parents, parent)
+	t.leaf = id
+	return parent
+}
+
+// load records an entry read back from the log.
+func (t *entryTree) load(id string, parent *string) {
+	prev := ""
+	if len(t.ids) > 0 {
+		prev = t.ids[len(t.ids)-1]
+	}
+	if parent != nil {
+		prev = *parent
+	}
+	t.ids = append(t.ids, id)
+	t.parents = append(t.parents, prev)
+	t.leaf = id
+}
+
+func (t *entryTree) branch(entryID string) error {
+	if t.index(entryID) < 0 {
+		return fmt.Errorf("unknown session entry: %s", entryID)
+	}
+	t.leaf = entryID
+	return nil
+}
+
+// path returns the log indexes of the entries from the root to the leaf.
+func (t *entryTree) path() []int {
+	out := []int{}
+	for id := t.leaf; id != "" && len(out) < len(t.ids); {
+		i := t.index(id)
+		if i < 0 {
+			break
+		}
+		out = append(out, i)
+		id = t.parents[i]
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// entries pairs the entries on the current branch with their IDs.
+func (t *entryTree) entries(all []any) []PathEntry {
+	out := []PathEntry{}
+	for _, i := range t.path() {
+		out = append(out, PathEntry{ID: t.ids[i], Entry: all[i]})
+	}
+	return out
+}
+
+func (t *entryTree) index(id string) int {
+	for i := len(t.ids) - 1; i >= 0; i-- {
+		if t.ids[i] == id {
+			return i
+		}
+	}
+	return -1
+}
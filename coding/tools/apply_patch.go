@@ -0,0 +1,252 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+type applyPatchTool struct {
+	paths       pathGuard
+	files       *fileTracker
+	checkpoints *CheckpointStore
+	diffContext int
+}
+
+// NewApplyPatchTool returns a standalone apply_patch tool; NewCodingTools
+// includes one that shares the toolset's file checks and checkpoints.
+func NewApplyPatchTool(cwd string) agent.Tool {
+	return &applyPatchTool{paths: standalonePaths(cwd)}
+}
+
+func (t *applyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+func (t *applyPatchTool) Description() string {
+	return "Apply a patch that adds, updates, deletes or moves files. Accepts a unified diff or the " +
+		"*** Begin Patch / *** End Patch format. Every hunk is checked before any file is written."
+}
+
+func (t *applyPatchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"patch": map[string]any{
+				"type":        "string",
+				"description": "Patch text in unified diff or *** Begin Patch format",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+// plannedChange is one validated file change; nothing touches the disk until
+// every change in the patch has been planned.
+type plannedChange struct {
+	file       patchFile
+	source     string
+	target     string
+	oldContent string
+	newContent string
+	crlf       bool
+}
+
+func (t *applyPatchTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	text, ok := toStringArg(args, "patch")
+	if !ok || strings.TrimSpace(text) == "" {
+		return agent.ToolResult{}, fmt.Errorf("missing required argument: patch")
+	}
+	files, err := parsePatch(text)
+	if err != nil {
+		return agent.ToolResult{}, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	changes := make([]plannedChange, 0, len(files))
+	touched := map[string]bool{}
+	for _, file := range files {
+		change, err := t.plan(file)
+		if err != nil {
+			return agent.ToolResult{}, fmt.Errorf("%s: %w; no files were changed", file.Path, err)
+		}
+		for _, path := range change.paths() {
+			if touched[path] {
+				return agent.ToolResult{}, fmt.Errorf("%s: file is changed more than once in the patch; no files were changed", file.Path)
+			}
+			touched[path] = true
+		}
+		changes = append(changes, change)
+	}
+
+	// The snapshots restore the files written so far if a later write fails.
+	originals := make([][]fileSnapshot, len(changes))
+	for i, change := range changes {
+		for _, path := range change.paths() {
+			snapshot, err := snapshotFile(path)
+			if err != nil {
+				return agent.ToolResult{}, fmt.Errorf("%s: %w; no files were changed", change.file.Path, err)
+			}
+			originals[i] = append(originals[i], snapshot)
+		}
+	}
+	for i, change := range changes {
+		if err := change.write(); err != nil {
+			if rollbackErr := restoreSnapshots(originals[:i+1]); rollbackErr != nil {
+				return agent.ToolResult{}, fmt.Errorf("%s: %w; restoring the files already changed failed: %v", change.file.Path, err, rollbackErr)
+			}
+			return agent.ToolResult{}, fmt.Errorf("%s: %w; no files were changed", change.file.Path, err)
+		}
+	}
+
+	summary := []string{}
+	results := []map[string]any{}
+	for i, change := range changes {
+		for _, snapshot := range originals[i] {
+			t.checkpoints.add(toolCallID, snapshot)
+		}
+		t.files.forget(change.source)
+		if change.file.Action != patchDelete {
+			t.files.record(change.target, []byte(change.written()))
+		}
+		entry := map[string]any{"path": change.file.Path, "action": change.file.Action}
+		diffPath := change.file.Path
+		switch {
+		case change.file.Action == patchAdd:
+			summary = append(summary, "A "+change.file.Path)
+		case change.file.Action == patchDelete:
+			summary = append(summary, "D "+change.file.Path)
+		case change.file.MoveTo != "":
+			summary = append(summary, fmt.Sprintf("R %s -> %s", change.file.Path, change.file.MoveTo))
+			entry["moveTo"] = change.file.MoveTo
+			diffPath = change.file.MoveTo
+		default:
+			summary = append(summary, "M "+change.file.Path)
+		}
+		diff := generateDiff(diffPath, change.oldContent, change.newContent, contextLines(t.diffContext))
+		entry["diff"] = diff.Text
+		entry["hunks"] = diff.Hunks
+		results = append(results, entry)
+	}
+
+	return agent.ToolResult{
+		Content: []any{
+			model.TextContent{
+				Type: model.ContentText,
+				Text: "Applied patch:\n" + strings.Join(summary, "\n"),
+			},
+		},
+		Details: map[string]any{"files": results},
+	}, nil
+}
+
+// restoreSnapshots puts back the files of the given changes, newest first.
+func restoreSnapshots(changes [][]fileSnapshot) error {
+	var errs []error
+	for i := len(changes) - 1; i >= 0; i-- {
+		for _, snapshot := range changes[i] {
+			errs = append(errs, snapshot.restore())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c plannedChange) paths() []string {
+	paths := []string{}
+	if c.source != "" {
+		paths = append(paths, c.source)
+	}
+	if c.target != "" && c.target != c.source {
+		paths = append(paths, c.target)
+	}
+	return paths
+}
+
+func (t *applyPatchTool) plan(file patchFile) (plannedChange, error) {
+	if strings.TrimSpace(file.Path) == "" {
+		return plannedChange{}, errors.New("missing file path")
+	}
+	source, err := t.paths.resolve(file.Path, accessWrite)
+	if err != nil {
+		return plannedChange{}, err
+	}
+	change := plannedChange{file: file, source: source}
+
+	switch file.Action {
+	case patchAdd:
+		if _, err := os.Stat(source); err == nil {
+			return plannedChange{}, errors.New("file already exists")
+		}
+		change.target = source
+		change.source = ""
+		change.newContent = file.Content
+		return change, nil
+	case patchDelete:
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return plannedChange{}, err
+		}
+		if err := t.files.check(source, file.Path, data, true); err != nil {
+			return plannedChange{}, err
+		}
+		change.oldContent = string(data)
+		return change, nil
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return plannedChange{}, err
+	}
+	if err := t.files.check(source, file.Path, data, false); err != nil {
+		return plannedChange{}, err
+	}
+	original := string(data)
+	change.crlf = strings.Contains(original, "\r\n")
+	change.oldContent = strings.ReplaceAll(original, "\r\n", "\n")
+	change.newContent, err = applyHunks(change.oldContent, file.Hunks)
+	if err != nil {
+		return plannedChange{}, err
+	}
+	change.target = source
+	if file.MoveTo != "" {
+		target, err := t.paths.resolve(file.MoveTo, accessWrite)
+		if err != nil {
+			return plannedChange{}, err
+		}
+		if target != source {
+			if _, err := os.Stat(target); err == nil {
+				return plannedChange{}, fmt.Errorf("move target %s already exists", file.MoveTo)
+			}
+		}
+		change.target = target
+	}
+	return change, nil
+}
+
+// written is the file content as stored on disk.
+func (c plannedChange) written() string {
+	if c.crlf {
+		return strings.ReplaceAll(c.newContent, "\n", "\r\n")
+	}
+	return c.newContent
+}
+
+func (c plannedChange) write() error {
+	if c.file.Action == patchDelete {
+		return os.Remove(c.source)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.target), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.target, []byte(c.written()), 0o644); err != nil {
+		return err
+	}
+	if c.source != "" && c.source != c.target {
+		return os.Remove(c.source)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFileString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestApplyPatchCodexFormat(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"app.py":      "import os\n\ndef main():\n    print('hi')\n    return 0\n\ndef other():\n    return 1\n",
+		"old.txt":     "remove me\n",
+		"src/name.go": "package name\n\nconst Name = \"a\"\n",
+	})
+	patch := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Add File: docs/readme.md",
+		"+# Title",
+		"+",
+		"+body",
+		"*** Update File: app.py",
+		"@@ def main():",
+		"-    print('hi')",
+		"+    print('hello')",
+		"     return 0",
+		"@@ def other():",
+		"-    return 1",
+		"+    return 2",
+		"*** Delete File: old.txt",
+		"*** Update File: src/name.go",
+		"*** Move to: pkg/name.go",
+		"@@",
+		"-const Name = \"a\"",
+		"+const Name = \"b\"",
+		"*** End Patch",
+	}, "\n")
+
+	result, err := NewApplyPatchTool(dir).Execute("p1", map[string]any{"patch": patch})
+	if err != nil {
+		t.Fatalf("apply_patch failed: %v", err)
+	}
+	want := "Applied patch:\nA docs/readme.md\nM app.py\nD old.txt\nR src/name.go -> pkg/name.go"
+	if result.Text() != want {
+		t.Fatalf("unexpected summary:\n%s", result.Text())
+	}
+	if got := readFileString(t, filepath.Join(dir, "docs/readme.md")); got != "# Title\n\nbody\n" {
+		t.Fatalf("unexpected added file: %q", got)
+	}
+	if got := readFileString(t, filepath.Join(dir, "app.py")); got != "import os\n\ndef main():\n    print('hello')\n    return 0\n\ndef other():\n    return 2\n" {
+		t.Fatalf("unexpected updated file: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected old.txt to be deleted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "src/name.go")); !os.IsNotExist(err) {
+		t.Fatalf("expected moved file source to be removed, got %v", err)
+	}
+	if got := readFileString(t, filepath.Join(dir, "pkg/name.go")); got != "package name\n\nconst Name = \"b\"\n" {
+		t.Fatalf("unexpected moved file: %q", got)
+	}
+}
+
+func TestApplyPatchUnifiedDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt": "one\ntwo\nthree\nfour\nfive\n",
+		"b.txt": "gone\n",
+	})
+	patch := strings.Join([]string{
+		"diff --git a/a.txt b/a.txt",
+		"--- a/a.txt",
+		"+++ b/a.txt",
+		"@@ -1,3 +1,3 @@",
+		" one",
+		"-two",
+		"+2",
+		" three",
+		"@@ -5 +5,2 @@",
+		" five",
+		"+six",
+		"--- a/b.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-gone",
+		"--- /dev/null",
+		"+++ b/c.txt",
+		"@@ -0,0 +1,2 @@",
+		"+new",
+		"+file",
+	}, "\n")
+
+	result, err := NewApplyPatchTool(dir).Execute("p1", map[string]any{"patch": patch})
+	if err != nil {
+		t.Fatalf("apply_patch failed: %v", err)
+	}
+	if result.Text() != "Applied patch:\nM a.txt\nD b.txt\nA c.txt" {
+		t.Fatalf("unexpected summary:\n%s", result.Text())
+	}
+	if got := readFileString(t, filepath.Join(dir, "a.txt")); got != "one\n2\nthree\nfour\nfive\nsix\n" {
+		t.Fatalf("unexpected updated file: %q", got)
+	}
+	if got := readFileString(t, filepath.Join(dir, "c.txt")); got != "new\nfile\n" {
+		t.Fatalf("unexpected added file: %q", got)
+	}
+	files := result.Details["files"].([]map[string]any)
+	if !strings.Contains(files[0]["diff"].(string), "-two\n+2") {
+		t.Fatalf("expected diff details, got %#v", files[0])
+	}
+}
+
+func TestApplyPatchUnifiedDiffHunksShiftLines(t *testing.T) {
+	cases := []struct {
+		name     string
+		original string
+		hunks    []string
+		want     string
+	}{
+		{
+			name:  "zero context inserts",
+			hunks: []string{"@@ -1,0 +2,2 @@", "+X", "+Y", "@@ -4,0 +7 @@", "+Z"},
+			want:  "a\nX\nY\nb\nc\nd\nZ\ne\nf\n",
+		},
+		{
+			name:  "delete then replace",
+			hunks: []string{"@@ -1,2 +0,0 @@", "-a", "-b", "@@ -5 +3,2 @@", "-e", "+E", "+E2"},
+			want:  "c\nd\nE\nE2\nf\n",
+		},
+		{
+			name:     "repeated lines",
+			original: "a\nb\nc\nc\ne\nf\n",
+			hunks:    []string{"@@ -2,0 +3,2 @@", "+c", "+d", "@@ -4 +6 @@", "-c", "+C"},
+			want:     "a\nb\nc\nd\nc\nC\ne\nf\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			original := tc.original
+			if original == "" {
+				original = "a\nb\nc\nd\ne\nf\n"
+			}
+			writeTree(t, dir, map[string]string{"a.txt": original})
+			patch := strings.Join(append([]string{"--- a/a.txt", "+++ b/a.txt"}, tc.hunks...), "\n")
+			if _, err := NewApplyPatchTool(dir).Execute("p1", map[string]any{"patch": patch}); err != nil {
+				t.Fatalf("apply_patch failed: %v", err)
+			}
+			if got := readFileString(t, filepath.Join(dir, "a.txt")); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyPatchValidatesBeforeWriting(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n"})
+	tool := NewApplyPatchTool(dir)
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{
+			name:  "missing context",
+			patch: "*** Begin Patch\n*** Update File: a.txt\n-alpha\n+ALPHA\n*** Update File: b.txt\n-missing\n+x\n*** End Patch",
+			want:  "could not find lines to replace",
+		},
+		{
+			name:  "path escape",
+			patch: "*** Begin Patch\n*** Update File: a.txt\n-alpha\n+ALPHA\n*** Add File: ../evil.txt\n+x\n*** End Patch",
+			want:  "path escapes working directory",
+		},
+		{
+			name:  "add existing",
+			patch: "*** Begin Patch\n*** Update File: a.txt\n-alpha\n+ALPHA\n*** Add File: b.txt\n+x\n*** End Patch",
+			want:  "file already exists",
+		},
+		{
+			name:  "unterminated",
+			patch: "*** Begin Patch\n*** Update File: a.txt\n-alpha\n+ALPHA",
+			want:  "missing *** End Patch",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tool.Execute("p", map[string]any{"patch": tc.patch})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected %q error, got %v", tc.want, err)
+			}
+			if got := readFileString(t, filepath.Join(dir, "a.txt")); got != "alpha\n" {
+				t.Fatalf("a.txt changed by a rejected patch: %q", got)
+			}
+		})
+	}
+}
+
+func TestApplyPatchRollsBackWhenAWriteFails(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n", "blocker": "not a directory\n"})
+	tool := NewApplyPatchTool(dir)
+
+	// Every hunk applies, but blocker/new.txt cannot be created.
+	patch := "*** Begin Patch\n*** Update File: a.txt\n-alpha\n+ALPHA\n*** Delete File: b.txt\n" +
+		"*** Add File: c.txt\n+gamma\n*** Add File: blocker/new.txt\n+x\n*** End Patch"
+	_, err := tool.Execute("p", map[string]any{"patch": patch})
+	if err == nil || !strings.Contains(err.Error(), "no files were changed") {
+		t.Fatalf("expected write failure, got %v", err)
+	}
+	if got := readFileString(t, filepath.Join(dir, "a.txt")); got != "alpha\n" {
+		t.Fatalf("a.txt not restored: %q", got)
+	}
+	if got := readFileString(t, filepath.Join(dir, "b.txt")); got != "beta\n" {
+		t.Fatalf("b.txt not restored: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c.txt")); !os.IsNotExist(err) {
+		t.Fatalf("c.txt should have been removed: %v", err)
+	}
+}
+
+func TestApplyPatchToleratesWhitespaceDrift(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.go": "func f() {\n\treturn 1   \n}\n"})
+	patch := "*** Begin Patch\n*** Update File: a.go\n func f() {\n-    return 1\n+\treturn 2\n }\n*** End Patch"
+	if _, err := NewApplyPatchTool(dir).Execute("p", map[string]any{"patch": patch}); err != nil {
+		t.Fatalf("apply_patch failed: %v", err)
+	}
+	if got := readFileString(t, filepath.Join(dir, "a.go")); got != "func f() {\n\treturn 2\n}\n" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+}
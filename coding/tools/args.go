@@ -3,7 +3,6 @@ package tools
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -19,28 +18,6 @@ func defaultCWD(cwd string) string {
 	return dir
 }
 
-func resolveSafePath(cwd, input string) (string, error) {
-	base, err := filepath.Abs(cwd)
-	if err != nil {
-		return "", err
-	}
-
-	target := input
-	if !filepath.IsAbs(target) {
-		target = filepath.Join(base, target)
-	}
-	target, err = filepath.Abs(target)
-	if err != nil {
-		return "", err
-	}
-
-	sep := string(os.PathSeparator)
-	if target != base && !strings.HasPrefix(target, base+sep) {
-		return "", fmt.Errorf("path escapes working directory: %s", input)
-	}
-	return target, nil
-}
-
 func toStringArg(args map[string]any, key string) (string, bool) {
 	raw, ok := args[key]
 	if !ok {
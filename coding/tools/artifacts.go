@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultArtifactMaxBytes = 256 << 20
+	defaultArtifactMaxAge   = 24 * time.Hour
+)
+
+// ArtifactOptions configures NewArtifactStore.
+type ArtifactOptions struct {
+	// Dir is shared by all sessions; each store writes to its own
+	// subdirectory. Defaults to phi-artifacts in os.TempDir().
+	Dir string
+	// MaxBytes caps the size of each session's artifacts; the oldest go
+	// first.
+	MaxBytes int64
+	// MaxAge removes artifacts older than this, and the directories of
+	// other sessions once all their artifacts are older than this.
+	MaxAge time.Duration
+}
+
+// ArtifactStore keeps the full output of truncated tool results so the model
+// can page through it with read. Each store is one session's directory; it is
+// created on first use and pruned by the retention limits on every save.
+type ArtifactStore struct {
+	root     string
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+
+	mu   sync.Mutex
+	next int
+}
+
+var (
+	defaultStoreOnce sync.Once
+	defaultStore     *ArtifactStore
+)
+
+// defaultArtifacts is used by tools built outside NewCodingToolsWithOptions.
+func defaultArtifacts() *ArtifactStore {
+	defaultStoreOnce.Do(func() {
+		defaultStore = NewArtifactStore(ArtifactOptions{})
+	})
+	return defaultStore
+}
+
+func NewArtifactStore(options ArtifactOptions) *ArtifactStore {
+	root := options.Dir
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "phi-artifacts")
+	}
+	store := &ArtifactStore{
+		root:     absPath(root),
+		maxBytes: options.MaxBytes,
+		maxAge:   options.MaxAge,
+	}
+	if store.maxBytes <= 0 {
+		store.maxBytes = defaultArtifactMaxBytes
+	}
+	if store.maxAge <= 0 {
+		store.maxAge = defaultArtifactMaxAge
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	store.dir = filepath.Join(store.root, fmt.Sprintf("session-%s-%s", time.Now().Format("20060102-150405"), hex.EncodeToString(suffix)))
+	return store
+}
+
+// Dir is the session directory; it may not exist until the first Save.
+func (s *ArtifactStore) Dir() string {
+	return s.dir
+}
+
+// Save writes content to a new artifact named after kind and returns its path.
+func (s *ArtifactStore) Save(kind, content string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", err
+	}
+	s.next++
+	path := filepath.Join(s.dir, fmt.Sprintf("%s-%d.log", kind, s.next))
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return "", err
+	}
+	s.prune(path)
+	return path, nil
+}
+
+// Close removes the session directory.
+func (s *ArtifactStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.RemoveAll(s.dir)
+}
+
+// spill saves content and returns the artifact path along with a notice for
+// the tool output.
+func (s *ArtifactStore) spill(kind, content string) (string, string) {
+	path, err := s.Save(kind, content)
+	if err != nil {
+		return "", fmt.Sprintf("Full output could not be saved: %v", err)
+	}
+	return path, fmt.Sprintf("Full output: %s (use read with offset/limit)", path)
+}
+
+// prune applies the retention limits to this session's artifacts and removes
+// other sessions' directories that have expired. keep is never removed.
+func (s *ArtifactStore) prune(keep string) {
+	type artifact struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	cutoff := time.Now().Add(-s.maxAge)
+	entries, _ := os.ReadDir(s.root)
+	for _, entry := range entries {
+		dir := filepath.Join(s.root, entry.Name())
+		if entry.IsDir() && dir != s.dir && expiredDir(dir, cutoff) {
+			_ = os.RemoveAll(dir)
+		}
+	}
+
+	artifacts := []artifact{}
+	entries, _ = os.ReadDir(s.dir)
+	for _, entry := range entries {
+		path := filepath.Join(s.dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || path == keep {
+			continue
+		}
+		if info.ModTime().Before(cutoff) {
+			_ = os.Remove(path)
+			continue
+		}
+		artifacts = append(artifacts, artifact{path: path, size: info.Size(), modTime: info.ModTime()})
+	}
+
+	var total int64
+	if info, err := os.Stat(keep); err == nil {
+		total = info.Size()
+	}
+	for _, a := range artifacts {
+		total += a.size
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].modTime.Before(artifacts[j].modTime) })
+	for _, a := range artifacts {
+		if total <= s.maxBytes {
+			break
+		}
+		if os.Remove(a.path) == nil {
+			total -= a.size
+		}
+	}
+}
+
+// expiredDir reports whether nothing under dir was modified after cutoff. A
+// live session keeps writing to its directory, so only abandoned ones expire.
+func expiredDir(dir string, cutoff time.Time) bool {
+	expired := true
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(cutoff) {
+			expired = false
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return expired
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArtifactStoreRetention(t *testing.T) {
+	root := t.TempDir()
+	oldSession := filepath.Join(root, "session-old")
+	writeTree(t, oldSession, map[string]string{"bash-1.log": "stale"})
+	past := time.Now().Add(-48 * time.Hour)
+	for _, path := range []string{filepath.Join(oldSession, "bash-1.log"), oldSession} {
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another live session is over the size limit on its own; its
+	// artifacts are not this store's to prune.
+	liveSession := filepath.Join(root, "session-live")
+	writeTree(t, liveSession, map[string]string{"bash-1.log": strings.Repeat("l", 100)})
+
+	store := NewArtifactStore(ArtifactOptions{Dir: root, MaxBytes: 25})
+	first, err := store.Save("bash", strings.Repeat("a", 10))
+	if err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if !strings.HasPrefix(first, store.Dir()+string(os.PathSeparator)) {
+		t.Fatalf("artifact %s outside session dir %s", first, store.Dir())
+	}
+	if _, err := os.Stat(oldSession); !os.IsNotExist(err) {
+		t.Fatalf("expected expired session to be removed, got %v", err)
+	}
+
+	// Make the first artifact clearly older so size pruning picks it.
+	earlier := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(first, earlier, earlier); err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.Save("grep", strings.Repeat("b", 10))
+	if err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	third, err := store.Save("grep", strings.Repeat("c", 10))
+	if err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Fatalf("expected oldest artifact to be pruned, got %v", err)
+	}
+	for _, path := range []string{second, third, filepath.Join(liveSession, "bash-1.log")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %s to be kept: %v", path, err)
+		}
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := os.Stat(store.Dir()); !os.IsNotExist(err) {
+		t.Fatalf("expected session dir to be removed, got %v", err)
+	}
+}
+
+func TestTruncatedOutputIsReadableArtifact(t *testing.T) {
+	dir := t.TempDir()
+	lines := make([]string, 3000)
+	for i := range lines {
+		lines[i] = "match " + strings.Repeat("x", 10)
+	}
+	writeTree(t, dir, map[string]string{
+		"many.txt": strings.Join(lines, "\n") + "\n",
+		"wide.txt": strings.Repeat("é", 3000) + "\n",
+	})
+	store := NewArtifactStore(ArtifactOptions{Dir: t.TempDir()})
+	byName := map[string]int{}
+	toolset := NewCodingToolsWithOptions(dir, Options{Artifacts: store})
+	for i, tool := range toolset {
+		byName[tool.Name()] = i
+	}
+	read := toolset[byName["read"]]
+
+	cases := []struct {
+		tool string
+		args map[string]any
+	}{
+		{"bash", map[string]any{"command": "seq 1 3000"}},
+		{"grep", map[string]any{"pattern": "match", "limit": 5000}},
+		{"read", map[string]any{"path": "wide.txt", "max_bytes": 100}},
+	}
+	for _, tc := range cases {
+		result, err := toolset[byName[tc.tool]].Execute("t", tc.args)
+		if err != nil {
+			t.Fatalf("%s failed: %v", tc.tool, err)
+		}
+		artifact, _ := result.Details["artifact"].(string)
+		if filepath.Dir(artifact) != store.Dir() || !strings.Contains(result.Text(), "Full output: "+artifact) {
+			t.Fatalf("%s: expected artifact in store, got %q\n%s", tc.tool, artifact, result.Text())
+		}
+		page, err := read.Execute("r", map[string]any{"path": artifact, "offset": 2, "limit": 1})
+		if err != nil {
+			t.Fatalf("%s: reading artifact failed: %v", tc.tool, err)
+		}
+		if strings.TrimSpace(page.Text()) == "" {
+			t.Fatalf("%s: empty artifact page", tc.tool)
+		}
+	}
+}
+
+func TestClosingToolsetRemovesOwnArtifacts(t *testing.T) {
+	byName := toolsByName(NewCodingTools(t.TempDir()))
+	result, err := byName["bash"].Execute("t", map[string]any{"command": "seq 1 3000"})
+	if err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+	artifact, _ := result.Details["artifact"].(string)
+	if _, err := os.Stat(artifact); err != nil {
+		t.Fatalf("expected artifact to exist: %v", err)
+	}
+	if err := byName["bash"].(io.Closer).Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(artifact)); !os.IsNotExist(err) {
+		t.Fatalf("expected session artifacts to be removed, got %v", err)
+	}
+}
@@ -1,26 +1,73 @@
 package tools
 
 import (
+	"bytes"
 	"context"
-	"crypto/rand"
+	"errors"
 	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/zahlmann/phi/agent"
 	"github.com/zahlmann/phi/ai/model"
 )
 
+const bashWaitDelay = 2 * time.Second
+
 type bashTool struct {
-	cwd     string
-	timeout time.Duration
+	cwd       string
+	timeout   time.Duration
+	executor  Executor
+	shell     *persistentShell
+	processes *processTable
+	artifacts *ArtifactStore
+	// ownsArtifacts is set when the toolset created the store, so closing
+	// the tool removes the session's artifacts.
+	ownsArtifacts bool
 }
 
 func NewBashTool(cwd string, timeout time.Duration) agent.Tool {
-	return &bashTool{cwd: defaultCWD(cwd), timeout: timeout}
+	return NewBashToolWithExecutor(cwd, timeout, nil)
+}
+
+// NewBashToolWithExecutor runs commands through executor; nil means HostExecutor.
+func NewBashToolWithExecutor(cwd string, timeout time.Duration, executor Executor) agent.Tool {
+	if executor == nil {
+		executor = HostExecutor{}
+	}
+	return &bashTool{
+		cwd:       defaultCWD(cwd),
+		timeout:   timeout,
+		executor:  executor,
+		processes: newProcessTable(),
+		artifacts: defaultArtifacts(),
+	}
+}
+
+// NewPersistentBashTool runs every command in one long-lived shell, so cd and
+// exported variables carry over between calls. A timeout interrupts only the
+// running command. The returned tool implements io.Closer; close it when the
+// session ends.
+func NewPersistentBashTool(cwd string, timeout time.Duration, executor Executor) agent.Tool {
+	tool := NewBashToolWithExecutor(cwd, timeout, executor).(*bashTool)
+	tool.shell = newPersistentShell(tool.cwd, tool.executor)
+	return tool
+}
+
+// Close stops the persistent shell and every background process, and removes
+// the artifact store the toolset created.
+func (t *bashTool) Close() error {
+	t.processes.Close()
+	var errs []error
+	if t.shell != nil {
+		errs = append(errs, t.shell.Close())
+	}
+	if t.ownsArtifacts {
+		errs = append(errs, t.artifacts.Close())
+	}
+	return errors.Join(errs...)
 }
 
 func (t *bashTool) Name() string {
@@ -42,6 +89,11 @@ func (t *bashTool) Parameters() map[string]any {
 			"timeout": map[string]any{
 				"type":        "number",
 				"description": "Timeout in seconds (optional, no default timeout)",
+				"minimum":     0,
+			},
+			"background": map[string]any{
+				"type":        "boolean",
+				"description": "Start the command in the background and return a process id for process_output and process_kill",
 			},
 		},
 		"required": []string{"command"},
@@ -49,10 +101,30 @@ func (t *bashTool) Parameters() map[string]any {
 }
 
 func (t *bashTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *bashTool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteStreaming(ctx, toolCallID, args, nil)
+}
+
+// ExecuteStreaming reports output while the command runs. Each update carries
+// the rolling tail of the output as content and the new chunk in
+// Details["delta"], labelled stdout or stderr in Details["stream"]. A
+// persistent shell merges both streams and labels them "combined".
+func (t *bashTool) ExecuteStreaming(
+	parent context.Context,
+	toolCallID string,
+	args map[string]any,
+	onUpdate agent.ToolUpdateFunc,
+) (agent.ToolResult, error) {
 	command, ok := toStringArg(args, "command")
 	if !ok || strings.TrimSpace(command) == "" {
 		return agent.ToolResult{}, fmt.Errorf("missing required argument: command")
 	}
+	if background, _ := args["background"].(bool); background {
+		return t.startBackground(command)
+	}
 
 	timeout := t.timeout
 	if raw, ok := args["timeout"]; ok {
@@ -60,18 +132,58 @@ func (t *bashTool) Execute(toolCallID string, args map[string]any) (agent.ToolRe
 			timeout = time.Duration(secs * float64(time.Second))
 		}
 	}
-	ctx := context.Background()
-	cancel := func() {}
+	var ctx context.Context
+	var cancel context.CancelFunc
 	if timeout > 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
 	}
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "bash", "-lc", command)
-	cmd.Dir = t.cwd
-	output, err := cmd.CombinedOutput()
+	details := map[string]any{"command": command, "cwd": t.cwd}
+	stream := &bashStream{onUpdate: onUpdate}
+	var output string
+	var exitCode int
+	var err error
+	if t.shell != nil {
+		var onLine func(string)
+		if onUpdate != nil {
+			onLine = func(line string) { stream.write("combined", []byte(line)) }
+		}
+		var res shellResult
+		if res, err = t.shell.run(ctx, command, onLine); err != nil {
+			return agent.ToolResult{}, err
+		}
+		output, exitCode = res.output, res.exitCode
+		details["cwd"] = res.cwd
+		details["exitCode"] = res.exitCode
+		if res.cwd != res.previousCwd {
+			details["previousCwd"] = res.previousCwd
+		}
+		if res.exited {
+			details["shellExited"] = true
+			output += "\n[Shell exited; the next command starts a new shell]"
+		}
+	} else {
+		cmd, cmdErr := t.executor.Command(ctx, command, t.cwd)
+		if cmdErr != nil {
+			return agent.ToolResult{}, cmdErr
+		}
+		// Background children can keep the output pipe open after bash is killed.
+		cmd.WaitDelay = bashWaitDelay
+		// Equal writers share one pipe and keep the exact interleaving.
+		cmd.Stdout = bashStreamWriter{name: "output", out: stream}
+		cmd.Stderr = bashStreamWriter{name: "output", out: stream}
+		if onUpdate != nil {
+			cmd.Stdout = bashStreamWriter{name: "stdout", out: stream}
+			cmd.Stderr = bashStreamWriter{name: "stderr", out: stream}
+		}
+		err = cmd.Run()
+		output, exitCode = stream.String(), exitCodeOf(err)
+	}
 
-	fullOutput := strings.ReplaceAll(string(output), "\r\n", "\n")
+	fullOutput := strings.ReplaceAll(output, "\r\n", "\n")
 	fullOutput = strings.ReplaceAll(fullOutput, "\r", "\n")
 	trunc := truncateTail(fullOutput, defaultMaxLines, defaultMaxBytes)
 	outputText := trunc.Content
@@ -81,66 +193,130 @@ func (t *bashTool) Execute(toolCallID string, args map[string]any) (agent.ToolRe
 
 	var fullOutputPath string
 	if trunc.Truncated {
-		fullOutputPath = tempOutputFilePath("phi-bash")
-		_ = os.WriteFile(fullOutputPath, []byte(fullOutput), 0o600)
+		var notice string
+		fullOutputPath, notice = t.artifacts.spill("bash", fullOutput)
 
 		startLine := trunc.TotalLines - trunc.OutputLines + 1
 		endLine := trunc.TotalLines
 		if trunc.LastLinePartial {
 			lastLineSize := formatSize(byteLen(lastLine(fullOutput)))
 			outputText += fmt.Sprintf(
-				"\n\n[Showing last %s of line %d (line is %s). Full output: %s]",
+				"\n\n[Showing last %s of line %d (line is %s). %s]",
 				formatSize(trunc.OutputBytes),
 				endLine,
 				lastLineSize,
-				fullOutputPath,
+				notice,
 			)
 		} else if trunc.TruncatedBy == "lines" {
 			outputText += fmt.Sprintf(
-				"\n\n[Showing lines %d-%d of %d. Full output: %s]",
-				startLine, endLine, trunc.TotalLines, fullOutputPath,
+				"\n\n[Showing lines %d-%d of %d. %s]",
+				startLine, endLine, trunc.TotalLines, notice,
 			)
 		} else {
 			outputText += fmt.Sprintf(
-				"\n\n[Showing lines %d-%d of %d (%s limit). Full output: %s]",
-				startLine, endLine, trunc.TotalLines, formatSize(defaultMaxBytes), fullOutputPath,
+				"\n\n[Showing lines %d-%d of %d (%s limit). %s]",
+				startLine, endLine, trunc.TotalLines, formatSize(defaultMaxBytes), notice,
 			)
 		}
 	}
 
-	if ctx.Err() == context.DeadlineExceeded {
+	if parent.Err() != nil {
+		outputText += "\n\nCommand cancelled"
+		err = fmt.Errorf("command cancelled: %w", parent.Err())
+	} else if ctx.Err() == context.DeadlineExceeded {
 		outputText += fmt.Sprintf("\n\nCommand timed out after %.1f seconds", timeout.Seconds())
 		err = fmt.Errorf("command timed out")
 	}
 
 	result := agent.ToolResult{
-		Content: []model.TextContent{
-			{Type: model.ContentText, Text: outputText},
-		},
-		Details: map[string]any{
-			"command": command,
-			"cwd":     t.cwd,
-			"truncation": func() any {
-				if trunc.Truncated {
-					return trunc.toMap()
-				}
-				return nil
-			}(),
-			"fullOutputPath": fullOutputPath,
+		Content: []any{
+			model.TextContent{Type: model.ContentText, Text: outputText},
 		},
+		Details: details,
+	}
+	details["truncation"] = nil
+	if trunc.Truncated {
+		details["truncation"] = trunc.toMap()
+	}
+	details["fullOutputPath"] = fullOutputPath
+	if fullOutputPath != "" {
+		details["artifact"] = fullOutputPath
 	}
-	if exitCode := exitCodeOf(err); exitCode != 0 && ctx.Err() == nil {
+	if exitCode != 0 && ctx.Err() == nil {
 		return result, fmt.Errorf("%s\n\nCommand exited with code %d", outputText, exitCode)
 	}
 	return result, err
 }
 
-func tempOutputFilePath(prefix string) string {
-	buf := make([]byte, 8)
-	if _, err := rand.Read(buf); err != nil {
-		return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d.log", prefix, time.Now().UnixNano()))
+// bashStream collects command output and reports every chunk to onUpdate
+// together with a rolling tail of everything written so far.
+type bashStream struct {
+	mu       sync.Mutex
+	full     bytes.Buffer
+	tail     []byte
+	onUpdate agent.ToolUpdateFunc
+}
+
+type bashStreamWriter struct {
+	name string
+	out  *bashStream
+}
+
+func (w bashStreamWriter) Write(p []byte) (int, error) {
+	w.out.write(w.name, p)
+	return len(p), nil
+}
+
+func (s *bashStream) write(name string, p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.full.Write(p)
+	if s.onUpdate == nil {
+		return
 	}
-	return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%x.log", prefix, buf))
+	s.tail = append(s.tail, p...)
+	if over := len(s.tail) - defaultMaxBytes; over > 0 {
+		s.tail = append([]byte(nil), s.tail[over:]...)
+	}
+	tail := truncateTail(strings.ReplaceAll(string(s.tail), "\r\n", "\n"), defaultMaxLines, defaultMaxBytes)
+	s.onUpdate(agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: tail.Content}},
+		Details: map[string]any{"stream": name, "delta": string(p)},
+	})
+}
+
+func (s *bashStream) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.full.String()
+}
+
+func (t *bashTool) startBackground(command string) (agent.ToolResult, error) {
+	dir := t.cwd
+	if t.shell != nil {
+		dir = t.shell.currentDir()
+	}
+	proc, err := t.processes.start(t.executor, command, dir)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	return agent.ToolResult{
+		Content: []any{
+			model.TextContent{
+				Type: model.ContentText,
+				Text: fmt.Sprintf(
+					"Started background process %s (pid %d). Use process_output to read its output and process_kill to stop it.",
+					proc.id, proc.pid,
+				),
+			},
+		},
+		Details: map[string]any{
+			"command": command,
+			"cwd":     dir,
+			"id":      proc.id,
+			"pid":     proc.pid,
+		},
+	}, nil
 }
 
 func lastLine(s string) string {
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// CheckpointStore keeps the contents files had before write, edit and
+// apply_patch changed them, keyed by tool call ID, so a session can undo
+// those changes. Snapshots live in memory for the lifetime of the store.
+type CheckpointStore struct {
+	mu        sync.Mutex
+	snapshots []fileSnapshot
+	files     *fileTracker
+}
+
+type fileSnapshot struct {
+	toolCallID string
+	path       string
+	existed    bool
+	data       []byte
+	mode       fs.FileMode
+}
+
+func NewCheckpointStore() *CheckpointStore {
+	return &CheckpointStore{}
+}
+
+// capture snapshots path before toolCallID changes it. A nil store does
+// nothing.
+func (s *CheckpointStore) capture(toolCallID, path string) error {
+	if s == nil {
+		return nil
+	}
+	snapshot, err := snapshotFile(path)
+	if err != nil {
+		return err
+	}
+	s.add(toolCallID, snapshot)
+	return nil
+}
+
+func (s *CheckpointStore) add(toolCallID string, snapshot fileSnapshot) {
+	if s == nil {
+		return
+	}
+	snapshot.toolCallID = toolCallID
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = append(s.snapshots, snapshot)
+}
+
+// snapshotFile records the current contents of path, or that it does not
+// exist.
+func snapshotFile(path string) (fileSnapshot, error) {
+	snapshot := fileSnapshot{path: path}
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fileSnapshot{}, err
+		}
+		snapshot.existed = true
+		snapshot.data = data
+		snapshot.mode = info.Mode().Perm()
+	case !missingPath(err):
+		return fileSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// missingPath reports whether err means the path does not exist, including
+// when a parent is not a directory.
+func missingPath(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR)
+}
+
+// ToolCalls returns the IDs of the tool calls that changed files, oldest
+// first.
+func (s *CheckpointStore) ToolCalls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, snapshot := range s.snapshots {
+		if !seen[snapshot.toolCallID] {
+			seen[snapshot.toolCallID] = true
+			ids = append(ids, snapshot.toolCallID)
+		}
+	}
+	return ids
+}
+
+// Revert undoes the changes of every tool call not in keep, newest first, so
+// each file ends up as it was before the first reverted change. It returns
+// the restored paths. A snapshot that cannot be restored does not stop the
+// others; it is kept for a retry and its error is joined into the result.
+func (s *CheckpointStore) Revert(keep []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := map[string]bool{}
+	for _, id := range keep {
+		kept[id] = true
+	}
+	restored := []string{}
+	seen := map[string]bool{}
+	var errs []error
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		snapshot := s.snapshots[i]
+		if kept[snapshot.toolCallID] {
+			continue
+		}
+		if err := snapshot.restore(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if snapshot.existed {
+			s.files.record(snapshot.path, snapshot.data)
+		} else {
+			s.files.forget(snapshot.path)
+		}
+		s.snapshots = append(s.snapshots[:i], s.snapshots[i+1:]...)
+		if !seen[snapshot.path] {
+			seen[snapshot.path] = true
+			restored = append(restored, snapshot.path)
+		}
+	}
+	return restored, errors.Join(errs...)
+}
+
+func (f fileSnapshot) restore() error {
+	if !f.existed {
+		if err := os.Remove(f.path); err != nil && !missingPath(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(f.path, f.data, f.mode); err != nil {
+		return err
+	}
+	return os.Chmod(f.path, f.mode)
+}
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpointsRevertToolCalls(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "one\n", "gone.txt": "bye\n"})
+	store := NewCheckpointStore()
+	tools := toolsByName(NewCodingToolsWithOptions(dir, Options{Checkpoints: store}))
+
+	for _, path := range []string{"a.txt", "gone.txt"} {
+		if _, err := tools["read"].Execute("r", map[string]any{"path": path}); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+	}
+	if _, err := tools["edit"].Execute("c1", map[string]any{"path": "a.txt", "oldText": "one", "newText": "two"}); err != nil {
+		t.Fatalf("edit failed: %v", err)
+	}
+	if _, err := tools["write"].Execute("c2", map[string]any{"path": "a.txt", "content": "three\n"}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := tools["write"].Execute("c3", map[string]any{"path": "sub/new.txt", "content": "new\n"}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	patch := "*** Begin Patch\n*** Delete File: gone.txt\n*** End Patch\n"
+	if _, err := tools["apply_patch"].Execute("c4", map[string]any{"patch": patch}); err != nil {
+		t.Fatalf("apply_patch failed: %v", err)
+	}
+	if got := store.ToolCalls(); !reflect.DeepEqual(got, []string{"c1", "c2", "c3", "c4"}) {
+		t.Fatalf("unexpected tool calls: %v", got)
+	}
+
+	restored, err := store.Revert([]string{"c1"})
+	if err != nil {
+		t.Fatalf("revert failed: %v", err)
+	}
+	if len(restored) != 3 {
+		t.Fatalf("expected 3 restored paths, got %v", restored)
+	}
+	assertFile(t, filepath.Join(dir, "a.txt"), "two\n")
+	assertFile(t, filepath.Join(dir, "gone.txt"), "bye\n")
+	if _, err := os.Stat(filepath.Join(dir, "sub", "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected created file to be removed, got %v", err)
+	}
+	if got := store.ToolCalls(); !reflect.DeepEqual(got, []string{"c1"}) {
+		t.Fatalf("expected only the kept tool call, got %v", got)
+	}
+
+	// Restored files count as seen, so editing them again needs no re-read.
+	if _, err := tools["edit"].Execute("c5", map[string]any{"path": "a.txt", "oldText": "two", "newText": "four"}); err != nil {
+		t.Fatalf("edit after revert failed: %v", err)
+	}
+	if _, err := store.Revert(nil); err != nil {
+		t.Fatalf("revert failed: %v", err)
+	}
+	assertFile(t, filepath.Join(dir, "a.txt"), "one\n")
+}
+
+func TestCheckpointsRevertContinuesPastFailures(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "one\n", "d/b.txt": "one\n"})
+	store := NewCheckpointStore()
+	tools := toolsByName(NewCodingToolsWithOptions(dir, Options{Checkpoints: store, FileChecks: FileChecksOff}))
+	for _, path := range []string{"d/b.txt", "a.txt"} {
+		if _, err := tools["write"].Execute("c-"+path, map[string]any{"path": path, "content": "two\n"}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	// A file where the directory was makes d/b.txt impossible to restore.
+	if err := os.RemoveAll(filepath.Join(dir, "d")); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dir, map[string]string{"d": "blocked\n"})
+
+	restored, err := store.Revert(nil)
+	if err == nil {
+		t.Fatal("expected restore error")
+	}
+	if !reflect.DeepEqual(restored, []string{filepath.Join(dir, "a.txt")}) {
+		t.Fatalf("expected a.txt to be restored despite the failure, got %v", restored)
+	}
+	assertFile(t, filepath.Join(dir, "a.txt"), "one\n")
+	if got := store.ToolCalls(); !reflect.DeepEqual(got, []string{"c-d/b.txt"}) {
+		t.Fatalf("expected the failed snapshot to be kept, got %v", got)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "d")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Revert(nil); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	assertFile(t, filepath.Join(dir, "d", "b.txt"), "one\n")
+}
+
+func TestCheckpointsSkipFailedChanges(t *testing.T) {
+	dir := t.TempDir()
+	store := NewCheckpointStore()
+	tools := toolsByName(NewCodingToolsWithOptions(dir, Options{Checkpoints: store}))
+	if _, err := tools["edit"].Execute("c1", map[string]any{"path": "missing.txt", "oldText": "a", "newText": "b"}); err == nil {
+		t.Fatal("expected edit of a missing file to fail")
+	}
+	if got := store.ToolCalls(); len(got) != 0 {
+		t.Fatalf("expected no checkpoints, got %v", got)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s failed: %v", path, err)
+	}
+	if string(data) != want {
+		t.Fatalf("expected %s to contain %q, got %q", path, want, data)
+	}
+}
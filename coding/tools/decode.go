@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	encodingUTF8    = "utf-8"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+
+	hexPreviewBytes = 256
+	maxPDFStream    = 16 << 20
+)
+
+// decodeText returns data as UTF-8 text and the encoding it was stored in.
+// A BOM or the zero byte pattern of ASCII-range UTF-16 selects UTF-16; ok is
+// false when the content looks binary.
+func decodeText(data []byte) (text string, encoding string, ok bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], binary.LittleEndian), encodingUTF16LE, true
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], binary.BigEndian), encodingUTF16BE, true
+	default:
+		if order, name, ok := sniffUTF16(data); ok {
+			return decodeUTF16(data, order), name, true
+		}
+	}
+	if looksBinary(data) {
+		return "", "", false
+	}
+	return strings.ToValidUTF8(string(data), "�"), encodingUTF8, true
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// sniffUTF16 recognises BOM-less UTF-16 text whose sample is mostly ASCII,
+// where every other byte is zero.
+func sniffUTF16(data []byte) (binary.ByteOrder, string, bool) {
+	sample := data[:minInt(len(data), binarySniffLength)]
+	if len(sample) < 4 || len(sample)%2 != 0 {
+		return nil, "", false
+	}
+	evenZeros, oddZeros := 0, 0
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case oddZeros*10 >= pairs*9 && evenZeros == 0:
+		return binary.LittleEndian, encodingUTF16LE, true
+	case evenZeros*10 >= pairs*9 && oddZeros == 0:
+		return binary.BigEndian, encodingUTF16BE, true
+	}
+	return nil, "", false
+}
+
+// looksBinary reports NUL bytes or a high share of invalid UTF-8 and control
+// characters in the first bytes of data.
+func looksBinary(data []byte) bool {
+	sample := data[:minInt(len(data), binarySniffLength)]
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	suspicious := 0
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// A sequence cut off by the sample boundary is not suspicious.
+			if len(sample)-i >= utf8.UTFMax {
+				suspicious++
+			}
+		case r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f' && r != '\b' && r != 0x1b:
+			suspicious++
+		}
+		i += size
+	}
+	return suspicious*10 > len(sample)
+}
+
+func hexPreview(data []byte) string {
+	return strings.TrimSuffix(hex.Dump(data[:minInt(len(data), hexPreviewBytes)]), "\n")
+}
+
+var (
+	pdfStreamPattern = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+	pdfTextOperator  = regexp.MustCompile(`(?s)\((?:\\.|[^\\)])*\)\s*(?:Tj|'|")|\[(?:\\.|[^\]])*\]\s*TJ|T\*|ET|Td|TD`)
+	pdfStringPattern = regexp.MustCompile(`(?s)\((?:\\.|[^\\)])*\)`)
+)
+
+// extractPDFText pulls the text drawn by the Tj, TJ, ' and " operators out of
+// the content streams of a simple PDF. Fonts with custom encodings, object
+// streams and encryption are not supported; an empty result means no text
+// could be recovered.
+func extractPDFText(data []byte) string {
+	var out strings.Builder
+	for _, loc := range pdfStreamPattern.FindAllSubmatchIndex(data, -1) {
+		dict := string(data[loc[2]:loc[3]])
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := data[start : start+end]
+		if strings.Contains(dict, "/Subtype/Image") || strings.Contains(dict, "/Subtype /Image") {
+			continue
+		}
+		content := raw
+		if strings.Contains(dict, "/FlateDecode") {
+			reader, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			decoded, err := io.ReadAll(io.LimitReader(reader, maxPDFStream))
+			reader.Close()
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			content = decoded
+		} else if strings.Contains(dict, "/Filter") {
+			continue
+		}
+		appendPDFText(&out, content)
+	}
+	return strings.TrimSpace(out.String())
+}
+
+func appendPDFText(out *strings.Builder, content []byte) {
+	for _, op := range pdfTextOperator.FindAll(content, -1) {
+		token := string(op)
+		switch {
+		case token == "ET" || token == "T*" || token == "Td" || token == "TD":
+			if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+				out.WriteByte('\n')
+			}
+		default:
+			if strings.HasSuffix(token, "'") || strings.HasSuffix(token, "\"") {
+				if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+					out.WriteByte('\n')
+				}
+			}
+			for _, literal := range pdfStringPattern.FindAllString(token, -1) {
+				out.WriteString(unescapePDFString(literal[1 : len(literal)-1]))
+			}
+		}
+	}
+}
+
+func unescapePDFString(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 >= len(s) {
+			out.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'b', 'f':
+		case '\r', '\n':
+			// Line continuation.
+		default:
+			if s[i] >= '0' && s[i] <= '7' {
+				value := 0
+				j := i
+				for ; j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7'; j++ {
+					value = value*8 + int(s[j]-'0')
+				}
+				out.WriteRune(rune(value))
+				i = j - 1
+				continue
+			}
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String()
+}
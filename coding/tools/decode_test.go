@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16Bytes(text string, bigEndian, bom bool) []byte {
+	var out []byte
+	units := utf16.Encode([]rune(text))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	for _, u := range units {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+func simplePDF(t *testing.T, content string) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	w.Write([]byte(content))
+	w.Close()
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestReadToolDecodesContent(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"le.txt":     utf16Bytes("héllo\r\nworld\r\n", false, true),
+		"be.txt":     utf16Bytes("big endian\n", true, true),
+		"nobom.txt":  utf16Bytes("no bom here\n", false, false),
+		"bom8.txt":   append([]byte{0xEF, 0xBB, 0xBF}, "utf8 bom\n"...),
+		"doc.pdf":    simplePDF(t, "BT /F1 12 Tf 72 712 Td (Hello \\(PDF\\)) Tj ET BT [(Wor) -20 (ld)] TJ ET"),
+		"blob.bin":   append([]byte("\x7fELF\x02\x01\x01\x00"), bytes.Repeat([]byte{0, 1, 2, 3}, 100)...),
+		"latin1.txt": []byte("plain ascii with one \xe9 byte\n"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := NewReadFileTool(dir)
+
+	cases := []struct {
+		path     string
+		want     string
+		encoding string
+	}{
+		{"le.txt", "héllo\nworld\n", encodingUTF16LE},
+		{"be.txt", "big endian\n", encodingUTF16BE},
+		{"nobom.txt", "no bom here\n", encodingUTF16LE},
+		{"bom8.txt", "utf8 bom\n", ""},
+		{"doc.pdf", "Hello (PDF)\nWorld", ""},
+		{"latin1.txt", "plain ascii with one � byte\n", ""},
+	}
+	for _, tc := range cases {
+		result, err := tool.Execute("r", map[string]any{"path": tc.path})
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", tc.path, err)
+		}
+		if result.Text() != tc.want {
+			t.Fatalf("%s: unexpected text %q", tc.path, result.Text())
+		}
+		if encoding, _ := result.Details["encoding"].(string); encoding != tc.encoding {
+			t.Fatalf("%s: unexpected encoding %q", tc.path, encoding)
+		}
+	}
+
+	result, err := tool.Execute("r", map[string]any{"path": "blob.bin"})
+	if err != nil {
+		t.Fatalf("binary read failed: %v", err)
+	}
+	if result.Details["binary"] != true || result.Details["size"] != 408 {
+		t.Fatalf("unexpected binary details: %#v", result.Details)
+	}
+	if !strings.HasPrefix(result.Text(), "[Binary file, 408B. First 256 bytes:]\n00000000  7f 45 4c 46") {
+		t.Fatalf("unexpected binary preview: %q", result.Text())
+	}
+
+	grep := NewGrepTool(dir)
+	result, err = grep.Execute("g", map[string]any{"pattern": "world|endian"})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if result.Text() != "be.txt:1: big endian\nle.txt:2: world" {
+		t.Fatalf("expected grep to search UTF-16 files, got %q", result.Text())
+	}
+}
+
+func TestReadToolLineNumbersAndDirectories(t *testing.T) {
+	dir := t.TempDir()
+	lines := []string{}
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	writeTree(t, dir, map[string]string{
+		"numbers.txt": strings.Join(lines, "\n"),
+		"pkg/a.go":    "package pkg\n",
+	})
+	tool := NewReadFileTool(dir)
+
+	result, err := tool.Execute("r", map[string]any{"path": "numbers.txt", "offset": 8, "limit": 3, "line_numbers": true})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if want := " 8\tline 8\n 9\tline 9\n10\tline 10\n\n[2 more lines in file. Use offset=11 to continue.]"; result.Text() != want {
+		t.Fatalf("unexpected numbered output: %q", result.Text())
+	}
+
+	result, err = tool.Execute("r", map[string]any{"path": "pkg"})
+	if err != nil {
+		t.Fatalf("directory read failed: %v", err)
+	}
+	if !strings.Contains(result.Text(), "a.go") || !strings.HasPrefix(result.Text(), "file") {
+		t.Fatalf("expected a listing, got %q", result.Text())
+	}
+}
@@ -0,0 +1,333 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const defaultDiffContext = 3
+
+// contextLines resolves Options.DiffContext: 0 is the default and a negative
+// value shows changed lines only.
+func contextLines(n int) int {
+	switch {
+	case n == 0:
+		return defaultDiffContext
+	case n < 0:
+		return 0
+	}
+	return n
+}
+
+type diffLine struct {
+	Kind    string `json:"kind"`
+	OldLine int    `json:"oldLine,omitempty"`
+	NewLine int    `json:"newLine,omitempty"`
+	Text    string `json:"text"`
+	// NoNewline marks the last line of a file without a trailing newline.
+	NoNewline bool `json:"noNewline,omitempty"`
+}
+
+// noNewlineMarker follows a line that ends a file without a newline. It is
+// appended to that line while diffing so that it differs from the same line
+// with a newline.
+const noNewlineMarker = "\\ No newline at end of file"
+
+type diffHunk struct {
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Lines    []diffLine `json:"lines"`
+}
+
+type diffResult struct {
+	Text             string
+	Hunks            []diffHunk
+	FirstChangedLine int
+	Added            int
+	Removed          int
+}
+
+func (d diffResult) toMap() map[string]any {
+	return map[string]any{
+		"diff":             d.Text,
+		"hunks":            d.Hunks,
+		"firstChangedLine": d.FirstChangedLine,
+		"linesAdded":       d.Added,
+		"linesRemoved":     d.Removed,
+	}
+}
+
+const (
+	diffContext = "context"
+	diffAdd     = "add"
+	diffRemove  = "remove"
+)
+
+// generateDiff returns a unified diff of two texts with contextLines of
+// unchanged lines around each change. Line numbers are 1-based.
+func generateDiff(path, oldContent, newContent string, contextLines int) diffResult {
+	if oldContent == newContent {
+		return diffResult{}
+	}
+	ops := diffOps(markedDiffLines(oldContent), markedDiffLines(newContent))
+	for i := range ops {
+		if text, ok := strings.CutSuffix(ops[i].Text, "\n"+noNewlineMarker); ok {
+			ops[i].Text, ops[i].NoNewline = text, true
+		}
+	}
+	hunks := buildHunks(ops, contextLines)
+
+	result := diffResult{Hunks: hunks}
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", path, path)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case diffAdd:
+				out.WriteString("+")
+				result.Added++
+				if result.FirstChangedLine == 0 {
+					result.FirstChangedLine = line.NewLine
+				}
+			case diffRemove:
+				out.WriteString("-")
+				result.Removed++
+				if result.FirstChangedLine == 0 {
+					result.FirstChangedLine = maxInt(line.OldLine, 1)
+				}
+			default:
+				out.WriteString(" ")
+			}
+			out.WriteString(line.Text)
+			out.WriteString("\n")
+			if line.NoNewline {
+				out.WriteString(noNewlineMarker + "\n")
+			}
+		}
+	}
+	result.Text = strings.TrimSuffix(out.String(), "\n")
+	return result
+}
+
+func splitDiffLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// markedDiffLines splits content like splitDiffLines and tags a last line
+// that has no trailing newline with noNewlineMarker.
+func markedDiffLines(content string) []string {
+	lines := splitDiffLines(content)
+	if len(lines) > 0 && !strings.HasSuffix(content, "\n") {
+		lines[len(lines)-1] += "\n" + noNewlineMarker
+	}
+	return lines
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// diffOps computes a shortest edit script with Myers' algorithm after
+// trimming the common prefix and suffix.
+func diffOps(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffLine, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffLine{Kind: diffContext, OldLine: i + 1, NewLine: i + 1, Text: a[i]})
+	}
+	middle := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, op := range middle {
+		if op.OldLine > 0 {
+			op.OldLine += prefix
+		}
+		if op.NewLine > 0 {
+			op.NewLine += prefix
+		}
+		ops = append(ops, op)
+	}
+	for i := 0; i < suffix; i++ {
+		oldIdx := len(a) - suffix + i
+		newIdx := len(b) - suffix + i
+		ops = append(ops, diffLine{Kind: diffContext, OldLine: oldIdx + 1, NewLine: newIdx + 1, Text: a[oldIdx]})
+	}
+	return ops
+}
+
+// maxDiffEdits bounds the edit distance myers searches. Past it the texts
+// have little in common and a remove-all/add-all diff is returned instead,
+// which keeps time and memory bounded for rewrites of large files.
+const maxDiffEdits = 1000
+
+func myers(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	maxD := minInt(n+m, maxDiffEdits)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+2)
+	// trace[d] holds v[-d..d] as it was before round d.
+	trace := [][]int{}
+
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, d)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+// replaceAll is the edit script that removes every line of a and adds every
+// line of b.
+func replaceAll(a, b []string) []diffLine {
+	ops := make([]diffLine, 0, len(a)+len(b))
+	for i, line := range a {
+		ops = append(ops, diffLine{Kind: diffRemove, OldLine: i + 1, Text: line})
+	}
+	for i, line := range b {
+		ops = append(ops, diffLine{Kind: diffAdd, NewLine: i + 1, Text: line})
+	}
+	return ops
+}
+
+func backtrack(trace [][]int, a, b []string, depth int) []diffLine {
+	x, y := len(a), len(b)
+	reversed := []diffLine{}
+	for d := depth; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, diffLine{Kind: diffContext, OldLine: x + 1, NewLine: y + 1, Text: a[x]})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, diffLine{Kind: diffAdd, NewLine: y + 1, Text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, diffLine{Kind: diffRemove, OldLine: x + 1, Text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, diffLine{Kind: diffContext, OldLine: x + 1, NewLine: y + 1, Text: a[x]})
+	}
+
+	out := make([]diffLine, len(reversed))
+	for i, line := range reversed {
+		out[len(reversed)-1-i] = line
+	}
+	return out
+}
+
+// buildHunks groups changes that are at most 2*contextLines apart.
+func buildHunks(ops []diffLine, contextLines int) []diffHunk {
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	hunks := []diffHunk{}
+	i := 0
+	for i < len(ops) {
+		for i < len(ops) && ops[i].Kind == diffContext {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := maxInt(i-contextLines, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != diffContext {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == diffContext {
+				run++
+			}
+			if run == len(ops) || run-end > 2*contextLines {
+				end = minInt(end+contextLines, len(ops))
+				break
+			}
+			end = run
+		}
+
+		hunk := diffHunk{Lines: append([]diffLine(nil), ops[start:end]...)}
+		oldNext, newNext := nextLineNumbers(ops, start)
+		hunk.OldStart, hunk.NewStart = oldNext, newNext
+		for _, line := range hunk.Lines {
+			if line.Kind != diffAdd {
+				hunk.OldLines++
+			}
+			if line.Kind != diffRemove {
+				hunk.NewLines++
+			}
+		}
+		// Unified diffs number an empty range by the line before it.
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+		hunks = append(hunks, hunk)
+		i = end
+	}
+	return hunks
+}
+
+// nextLineNumbers returns the old and new line numbers at position idx.
+func nextLineNumbers(ops []diffLine, idx int) (int, int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:idx] {
+		if op.Kind != diffAdd {
+			oldLine++
+		}
+		if op.Kind != diffRemove {
+			newLine++
+		}
+	}
+	return oldLine, newLine
+}
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return lines
+}
+
+func TestGenerateDiffSingleChangeInLargeFile(t *testing.T) {
+	lines := numberedLines(2000)
+	oldContent := strings.Join(lines, "\n") + "\n"
+	lines[999] = "changed"
+	newContent := strings.Join(lines, "\n") + "\n"
+
+	result := generateDiff("big.txt", oldContent, newContent, 3)
+	want := strings.Join([]string{
+		"--- a/big.txt",
+		"+++ b/big.txt",
+		"@@ -997,7 +997,7 @@",
+		" line 997",
+		" line 998",
+		" line 999",
+		"-line 1000",
+		"+changed",
+		" line 1001",
+		" line 1002",
+		" line 1003",
+	}, "\n")
+	if result.Text != want {
+		t.Fatalf("unexpected diff:\n%s", result.Text)
+	}
+	if result.FirstChangedLine != 1000 || result.Added != 1 || result.Removed != 1 {
+		t.Fatalf("unexpected diff stats: %#v", result)
+	}
+	hunk := result.Hunks[0]
+	if hunk.Lines[3].Kind != diffRemove || hunk.Lines[3].OldLine != 1000 || hunk.Lines[4].NewLine != 1000 {
+		t.Fatalf("unexpected hunk line numbers: %#v", hunk.Lines[3:5])
+	}
+}
+
+func TestGenerateDiffHunksAndContext(t *testing.T) {
+	lines := numberedLines(20)
+	oldContent := strings.Join(lines, "\n")
+	updated := append([]string{}, lines...)
+	updated[1] = "two"
+	updated[3] = "four"
+	updated = append(updated[:15], append([]string{"inserted"}, updated[15:]...)...)
+	newContent := strings.Join(updated, "\n")
+
+	result := generateDiff("f.txt", oldContent, newContent, 1)
+	if len(result.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d:\n%s", len(result.Hunks), result.Text)
+	}
+	first := result.Hunks[0]
+	if first.OldStart != 1 || first.OldLines != 5 || first.NewStart != 1 || first.NewLines != 5 {
+		t.Fatalf("unexpected first hunk header: %#v", first)
+	}
+	if !strings.Contains(result.Text, "@@ -15,2 +15,3 @@\n line 15\n+inserted\n line 16") {
+		t.Fatalf("unexpected insertion hunk:\n%s", result.Text)
+	}
+
+	if got := generateDiff("f.txt", "", "a\nb\n", 3).Text; got != "--- a/f.txt\n+++ b/f.txt\n@@ -0,0 +1,2 @@\n+a\n+b" {
+		t.Fatalf("unexpected diff from empty file:\n%s", got)
+	}
+	if got := generateDiff("f.txt", "a\nb\nc\n", "a\nc\n", 0).Text; got != "--- a/f.txt\n+++ b/f.txt\n@@ -2 +1,0 @@\n-b" {
+		t.Fatalf("unexpected deletion diff:\n%s", got)
+	}
+}
+
+func TestGenerateDiffTrailingNewline(t *testing.T) {
+	result := generateDiff("f.txt", "a\nb\n", "a\nb", 3)
+	want := "--- a/f.txt\n+++ b/f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file"
+	if result.Text != want {
+		t.Fatalf("unexpected diff:\n%s", result.Text)
+	}
+	if result.Added != 1 || result.Removed != 1 || result.FirstChangedLine != 2 {
+		t.Fatalf("unexpected counts: %#v", result)
+	}
+	lines := result.Hunks[0].Lines
+	if last := lines[len(lines)-1]; last.Text != "b" || !last.NoNewline {
+		t.Fatalf("expected the added line to be marked, got %#v", last)
+	}
+
+	want = "--- a/f.txt\n+++ b/f.txt\n@@ -1 +1,2 @@\n-a\n\\ No newline at end of file\n+a\n+b"
+	if got := generateDiff("f.txt", "a", "a\nb\n", 3).Text; got != want {
+		t.Fatalf("unexpected diff when appending to a file without newline:\n%s", got)
+	}
+}
+
+func TestGenerateDiffUnrelatedLargeFiles(t *testing.T) {
+	oldLines := numberedLines(50000)
+	newLines := make([]string, 50000)
+	for i := range newLines {
+		newLines[i] = fmt.Sprintf("other %d", i+1)
+	}
+	oldContent := strings.Join(oldLines, "\n") + "\n"
+	newContent := strings.Join(newLines, "\n") + "\n"
+
+	result := generateDiff("big.txt", oldContent, newContent, 3)
+	if result.Added != 50000 || result.Removed != 50000 || result.FirstChangedLine != 1 {
+		t.Fatalf("unexpected diff stats: added=%d removed=%d first=%d", result.Added, result.Removed, result.FirstChangedLine)
+	}
+	if len(result.Hunks) != 1 || !strings.HasPrefix(result.Text, "--- a/big.txt\n+++ b/big.txt\n@@ -1,50000 +1,50000 @@\n-line 1\n") {
+		t.Fatalf("unexpected diff header:\n%s", result.Text[:100])
+	}
+}
+
+func TestWriteToolDiffsOverwrittenFile(t *testing.T) {
+	dir := t.TempDir()
+	tool := NewWriteFileTool(dir)
+	result, err := tool.Execute("w1", map[string]any{"path": "a.txt", "content": "one\ntwo\n"})
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, ok := result.Details["diff"]; ok || result.Details["overwritten"] != false {
+		t.Fatalf("new file should not have a diff: %#v", result.Details)
+	}
+
+	result, err = tool.Execute("w2", map[string]any{"path": "a.txt", "content": "one\n2\n"})
+	if err != nil {
+		t.Fatalf("overwrite failed: %v", err)
+	}
+	if result.Details["diff"] != "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+2" {
+		t.Fatalf("unexpected write diff: %#v", result.Details["diff"])
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "one\n2\n" {
+		t.Fatalf("unexpected content: %q", data)
+	}
+}
+
+func TestDiffContextOption(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "one\ntwo\nthree\nfour\nfive\n"})
+	cases := []struct {
+		context int
+		want    string
+	}{
+		{0, "@@ -1,5 +1,5 @@\n one\n two\n-three\n+3\n four\n five"},
+		{1, "@@ -2,3 +2,3 @@\n two\n-three\n+3\n four"},
+		{-1, "@@ -3 +3 @@\n-three\n+3"},
+	}
+	for _, tc := range cases {
+		tools := toolsByName(NewCodingToolsWithOptions(dir, Options{DiffContext: tc.context, FileChecks: FileChecksOff}))
+		writeTree(t, dir, map[string]string{"a.txt": "one\ntwo\nthree\nfour\nfive\n"})
+		result, err := tools["edit"].Execute("e", map[string]any{"path": "a.txt", "oldText": "three", "newText": "3"})
+		if err != nil {
+			t.Fatalf("edit failed: %v", err)
+		}
+		if want := "--- a/a.txt\n+++ b/a.txt\n" + tc.want; result.Details["diff"] != want {
+			t.Fatalf("context %d: unexpected diff:\n%s", tc.context, result.Details["diff"])
+		}
+	}
+}
@@ -10,11 +10,14 @@ import (
 )
 
 type editTool struct {
-	cwd string
+	paths       pathGuard
+	files       *fileTracker
+	checkpoints *CheckpointStore
+	diffContext int
 }
 
 func NewEditTool(cwd string) agent.Tool {
-	return &editTool{cwd: defaultCWD(cwd)}
+	return &editTool{paths: standalonePaths(cwd)}
 }
 
 func (t *editTool) Name() string {
@@ -22,25 +25,52 @@ func (t *editTool) Name() string {
 }
 
 func (t *editTool) Description() string {
-	return "Edit a file by replacing exact oldText with newText."
+	return "Edit a file by replacing oldText with newText. Pass edits to apply several replacements at once; " +
+		"either all of them apply or none do. Exact matches are preferred; otherwise differences in indentation, " +
+		"whitespace and Unicode quotes are tolerated when the match is unique."
 }
 
 func (t *editTool) Parameters() map[string]any {
-	return map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"path": map[string]any{"type": "string", "description": "Relative file path"},
-			"oldText": map[string]any{
-				"type":        "string",
-				"description": "Exact text to replace",
-			},
-			"newText": map[string]any{
-				"type":        "string",
-				"description": "Replacement text",
+	replacement := map[string]any{
+		"oldText": map[string]any{
+			"type":        "string",
+			"description": "Text to replace",
+		},
+		"newText": map[string]any{
+			"type":        "string",
+			"description": "Replacement text",
+		},
+		"replaceAll": map[string]any{
+			"type":        "boolean",
+			"description": "Replace every exact occurrence instead of requiring a unique match",
+		},
+	}
+	properties := map[string]any{
+		"path": map[string]any{"type": "string", "description": "Relative file path"},
+		"edits": map[string]any{
+			"type":        "array",
+			"description": "Replacements applied in order; use instead of oldText/newText",
+			"items": map[string]any{
+				"type":       "object",
+				"properties": replacement,
+				"required":   []string{"oldText", "newText"},
 			},
 		},
-		"required": []string{"path", "oldText", "newText"},
 	}
+	for key, value := range replacement {
+		properties[key] = value
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"path"},
+	}
+}
+
+type editRequest struct {
+	OldText    string
+	NewText    string
+	ReplaceAll bool
 }
 
 func (t *editTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
@@ -48,16 +78,12 @@ func (t *editTool) Execute(toolCallID string, args map[string]any) (agent.ToolRe
 	if !ok || strings.TrimSpace(path) == "" {
 		return agent.ToolResult{}, fmt.Errorf("missing required argument: path")
 	}
-	oldText, ok := toStringArg(args, "oldText")
-	if !ok {
-		return agent.ToolResult{}, fmt.Errorf("missing required argument: oldText")
-	}
-	newText, ok := toStringArg(args, "newText")
-	if !ok {
-		return agent.ToolResult{}, fmt.Errorf("missing required argument: newText")
+	edits, err := parseEdits(args)
+	if err != nil {
+		return agent.ToolResult{}, err
 	}
 
-	target, err := resolveSafePath(t.cwd, path)
+	target, err := t.paths.resolve(path, accessWrite)
 	if err != nil {
 		return agent.ToolResult{}, err
 	}
@@ -65,70 +91,160 @@ func (t *editTool) Execute(toolCallID string, args map[string]any) (agent.ToolRe
 	if err != nil {
 		return agent.ToolResult{}, err
 	}
-	content := string(data)
-
-	matchCount := strings.Count(content, oldText)
-	if matchCount == 0 {
-		return agent.ToolResult{}, fmt.Errorf("could not find exact text in %s", path)
+	if err := t.files.check(target, path, data, false); err != nil {
+		return agent.ToolResult{}, err
 	}
-	if matchCount > 1 {
-		return agent.ToolResult{}, fmt.Errorf("oldText occurs multiple times in %s; provide unique context", path)
+	original := string(data)
+	usesCRLF := strings.Contains(original, "\r\n")
+	content := strings.ReplaceAll(original, "\r\n", "\n")
+
+	updated := content
+	applied := make([]map[string]any, 0, len(edits))
+	usedFuzzy := false
+	for i, edit := range edits {
+		next, strategy, count, err := applyEdit(updated, path, edit)
+		if err != nil {
+			if len(edits) > 1 {
+				return agent.ToolResult{}, fmt.Errorf("edit %d: %w; no edits were applied", i+1, err)
+			}
+			return agent.ToolResult{}, err
+		}
+		updated = next
+		usedFuzzy = usedFuzzy || strategy != matchExact
+		applied = append(applied, map[string]any{"strategy": strategy, "replacements": count})
 	}
-
-	updated := strings.Replace(content, oldText, newText, 1)
 	if updated == content {
 		return agent.ToolResult{}, fmt.Errorf("no changes applied")
 	}
 
-	if err := os.WriteFile(target, []byte(updated), 0o644); err != nil {
+	output := updated
+	if usesCRLF {
+		output = strings.ReplaceAll(updated, "\n", "\r\n")
+	}
+	if err := t.checkpoints.capture(toolCallID, target); err != nil {
+		return agent.ToolResult{}, err
+	}
+	if err := os.WriteFile(target, []byte(output), 0o644); err != nil {
 		return agent.ToolResult{}, err
 	}
+	t.files.record(target, []byte(output))
 
-	diff, firstChangedLine := generateDiffString(content, updated)
+	summary := fmt.Sprintf("Edited %s: applied %d edits", path, len(edits))
+	if len(edits) == 1 {
+		summary = fmt.Sprintf("Edited %s: replaced %d chars with %d chars", path, len(edits[0].OldText), len(edits[0].NewText))
+	}
+	if usedFuzzy {
+		summary += " (fuzzy match)"
+	}
+	details := generateDiff(path, content, updated, contextLines(t.diffContext)).toMap()
+	details["path"] = path
+	details["usedFuzzyMatch"] = usedFuzzy
+	details["edits"] = applied
 	return agent.ToolResult{
-		Content: []model.TextContent{
-			{
+		Content: []any{
+			model.TextContent{
 				Type: model.ContentText,
-				Text: fmt.Sprintf("Edited %s: replaced %d chars with %d chars", path, len(oldText), len(newText)),
+				Text: summary,
 			},
 		},
-		Details: map[string]any{
-			"path":             path,
-			"diff":             diff,
-			"firstChangedLine": firstChangedLine,
-			"usedFuzzyMatch":   false,
-		},
+		Details: details,
 	}, nil
 }
 
-func generateDiffString(oldContent, newContent string) (string, int) {
-	if oldContent == newContent {
-		return "", 0
+func parseEdits(args map[string]any) ([]editRequest, error) {
+	if raw, ok := args["edits"]; ok {
+		items, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("edits must be an array")
+		}
+		if len(items) == 0 {
+			return nil, fmt.Errorf("edits must not be empty")
+		}
+		edits := make([]editRequest, 0, len(items))
+		for i, item := range items {
+			fields, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("edit %d must be an object", i+1)
+			}
+			edit, err := parseEdit(fields)
+			if err != nil {
+				return nil, fmt.Errorf("edit %d: %w", i+1, err)
+			}
+			edits = append(edits, edit)
+		}
+		return edits, nil
+	}
+	edit, err := parseEdit(args)
+	if err != nil {
+		return nil, err
+	}
+	return []editRequest{edit}, nil
+}
+
+func parseEdit(fields map[string]any) (editRequest, error) {
+	oldText, ok := toStringArg(fields, "oldText")
+	if !ok {
+		return editRequest{}, fmt.Errorf("missing required argument: oldText")
+	}
+	if oldText == "" {
+		return editRequest{}, fmt.Errorf("oldText must not be empty")
 	}
+	newText, ok := toStringArg(fields, "newText")
+	if !ok {
+		return editRequest{}, fmt.Errorf("missing required argument: newText")
+	}
+	replaceAll, _ := fields["replaceAll"].(bool)
+	return editRequest{
+		OldText:    strings.ReplaceAll(oldText, "\r\n", "\n"),
+		NewText:    strings.ReplaceAll(newText, "\r\n", "\n"),
+		ReplaceAll: replaceAll,
+	}, nil
+}
 
-	oldLines := strings.Split(oldContent, "\n")
-	newLines := strings.Split(newContent, "\n")
-	minLen := len(oldLines)
-	if len(newLines) < minLen {
-		minLen = len(newLines)
+// applyEdit tries an exact match first and falls back to the fuzzy matchers.
+// Fuzzy matches must be unique, even with replaceAll.
+func applyEdit(content, path string, edit editRequest) (string, string, int, error) {
+	spans := findExact(content, edit.OldText)
+	switch {
+	case len(spans) > 1 && !edit.ReplaceAll:
+		return "", "", 0, fmt.Errorf(
+			"oldText occurs multiple times in %s (%d matches); provide unique context or set replaceAll",
+			path, len(spans),
+		)
+	case len(spans) > 0:
+		return replaceSpans(content, spans, edit.NewText), matchExact, len(spans), nil
 	}
 
-	firstChangedLine := 1
-	for i := 0; i < minLen; i++ {
-		if oldLines[i] != newLines[i] {
-			firstChangedLine = i + 1
-			goto build
+	newText := edit.NewText
+	for _, strategy := range []string{matchQuotes, matchWhitespace} {
+		if strategy == matchQuotes {
+			spans = findQuoteInsensitive(content, edit.OldText)
+		} else {
+			spans = findWhitespaceInsensitive(content, edit.OldText)
+			if len(spans) > 0 && strings.Trim(edit.OldText, "\n") != edit.OldText {
+				newText = strings.Trim(newText, "\n")
+			}
+		}
+		if len(spans) > 1 {
+			return "", "", 0, fmt.Errorf("fuzzy match for oldText is ambiguous in %s (%d matches); provide more context", path, len(spans))
+		}
+		if len(spans) == 1 {
+			span := spans[0]
+			replacement := reindent(newText, span.indents)
+			return replaceSpans(content, spans, replacement), strategy, 1, nil
 		}
 	}
-	firstChangedLine = minLen + 1
+	return "", "", 0, fmt.Errorf("could not find exact text in %s", path)
+}
 
-build:
+func replaceSpans(content string, spans []textSpan, replacement string) string {
 	var out strings.Builder
-	for i, line := range oldLines {
-		fmt.Fprintf(&out, "-%d %s\n", i+1, line)
-	}
-	for i, line := range newLines {
-		fmt.Fprintf(&out, "+%d %s\n", i+1, line)
+	last := 0
+	for _, span := range spans {
+		out.WriteString(content[last:span.start])
+		out.WriteString(replacement)
+		last = span.end
 	}
-	return strings.TrimSuffix(out.String(), "\n"), firstChangedLine
+	out.WriteString(content[last:])
+	return out.String()
 }
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	matchExact      = "exact"
+	matchQuotes     = "quotes"
+	matchWhitespace = "whitespace"
+)
+
+// textSpan is a byte range in the file content.
+type textSpan struct {
+	start int
+	end   int
+	// indents maps the indentation of oldText lines to the indentation of the
+	// matched file lines so newText can be re-indented to fit the file.
+	indents map[string]string
+}
+
+// findExact returns every non-overlapping occurrence of oldText.
+func findExact(content, oldText string) []textSpan {
+	spans := []textSpan{}
+	offset := 0
+	for {
+		idx := strings.Index(content[offset:], oldText)
+		if idx < 0 {
+			return spans
+		}
+		start := offset + idx
+		spans = append(spans, textSpan{start: start, end: start + len(oldText)})
+		offset = start + len(oldText)
+	}
+}
+
+// findQuoteInsensitive matches after folding Unicode quotes, dashes and
+// non-breaking spaces to their ASCII forms. The content is folded once;
+// folding shortens some runes, so origin maps each byte offset of the folded
+// text back to the original.
+func findQuoteInsensitive(content, oldText string) []textSpan {
+	needle := foldPunctuation(oldText)
+	if needle == "" {
+		return nil
+	}
+	var folded strings.Builder
+	folded.Grow(len(content))
+	origin := make([]int, 0, len(content)+1)
+	for i, r := range content {
+		r = foldRune(r)
+		for n := utf8.RuneLen(r); n > 0; n-- {
+			origin = append(origin, i)
+		}
+		folded.WriteRune(r)
+	}
+	origin = append(origin, len(content))
+	text := folded.String()
+
+	spans := []textSpan{}
+	offset := 0
+	for {
+		idx := strings.Index(text[offset:], needle)
+		if idx < 0 {
+			return spans
+		}
+		start := offset + idx
+		offset = start + len(needle)
+		spans = append(spans, textSpan{start: origin[start], end: origin[offset]})
+	}
+}
+
+func foldPunctuation(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+func foldRune(r rune) rune {
+	switch r {
+	case '‘', '’', '‚', '‛', '′':
+		return '\''
+	case '“', '”', '„', '‟', '″':
+		return '"'
+	case '‐', '‑', '‒', '–', '—', '−':
+		return '-'
+	case '\u00a0', '\u2007', '\u202f':
+		return ' '
+	}
+	return r
+}
+
+// findWhitespaceInsensitive matches whole lines, ignoring indentation, trailing
+// whitespace, runs of inner whitespace and punctuation differences.
+func findWhitespaceInsensitive(content, oldText string) []textSpan {
+	needle := strings.Split(strings.Trim(oldText, "\n"), "\n")
+	normalizedNeedle := make([]string, len(needle))
+	for i, line := range needle {
+		normalizedNeedle[i] = normalizeLine(line)
+	}
+	if strings.Join(normalizedNeedle, "") == "" {
+		return nil
+	}
+
+	lines := strings.Split(content, "\n")
+	lineStarts := make([]int, len(lines)+1)
+	pos := 0
+	for i, line := range lines {
+		lineStarts[i] = pos
+		pos += len(line) + 1
+	}
+	lineStarts[len(lines)] = pos
+
+	spans := []textSpan{}
+	for i := 0; i+len(needle) <= len(lines); {
+		matched := true
+		for j := range needle {
+			if normalizeLine(lines[i+j]) != normalizedNeedle[j] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			i++
+			continue
+		}
+		last := i + len(needle) - 1
+		indents := map[string]string{}
+		for j, line := range needle {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if _, seen := indents[leadingWhitespace(line)]; !seen {
+				indents[leadingWhitespace(line)] = leadingWhitespace(lines[i+j])
+			}
+		}
+		spans = append(spans, textSpan{
+			start:   lineStarts[i],
+			end:     lineStarts[last] + len(lines[last]),
+			indents: indents,
+		})
+		i = last + 1
+	}
+	return spans
+}
+
+func normalizeLine(line string) string {
+	return strings.Join(strings.Fields(foldPunctuation(line)), " ")
+}
+
+func leadingWhitespace(line string) string {
+	return line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]
+}
+
+// reindent gives newText lines the file's indentation for each indentation
+// level used in oldText. Lines at other levels keep the shift of the nearest
+// shallower known level.
+func reindent(newText string, indents map[string]string) string {
+	if len(indents) == 0 {
+		return newText
+	}
+	lines := strings.Split(newText, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := leadingWhitespace(line)
+		if mapped, ok := indents[indent]; ok {
+			lines[i] = mapped + line[len(indent):]
+			continue
+		}
+		best := ""
+		found := false
+		for oldIndent := range indents {
+			if strings.HasPrefix(indent, oldIndent) && (!found || len(oldIndent) > len(best)) {
+				best, found = oldIndent, true
+			}
+		}
+		if found {
+			lines[i] = indents[best] + line[len(best):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
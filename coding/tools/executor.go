@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// Executor starts the shell commands run by the bash tool. Command returns an
+// unstarted command that runs script with bash in dir and is killed when ctx
+// is done.
+type Executor interface {
+	Command(ctx context.Context, script, dir string) (*exec.Cmd, error)
+}
+
+// HostExecutor runs commands directly on the host with the caller's privileges.
+type HostExecutor struct{}
+
+func (HostExecutor) Command(ctx context.Context, script, dir string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, "bash", "-lc", script)
+	cmd.Dir = dir
+	return cmd, nil
+}
+
+// SandboxOptions configures NewSandboxExecutor. Zero limits are unlimited.
+type SandboxOptions struct {
+	// AllowNetwork keeps the host network namespace.
+	AllowNetwork bool
+	// CPUTime limits the CPU time of each process (RLIMIT_CPU).
+	CPUTime time.Duration
+	// MemoryBytes limits the address space of each process (RLIMIT_AS).
+	MemoryBytes int64
+	// Timeout kills the whole sandbox after this much wall-clock time.
+	Timeout time.Duration
+}
@@ -0,0 +1,347 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+const (
+	defaultFetchMaxBytes = 5 * 1024 * 1024
+	defaultFetchTimeout  = 30 * time.Second
+	maxFetchRedirects    = 10
+)
+
+// FetchOptions configures the fetch tool.
+type FetchOptions struct {
+	// AllowHosts restricts fetching to these hosts when set; "*.example.com"
+	// matches the subdomains of example.com.
+	AllowHosts []string
+	// DenyHosts are never fetched and take precedence over AllowHosts.
+	DenyHosts []string
+	// AllowPrivate permits loopback, private, link-local and other
+	// non-public addresses, which are blocked by default.
+	AllowPrivate bool
+	// MaxBytes limits the response body read; 5MB by default.
+	MaxBytes int64
+	// Timeout limits each request including redirects; 30s by default.
+	Timeout time.Duration
+}
+
+// nonPublicPrefixes are special-purpose ranges not covered by the netip
+// predicates used in blockedAddress.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+type fetchTool struct {
+	options FetchOptions
+	client  *http.Client
+}
+
+// NewFetchTool returns the fetch tool. Hosts are checked before each request
+// and redirect, and resolved addresses are checked when connecting, so DNS
+// cannot point an allowed name at a private address.
+func NewFetchTool(options FetchOptions) agent.Tool {
+	if options.MaxBytes <= 0 {
+		options.MaxBytes = defaultFetchMaxBytes
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultFetchTimeout
+	}
+	t := &fetchTool{options: options}
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: t.checkDial}
+	t.client = &http.Client{
+		Timeout: options.Timeout,
+		// Proxies from the environment would connect on our behalf and
+		// bypass the address check.
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			ForceAttemptHTTP2:   true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFetchRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+			}
+			return t.checkURL(req.URL)
+		},
+	}
+	return t
+}
+
+func (t *fetchTool) Name() string {
+	return "fetch"
+}
+
+func (t *fetchTool) Description() string {
+	return fmt.Sprintf(
+		"Fetch a URL. HTML is converted to Markdown without scripts and navigation, JSON is pretty-printed. "+
+			"Output is limited to %d lines or %s; use offset to continue.",
+		defaultMaxLines, formatSize(defaultMaxBytes),
+	)
+}
+
+func (t *fetchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"url": map[string]any{"type": "string", "description": "http or https URL to fetch"},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Line number of the converted content to start from (1-indexed)",
+				"minimum":     1,
+			},
+			"raw": map[string]any{
+				"type":        "boolean",
+				"description": "Return HTML as-is instead of converting it to Markdown",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *fetchTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *fetchTool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	rawURL, ok := toStringArg(args, "url")
+	if !ok || strings.TrimSpace(rawURL) == "" {
+		return agent.ToolResult{}, fmt.Errorf("missing required argument: url")
+	}
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return agent.ToolResult{}, fmt.Errorf("invalid url: %w", err)
+	}
+	if err := t.checkURL(target); err != nil {
+		return agent.ToolResult{}, err
+	}
+	offset := 1
+	if raw, ok := args["offset"]; ok {
+		if n, ok := toInt(raw); ok && n > 0 {
+			offset = n
+		}
+	}
+	raw, _ := args["raw"].(bool)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	req.Header.Set("User-Agent", "phi-fetch/1.0")
+	req.Header.Set("Accept", "text/html, text/markdown, text/plain, application/json;q=0.9, */*;q=0.8")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// Report the cause without url.Error's repetition of method and URL.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return agent.ToolResult{}, fmt.Errorf("fetch %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, t.options.MaxBytes+1))
+	if err != nil {
+		return agent.ToolResult{}, fmt.Errorf("fetch %s: %w", target, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return agent.ToolResult{}, fmt.Errorf("fetch %s: %s", target, resp.Status)
+	}
+	bodyTruncated := int64(len(data)) > t.options.MaxBytes
+	if bodyTruncated {
+		data = data[:t.options.MaxBytes]
+	}
+
+	final := resp.Request.URL
+	content, format, title, err := convertFetched(data, resp.Header.Get("Content-Type"), final, raw)
+	if err != nil {
+		return agent.ToolResult{}, fmt.Errorf("fetch %s: %w", final, err)
+	}
+	details := map[string]any{
+		"url":         final.String(),
+		"status":      resp.StatusCode,
+		"contentType": resp.Header.Get("Content-Type"),
+		"format":      format,
+		"bytes":       len(data),
+	}
+	if title != "" {
+		details["title"] = title
+	}
+
+	// Lines longer than the byte limit could never be shown, so split them.
+	allLines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	for i, line := range allLines {
+		if len(line) > defaultMaxBytes {
+			allLines[i] = foldLine(line, readFoldWidth)
+		}
+	}
+	allLines = strings.Split(strings.Join(allLines, "\n"), "\n")
+	totalLines := len(allLines)
+	if offset > totalLines {
+		return agent.ToolResult{}, fmt.Errorf("offset %d is beyond end of content (%d lines total)", offset, totalLines)
+	}
+	details["totalLines"] = totalLines
+
+	trunc := truncateHead(strings.Join(allLines[offset-1:], "\n"), defaultMaxLines, defaultMaxBytes)
+	outputText := trunc.Content
+	notices := []string{}
+	if trunc.Truncated {
+		endLine := offset + trunc.OutputLines - 1
+		notices = append(notices, fmt.Sprintf("Showing lines %d-%d of %d. Use offset=%d to continue.", offset, endLine, totalLines, endLine+1))
+		details["truncation"] = trunc.toMap()
+	}
+	if bodyTruncated {
+		notices = append(notices, fmt.Sprintf("Response exceeded %s; the rest was not fetched.", formatSize(int(t.options.MaxBytes))))
+		details["responseTruncated"] = true
+	}
+	if strings.TrimSpace(outputText) == "" {
+		outputText = "(empty response)"
+	}
+	for _, notice := range notices {
+		outputText += "\n\n[" + notice + "]"
+	}
+	return agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: outputText}},
+		Details: details,
+	}, nil
+}
+
+// convertFetched turns a response body into text and names its format.
+func convertFetched(data []byte, contentType string, base *url.URL, raw bool) (string, string, string, error) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	}
+	if mediaType == "application/pdf" {
+		text := extractPDFText(data)
+		if text == "" {
+			return "", "", "", errors.New("no text could be extracted from the PDF")
+		}
+		return text, "pdf", "", nil
+	}
+
+	var text string
+	switch strings.ToLower(params["charset"]) {
+	case "iso-8859-1", "latin1", "us-ascii":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		text = string(runes)
+	default:
+		var ok bool
+		if text, _, ok = decodeText(data); !ok {
+			return "", "", "", fmt.Errorf("unsupported content type: %s", mediaType)
+		}
+	}
+
+	switch {
+	case (mediaType == "text/html" || mediaType == "application/xhtml+xml") && !raw:
+		markdown, title := htmlToMarkdown(text, base)
+		return markdown, "markdown", title, nil
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, []byte(text), "", "  "); err == nil {
+			return pretty.String(), "json", "", nil
+		}
+		return text, "text", "", nil
+	}
+	return text, "text", "", nil
+}
+
+// checkURL applies the scheme and host rules to a request or redirect URL.
+func (t *fetchTool) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme: %q", u.Scheme)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("url has no host: %s", u)
+	}
+	if matchHost(t.options.DenyHosts, host) {
+		return fmt.Errorf("host is denied: %s", host)
+	}
+	if len(t.options.AllowHosts) > 0 && !matchHost(t.options.AllowHosts, host) {
+		return fmt.Errorf("host is not allowed: %s", host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !t.options.AllowPrivate && blockedAddress(addr) {
+		return &blockedAddressError{addr: addr}
+	}
+	return nil
+}
+
+// checkDial runs for every connection with the resolved address.
+func (t *fetchTool) checkDial(network, address string, _ syscall.RawConn) error {
+	if t.options.AllowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if blockedAddress(addrPort.Addr()) {
+		return &blockedAddressError{addr: addrPort.Addr()}
+	}
+	return nil
+}
+
+type blockedAddressError struct {
+	addr netip.Addr
+}
+
+func (e *blockedAddressError) Error() string {
+	return fmt.Sprintf("address %s is not public; private, loopback and link-local addresses are blocked", e.addr)
+}
+
+func blockedAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether host matches a pattern in patterns, either
+// exactly or, for "*.example.com", as a subdomain.
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fetchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!doctype html><html><head><title>Test &amp; Page</title>
+<script>alert("x")</script><style>body{}</style></head>
+<body><nav><a href="/">Home</a></nav>
+<h1>Hello</h1><p>Some <b>bold</b> text with a <a href="/docs">link</a>.</p>
+<ul><li>one</li><li>two</li></ul></body></html>`)
+	})
+	mux.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"phi","tags":["a","b"]}`)
+	})
+	mux.HandleFunc("/long", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		for i := 1; i <= 2500; i++ {
+			fmt.Fprintf(w, "line %d\n", i)
+		}
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchConvertsHTMLToMarkdown(t *testing.T) {
+	server := fetchServer(t)
+	tool := NewFetchTool(FetchOptions{AllowPrivate: true})
+
+	result, err := tool.Execute("f", map[string]any{"url": server.URL + "/page"})
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	text := resultText(t, result)
+	want := "# Hello\n\nSome **bold** text with a [link](" + server.URL + "/docs).\n\n- one\n- two"
+	if text != want {
+		t.Fatalf("unexpected markdown:\n%s\nwant:\n%s", text, want)
+	}
+	if result.Details["title"] != "Test & Page" || result.Details["format"] != "markdown" {
+		t.Fatalf("unexpected details: %#v", result.Details)
+	}
+
+	result, err = tool.Execute("f", map[string]any{"url": server.URL + "/page", "raw": true})
+	if err != nil {
+		t.Fatalf("raw fetch failed: %v", err)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "<script>") {
+		t.Fatalf("expected raw HTML, got:\n%s", text)
+	}
+}
+
+func TestFetchPrettyPrintsJSON(t *testing.T) {
+	server := fetchServer(t)
+	tool := NewFetchTool(FetchOptions{AllowPrivate: true})
+
+	result, err := tool.Execute("f", map[string]any{"url": server.URL + "/data"})
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	want := "{\n  \"name\": \"phi\",\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ]\n}"
+	if text := resultText(t, result); text != want {
+		t.Fatalf("unexpected JSON:\n%s", text)
+	}
+}
+
+func TestFetchTruncatesWithContinuationOffset(t *testing.T) {
+	server := fetchServer(t)
+	tool := NewFetchTool(FetchOptions{AllowPrivate: true})
+
+	result, err := tool.Execute("f", map[string]any{"url": server.URL + "/long"})
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	text := resultText(t, result)
+	if !strings.HasSuffix(text, "[Showing lines 1-2000 of 2500. Use offset=2001 to continue.]") {
+		t.Fatalf("expected continuation notice, got tail %q", text[len(text)-80:])
+	}
+
+	result, err = tool.Execute("f", map[string]any{"url": server.URL + "/long", "offset": 2001})
+	if err != nil {
+		t.Fatalf("fetch with offset failed: %v", err)
+	}
+	text = resultText(t, result)
+	if !strings.HasPrefix(text, "line 2001\n") || !strings.HasSuffix(text, "line 2500") {
+		t.Fatalf("unexpected continuation:\n%s", text)
+	}
+}
+
+func TestFetchLimits(t *testing.T) {
+	server := fetchServer(t)
+
+	small := NewFetchTool(FetchOptions{AllowPrivate: true, MaxBytes: 64})
+	result, err := small.Execute("f", map[string]any{"url": server.URL + "/long"})
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if result.Details["responseTruncated"] != true || result.Details["bytes"] != 64 {
+		t.Fatalf("expected truncated response, got %#v", result.Details)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "Response exceeded 64B") {
+		t.Fatalf("expected size notice, got:\n%s", text)
+	}
+
+	slow := NewFetchTool(FetchOptions{AllowPrivate: true, Timeout: 100 * time.Millisecond})
+	if _, err := slow.Execute("f", map[string]any{"url": server.URL + "/slow"}); err == nil {
+		t.Fatal("expected timeout error")
+	}
+
+	tool := NewFetchTool(FetchOptions{AllowPrivate: true})
+	if _, err := tool.Execute("f", map[string]any{"url": server.URL + "/missing"}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected 404 error, got %v", err)
+	}
+	if _, err := tool.Execute("f", map[string]any{"url": "file:///etc/passwd"}); err == nil || !strings.Contains(err.Error(), "unsupported url scheme") {
+		t.Fatalf("expected scheme error, got %v", err)
+	}
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	server := fetchServer(t)
+	tool := NewFetchTool(FetchOptions{})
+
+	_, err := tool.Execute("f", map[string]any{"url": server.URL + "/page"})
+	if err == nil || !strings.Contains(err.Error(), "is not public") {
+		t.Fatalf("expected loopback address to be blocked, got %v", err)
+	}
+	// Names are checked after resolution, not only as literals.
+	localhost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	if _, err := tool.Execute("f", map[string]any{"url": localhost + "/page"}); err == nil || !strings.Contains(err.Error(), "is not public") {
+		t.Fatalf("expected resolved loopback address to be blocked, got %v", err)
+	}
+
+	for _, addr := range []string{"10.1.2.3", "169.254.169.254", "100.64.0.1", "::1", "fd00::1", "::ffff:192.168.1.1"} {
+		if !blockedAddress(netip.MustParseAddr(addr)) {
+			t.Fatalf("expected %s to be blocked", addr)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:4700::1111"} {
+		if blockedAddress(netip.MustParseAddr(addr)) {
+			t.Fatalf("expected %s to be allowed", addr)
+		}
+	}
+}
+
+func TestFetchHostLists(t *testing.T) {
+	server := fetchServer(t)
+
+	allowed := NewFetchTool(FetchOptions{AllowPrivate: true, AllowHosts: []string{"127.0.0.1"}})
+	if _, err := allowed.Execute("f", map[string]any{"url": server.URL + "/data"}); err != nil {
+		t.Fatalf("expected allowed host to be fetched, got %v", err)
+	}
+	redirect := server.URL + "/redirect?to=http://other.example.com/"
+	if _, err := allowed.Execute("f", map[string]any{"url": redirect}); err == nil || !strings.Contains(err.Error(), "host is not allowed: other.example.com") {
+		t.Fatalf("expected redirect to a host outside the allow list to fail, got %v", err)
+	}
+
+	denied := NewFetchTool(FetchOptions{AllowPrivate: true, DenyHosts: []string{"*.internal", "127.0.0.1"}})
+	if _, err := denied.Execute("f", map[string]any{"url": server.URL + "/data"}); err == nil || !strings.Contains(err.Error(), "host is denied") {
+		t.Fatalf("expected denied host error, got %v", err)
+	}
+	if !matchHost([]string{"*.internal"}, "api.corp.internal") || matchHost([]string{"*.internal"}, "internal") {
+		t.Fatal("unexpected wildcard host matching")
+	}
+}
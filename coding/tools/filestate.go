@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// FileCheckPolicy controls how write, edit and apply_patch guard against
+// changing files the model has not seen.
+type FileCheckPolicy int
+
+const (
+	// FileChecksStrict rejects overwriting an existing file that was never
+	// read and changing a file that was modified since it was last read.
+	FileChecksStrict FileCheckPolicy = iota
+	// FileChecksStale only rejects changes to files modified since read.
+	FileChecksStale
+	// FileChecksOff disables the checks.
+	FileChecksOff
+)
+
+// fileTracker remembers a hash of each file as the model last saw it, either
+// by reading it or by writing it through a tool. Hashes rather than mtimes
+// are compared so that changes within the filesystem's timestamp resolution
+// are caught. A nil tracker allows everything.
+type fileTracker struct {
+	policy FileCheckPolicy
+	mu     sync.Mutex
+	seen   map[string][sha256.Size]byte
+}
+
+func newFileTracker(policy FileCheckPolicy) *fileTracker {
+	if policy == FileChecksOff {
+		return nil
+	}
+	return &fileTracker{policy: policy, seen: map[string][sha256.Size]byte{}}
+}
+
+// record stores the state of path after the model read or wrote data.
+func (f *fileTracker) record(path string, data []byte) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seen[path] = sha256.Sum256(data)
+}
+
+func (f *fileTracker) forget(path string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.seen, path)
+}
+
+// check returns an error when path may not be changed: it changed on disk
+// since it was seen or, for replacing changes under the strict policy, it was
+// never seen. Edits carry their own context and skip the second rule.
+// current is the file content, or nil if the file does not exist.
+func (f *fileTracker) check(path, display string, current []byte, replacing bool) error {
+	if f == nil || current == nil {
+		return nil
+	}
+	f.mu.Lock()
+	hash, ok := f.seen[path]
+	f.mu.Unlock()
+	if !ok {
+		if replacing && f.policy == FileChecksStrict {
+			return fmt.Errorf("%s exists but has not been read; read it first so existing content is not lost", display)
+		}
+		return nil
+	}
+	if sha256.Sum256(current) == hash {
+		return nil
+	}
+	return fmt.Errorf("%s was modified since it was last read; read it again and retry", display)
+}
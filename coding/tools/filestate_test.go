@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zahlmann/phi/agent"
+)
+
+func toolsByName(tools []agent.Tool) map[string]agent.Tool {
+	byName := map[string]agent.Tool{}
+	for _, tool := range tools {
+		byName[tool.Name()] = tool
+	}
+	return byName
+}
+
+func TestFileChecksRequireReadBeforeWrite(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"main.go": "package main\n", "notes.txt": "keep me\n"})
+	tools := toolsByName(NewCodingTools(dir))
+
+	_, err := tools["write"].Execute("w", map[string]any{"path": "notes.txt", "content": "replaced\n"})
+	if err == nil || !strings.Contains(err.Error(), "notes.txt exists but has not been read") {
+		t.Fatalf("expected unread overwrite to be rejected, got %v", err)
+	}
+	if _, err := tools["write"].Execute("w", map[string]any{"path": "new.txt", "content": "a\n"}); err != nil {
+		t.Fatalf("creating a file failed: %v", err)
+	}
+	if _, err := tools["write"].Execute("w", map[string]any{"path": "new.txt", "content": "b\n"}); err != nil {
+		t.Fatalf("overwriting a file the tool wrote failed: %v", err)
+	}
+	if _, err := tools["read"].Execute("r", map[string]any{"path": "notes.txt", "limit": 1}); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if _, err := tools["write"].Execute("w", map[string]any{"path": "notes.txt", "content": "replaced\n"}); err != nil {
+		t.Fatalf("overwrite after read failed: %v", err)
+	}
+
+	// Edits need no prior read, but a changed file must be read again.
+	if _, err := tools["edit"].Execute("e", map[string]any{"path": "main.go", "oldText": "main", "newText": "app"}); err != nil {
+		t.Fatalf("edit failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package app\n\nfunc init() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = tools["edit"].Execute("e", map[string]any{"path": "main.go", "oldText": "init", "newText": "setup"})
+	if err == nil || !strings.Contains(err.Error(), "main.go was modified since it was last read") {
+		t.Fatalf("expected stale edit to be rejected, got %v", err)
+	}
+	if _, err := tools["read"].Execute("r", map[string]any{"path": "main.go"}); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if _, err := tools["edit"].Execute("e", map[string]any{"path": "main.go", "oldText": "init", "newText": "setup"}); err != nil {
+		t.Fatalf("edit after re-read failed: %v", err)
+	}
+}
+
+func TestFileCheckPolicies(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "one\n"})
+
+	stale := toolsByName(NewCodingToolsWithOptions(dir, Options{FileChecks: FileChecksStale}))
+	if _, err := stale["write"].Execute("w", map[string]any{"path": "a.txt", "content": "two\n"}); err != nil {
+		t.Fatalf("stale policy should allow unread overwrites: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("three\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stale["write"].Execute("w", map[string]any{"path": "a.txt", "content": "four\n"}); err == nil {
+		t.Fatal("stale policy should reject overwriting a changed file")
+	}
+
+	off := toolsByName(NewCodingToolsWithOptions(dir, Options{FileChecks: FileChecksOff}))
+	if _, err := off["write"].Execute("w", map[string]any{"path": "a.txt", "content": "five\n"}); err != nil {
+		t.Fatalf("disabled checks should allow writes: %v", err)
+	}
+}
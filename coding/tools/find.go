@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+const defaultFindLimit = 1000
+
+type findTool struct {
+	paths     pathGuard
+	artifacts *ArtifactStore
+}
+
+func NewFindTool(cwd string) agent.Tool {
+	return &findTool{paths: standalonePaths(cwd), artifacts: defaultArtifacts()}
+}
+
+func (t *findTool) Name() string {
+	return "find"
+}
+
+func (t *findTool) Description() string {
+	return fmt.Sprintf(
+		"Find files and directories by glob pattern. Respects .gitignore. Output is limited to %d results or %s.",
+		defaultFindLimit, formatSize(defaultMaxBytes),
+	)
+}
+
+func (t *findTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Glob pattern, e.g. '*.go', '**/*_test.go' or 'src/**/index.ts'",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Directory to search in (default: working directory)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of results (default %d)", defaultFindLimit),
+				"minimum":     1,
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *findTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	pattern, ok := toStringArg(args, "pattern")
+	if !ok || strings.TrimSpace(pattern) == "" {
+		return agent.ToolResult{}, fmt.Errorf("missing required argument: pattern")
+	}
+	searchPath := "."
+	if raw, ok := toStringArg(args, "path"); ok && strings.TrimSpace(raw) != "" {
+		searchPath = raw
+	}
+	target, err := t.paths.resolve(searchPath, accessRead)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	root := t.paths.rootOf(target)
+	info, err := os.Stat(target)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	if !info.IsDir() {
+		return agent.ToolResult{}, fmt.Errorf("not a directory: %s", searchPath)
+	}
+	limit := defaultFindLimit
+	if raw, ok := args["limit"]; ok {
+		if n, ok := toInt(raw); ok && n > 0 {
+			limit = n
+		}
+	}
+
+	relTarget, err := filepath.Rel(root, target)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	relTarget = filepath.ToSlash(relTarget)
+	match, err := compileGlob(pattern)
+	if err != nil {
+		return agent.ToolResult{}, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	results := []string{}
+	limitReached := false
+	err = walkFiles(root, target, func(rel string, d fs.DirEntry) error {
+		if rel == relTarget {
+			return nil
+		}
+		// Patterns are relative to the searched directory.
+		candidate := rel
+		if relTarget != "." {
+			candidate = strings.TrimPrefix(rel, relTarget+"/")
+		}
+		if !match(candidate) {
+			return nil
+		}
+		if len(results) >= limit {
+			limitReached = true
+			return filepath.SkipAll
+		}
+		display := t.paths.displayPath(root, rel)
+		if d.IsDir() {
+			display += "/"
+		}
+		results = append(results, display)
+		return nil
+	})
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+
+	if len(results) == 0 {
+		return agent.ToolResult{
+			Content: []any{model.TextContent{Type: model.ContentText, Text: "No files found matching pattern"}},
+			Details: map[string]any{"count": 0},
+		}, nil
+	}
+
+	trunc := truncateHead(strings.Join(results, "\n"), defaultMaxLines, defaultMaxBytes)
+	outputText := trunc.Content
+	details := map[string]any{"count": len(results)}
+	notices := []string{}
+	if limitReached {
+		notices = append(notices, fmt.Sprintf("%d results limit reached. Use limit=%d for more, or refine pattern", limit, limit*2))
+		details["resultLimitReached"] = limit
+	}
+	if trunc.Truncated {
+		path, notice := t.artifacts.spill("find", strings.Join(results, "\n"))
+		notices = append(notices, fmt.Sprintf("%s limit reached. %s", formatSize(defaultMaxBytes), notice))
+		details["truncation"] = trunc.toMap()
+		details["artifact"] = path
+	}
+	if len(notices) > 0 {
+		outputText += "\n\n[" + strings.Join(notices, ". ") + "]"
+	}
+
+	return agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: outputText}},
+		Details: details,
+	}, nil
+}
@@ -0,0 +1,340 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+const defaultGitTimeout = 30 * time.Second
+
+// gitRunner runs the git CLI in the working directory for the git tools.
+// Pathspecs are taken literally and confined to the working directory.
+type gitRunner struct {
+	paths     pathGuard
+	artifacts *ArtifactStore
+	timeout   time.Duration
+}
+
+// NewGitTools returns git_status, git_diff, git_log, git_show, git_blame and
+// git_commit for the repository containing cwd.
+func NewGitTools(cwd string) []agent.Tool {
+	return newGitTools(&gitRunner{paths: standalonePaths(cwd), artifacts: defaultArtifacts()})
+}
+
+func newGitTools(git *gitRunner) []agent.Tool {
+	return []agent.Tool{
+		&gitStatusTool{git: git},
+		&gitDiffTool{git: git},
+		&gitLogTool{git: git},
+		&gitShowTool{git: git},
+		&gitBlameTool{git: git},
+		&gitCommitTool{git: git},
+	}
+}
+
+// run executes git with args and returns stdout. stdin, when not empty, is
+// passed to the command.
+func (g *gitRunner) run(ctx context.Context, stdin string, args ...string) (string, error) {
+	timeout := g.timeout
+	if timeout <= 0 {
+		timeout = defaultGitTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	global := []string{"--no-pager", "--literal-pathspecs", "-c", "core.quotepath=off", "-c", "color.ui=never"}
+	cmd := exec.CommandContext(ctx, "git", append(global, args...)...)
+	cmd.Dir = g.paths.cwd
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_OPTIONAL_LOCKS=0")
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("git %s timed out after %s", args[0], timeout)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			message := strings.TrimSpace(stderr.String())
+			if message == "" {
+				message = strings.TrimSpace(stdout.String())
+			}
+			return "", fmt.Errorf("git %s failed: %s", args[0], message)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// pathspec resolves a path argument to a pathspec relative to the working
+// directory.
+func (g *gitRunner) pathspec(input string, access pathAccess) (string, error) {
+	target, err := g.paths.resolve(input, access)
+	if err != nil {
+		return "", err
+	}
+	if g.paths.rootOf(target) != g.paths.cwd {
+		return "", fmt.Errorf("path is outside the repository working directory: %s", input)
+	}
+	rel, err := filepath.Rel(g.paths.cwd, target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// optionalPath returns the pathspec arguments for an optional path argument.
+func (g *gitRunner) optionalPath(args map[string]any) ([]string, error) {
+	path, ok := toStringArg(args, "path")
+	if !ok || strings.TrimSpace(path) == "" {
+		return nil, nil
+	}
+	spec, err := g.pathspec(path, accessRead)
+	if err != nil {
+		return nil, err
+	}
+	return []string{"--", spec}, nil
+}
+
+// result truncates output like the other tools, spilling the full text to an
+// artifact.
+func (g *gitRunner) result(kind, output, empty string, details map[string]any) agent.ToolResult {
+	output = strings.TrimRight(output, "\n")
+	if strings.TrimSpace(output) == "" {
+		output = empty
+	}
+	trunc := truncateHead(output, defaultMaxLines, defaultMaxBytes)
+	text := trunc.Content
+	if trunc.Truncated {
+		path, notice := g.artifacts.spill(kind, output)
+		text += fmt.Sprintf("\n\n[Output truncated to %d lines or %s. %s]", defaultMaxLines, formatSize(defaultMaxBytes), notice)
+		details["truncation"] = trunc.toMap()
+		details["artifact"] = path
+	}
+	return agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: text}},
+		Details: details,
+	}
+}
+
+// revision validates a revision argument so it cannot be read as an option.
+func revision(args map[string]any, key, fallback string) (string, error) {
+	rev, ok := toStringArg(args, key)
+	if !ok || strings.TrimSpace(rev) == "" {
+		if fallback == "" {
+			return "", fmt.Errorf("missing required argument: %s", key)
+		}
+		return fallback, nil
+	}
+	rev = strings.TrimSpace(rev)
+	if strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("invalid revision: %s", rev)
+	}
+	return rev, nil
+}
+
+type gitStatusTool struct {
+	git *gitRunner
+}
+
+func (t *gitStatusTool) Name() string {
+	return "git_status"
+}
+
+func (t *gitStatusTool) Description() string {
+	return "Show the current branch, its upstream and the changed and untracked files."
+}
+
+func (t *gitStatusTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{"type": "string", "description": "Limit status to this path"},
+		},
+	}
+}
+
+func (t *gitStatusTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *gitStatusTool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	spec, err := t.git.optionalPath(args)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	status, err := t.git.status(ctx, spec...)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	lines := []string{"## " + status.header}
+	files := make([]map[string]any, 0, len(status.files))
+	for _, file := range status.files {
+		lines = append(lines, file.String())
+		entry := map[string]any{"path": file.path, "index": string(file.index), "worktree": string(file.worktree)}
+		if file.from != "" {
+			entry["from"] = file.from
+		}
+		files = append(files, entry)
+	}
+	if len(status.files) == 0 {
+		lines = append(lines, "(working tree clean)")
+	}
+	details := map[string]any{
+		"branch":   status.branch,
+		"detached": status.detached,
+		"files":    files,
+	}
+	if status.upstream != "" {
+		details["upstream"] = status.upstream
+		details["ahead"] = status.ahead
+		details["behind"] = status.behind
+	}
+	return t.git.result("git", strings.Join(lines, "\n"), "", details), nil
+}
+
+type gitCommitTool struct {
+	git *gitRunner
+}
+
+func (t *gitCommitTool) Name() string {
+	return "git_commit"
+}
+
+func (t *gitCommitTool) Description() string {
+	return "Commit staged changes, optionally staging paths or all tracked changes under the working directory first. " +
+		"Never amends, pushes or skips hooks, and refuses to commit during a merge, rebase or " +
+		"cherry-pick or when nothing is staged."
+}
+
+func (t *gitCommitTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"message": map[string]any{"type": "string", "description": "Commit message"},
+			"paths": map[string]any{
+				"type":        "array",
+				"description": "Paths to stage before committing, including new and deleted files",
+				"items":       map[string]any{"type": "string"},
+			},
+			"all": map[string]any{
+				"type":        "boolean",
+				"description": "Stage changes to all tracked files under the working directory before committing",
+			},
+		},
+		"required": []string{"message"},
+	}
+}
+
+func (t *gitCommitTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *gitCommitTool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	message, _ := toStringArg(args, "message")
+	if strings.TrimSpace(message) == "" {
+		return agent.ToolResult{}, fmt.Errorf("missing required argument: message")
+	}
+	specs := []string{}
+	if raw, ok := args["paths"]; ok {
+		items, ok := raw.([]any)
+		if !ok {
+			return agent.ToolResult{}, fmt.Errorf("paths must be an array")
+		}
+		for _, item := range items {
+			path, ok := item.(string)
+			if !ok || strings.TrimSpace(path) == "" {
+				return agent.ToolResult{}, fmt.Errorf("paths must be non-empty strings")
+			}
+			spec, err := t.git.pathspec(path, accessWrite)
+			if err != nil {
+				return agent.ToolResult{}, err
+			}
+			specs = append(specs, spec)
+		}
+	}
+	if err := t.git.checkNoOperation(ctx); err != nil {
+		return agent.ToolResult{}, err
+	}
+
+	if all, _ := args["all"].(bool); all {
+		if _, err := t.git.run(ctx, "", "add", "--update", "--", "."); err != nil {
+			return agent.ToolResult{}, err
+		}
+	}
+	if len(specs) > 0 {
+		if _, err := t.git.run(ctx, "", append([]string{"add", "--all", "--"}, specs...)...); err != nil {
+			return agent.ToolResult{}, err
+		}
+	}
+	staged, err := t.git.run(ctx, "", "diff", "--cached", "--name-only", "-z")
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	if strings.Trim(staged, "\x00") == "" {
+		return agent.ToolResult{}, fmt.Errorf("nothing staged to commit; pass paths or all to stage changes")
+	}
+	if _, err := t.git.run(ctx, message, "commit", "--quiet", "--file=-"); err != nil {
+		return agent.ToolResult{}, err
+	}
+
+	commits, err := t.git.log(ctx, 1, "HEAD")
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	files, err := t.git.numstat(ctx, "show", "--format=", "HEAD")
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	status, err := t.git.status(ctx)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	commit := commits[0]
+	text := fmt.Sprintf("Committed %s on %s: %s\n%s", commit.short(), status.branchName(), commit.subject, diffStatSummary(files))
+	return t.git.result("git", text, "", map[string]any{
+		"hash":    commit.hash,
+		"branch":  status.branch,
+		"subject": commit.subject,
+		"files":   numstatMaps(files),
+	}), nil
+}
+
+// checkNoOperation refuses to commit while a merge, rebase, cherry-pick or
+// revert is in progress, where a commit would conclude it.
+func (g *gitRunner) checkNoOperation(ctx context.Context) error {
+	markers := []struct{ path, operation string }{
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+	}
+	for _, marker := range markers {
+		out, err := g.run(ctx, "", "rev-parse", "--git-path", marker.path)
+		if err != nil {
+			return err
+		}
+		path := strings.TrimSpace(out)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(g.paths.cwd, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("a %s is in progress; finish it with bash before committing", marker.operation)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	gitContextMaxFiles   = 20
+	gitContextMaxCommits = 5
+)
+
+// GitContext describes the git repository containing cwd for a system
+// prompt: the branch, changed files and recent commits. It returns "" when
+// cwd is not inside a repository or git is unavailable.
+func GitContext(cwd string) string {
+	git := &gitRunner{paths: newPathGuard(cwd, nil)}
+	ctx := context.Background()
+	root, err := git.run(ctx, "", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return ""
+	}
+	status, err := git.status(ctx)
+	if err != nil {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Git repository: %s\n", strings.TrimSpace(root))
+	branch := status.branchName()
+	if status.upstream != "" {
+		branch += fmt.Sprintf(" (tracking %s, ahead %d, behind %d)", status.upstream, status.ahead, status.behind)
+	}
+	fmt.Fprintf(&out, "Branch: %s\n", branch)
+	if len(status.files) == 0 {
+		out.WriteString("Working tree: clean\n")
+	} else {
+		fmt.Fprintf(&out, "Changed files (%d):\n", len(status.files))
+		for i, file := range status.files {
+			if i == gitContextMaxFiles {
+				fmt.Fprintf(&out, "... and %d more\n", len(status.files)-i)
+				break
+			}
+			fmt.Fprintf(&out, "%s\n", file)
+		}
+	}
+	// A repository without commits has no log.
+	if commits, err := git.log(ctx, gitContextMaxCommits, "HEAD"); err == nil && len(commits) > 0 {
+		out.WriteString("Recent commits:\n")
+		for _, commit := range commits {
+			fmt.Fprintf(&out, "%s %s\n", commit.short(), commit.subject)
+		}
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
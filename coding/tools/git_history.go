@@ -0,0 +1,328 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zahlmann/phi/agent"
+)
+
+const (
+	defaultGitLogLimit   = 20
+	defaultBlameMaxLines = 200
+)
+
+type gitDiffTool struct {
+	git *gitRunner
+}
+
+func (t *gitDiffTool) Name() string {
+	return "git_diff"
+}
+
+func (t *gitDiffTool) Description() string {
+	return "Show unstaged changes, or staged changes with staged=true, optionally for one path. " +
+		"Untracked files are not included; see git_status."
+}
+
+func (t *gitDiffTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"staged": map[string]any{"type": "boolean", "description": "Show changes staged for commit"},
+			"path":   map[string]any{"type": "string", "description": "Limit the diff to this path"},
+			"context": map[string]any{
+				"type":        "integer",
+				"description": "Lines of context around each change (default 3)",
+				"minimum":     0,
+			},
+		},
+	}
+}
+
+func (t *gitDiffTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *gitDiffTool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	spec, err := t.git.optionalPath(args)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	contextLines := 3
+	if raw, ok := args["context"]; ok {
+		if n, ok := toInt(raw); ok && n >= 0 {
+			contextLines = n
+		}
+	}
+	base := []string{"diff", "--no-ext-diff"}
+	staged, _ := args["staged"].(bool)
+	if staged {
+		base = append(base, "--cached")
+	}
+	diff, err := t.git.run(ctx, "", append(append(base, "-U"+strconv.Itoa(contextLines)), spec...)...)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	files, err := t.git.numstat(ctx, append(base, spec...)...)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	empty := "No unstaged changes"
+	if staged {
+		empty = "No staged changes"
+	}
+	return t.git.result("git-diff", diff, empty, map[string]any{
+		"staged": staged,
+		"files":  numstatMaps(files),
+	}), nil
+}
+
+type gitLogTool struct {
+	git *gitRunner
+}
+
+func (t *gitLogTool) Name() string {
+	return "git_log"
+}
+
+func (t *gitLogTool) Description() string {
+	return fmt.Sprintf("List commits reachable from a revision (default HEAD), newest first, optionally only those touching a path. "+
+		"Returns %d commits unless limit is set.", defaultGitLogLimit)
+}
+
+func (t *gitLogTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"revision": map[string]any{"type": "string", "description": "Revision or range, e.g. main or v1.0..HEAD"},
+			"path":     map[string]any{"type": "string", "description": "Only commits touching this path"},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of commits (default %d)", defaultGitLogLimit),
+				"minimum":     1,
+			},
+		},
+	}
+}
+
+func (t *gitLogTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *gitLogTool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	rev, err := revision(args, "revision", "HEAD")
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	spec, err := t.git.optionalPath(args)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	limit := defaultGitLogLimit
+	if raw, ok := args["limit"]; ok {
+		if n, ok := toInt(raw); ok && n > 0 {
+			limit = n
+		}
+	}
+	commits, err := t.git.log(ctx, limit, append([]string{rev}, spec...)...)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	lines := make([]string, 0, len(commits))
+	entries := make([]map[string]any, 0, len(commits))
+	for _, commit := range commits {
+		lines = append(lines, fmt.Sprintf("%s %s %s: %s", commit.short(), shortDate(commit.date), commit.author, commit.subject))
+		entries = append(entries, commit.toMap())
+	}
+	return t.git.result("git-log", strings.Join(lines, "\n"), "No commits found", map[string]any{
+		"revision": rev,
+		"commits":  entries,
+	}), nil
+}
+
+type gitShowTool struct {
+	git *gitRunner
+}
+
+func (t *gitShowTool) Name() string {
+	return "git_show"
+}
+
+func (t *gitShowTool) Description() string {
+	return "Show a commit with its message and diff, or with path set, the file's content at that revision."
+}
+
+func (t *gitShowTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"revision": map[string]any{"type": "string", "description": "Commit, branch or tag (default HEAD)"},
+			"path":     map[string]any{"type": "string", "description": "Show this file as of the revision"},
+		},
+	}
+}
+
+func (t *gitShowTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *gitShowTool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	rev, err := revision(args, "revision", "HEAD")
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	if path, ok := toStringArg(args, "path"); ok && strings.TrimSpace(path) != "" {
+		spec, err := t.git.pathspec(path, accessRead)
+		if err != nil {
+			return agent.ToolResult{}, err
+		}
+		content, err := t.git.run(ctx, "", "show", rev+":./"+spec)
+		if err != nil {
+			return agent.ToolResult{}, err
+		}
+		return t.git.result("git-show", content, "(empty file)", map[string]any{
+			"revision": rev,
+			"path":     spec,
+		}), nil
+	}
+
+	commits, err := t.git.log(ctx, 1, rev)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	if len(commits) == 0 {
+		return agent.ToolResult{}, fmt.Errorf("no commit found for %s", rev)
+	}
+	show, err := t.git.run(ctx, "", "show", "--no-ext-diff", "--format=fuller", rev)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	files, err := t.git.numstat(ctx, "show", "--format=", rev)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	details := commits[0].toMap()
+	details["files"] = numstatMaps(files)
+	return t.git.result("git-show", show, "", details), nil
+}
+
+type gitBlameTool struct {
+	git *gitRunner
+}
+
+func (t *gitBlameTool) Name() string {
+	return "git_blame"
+}
+
+func (t *gitBlameTool) Description() string {
+	return fmt.Sprintf("Show which commit last changed each line of a file. Returns up to %d lines from offset unless limit is set.",
+		defaultBlameMaxLines)
+}
+
+func (t *gitBlameTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":     map[string]any{"type": "string", "description": "File to blame"},
+			"revision": map[string]any{"type": "string", "description": "Blame the file as of this revision"},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Line number to start from (1-indexed)",
+				"minimum":     1,
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of lines (default %d)", defaultBlameMaxLines),
+				"minimum":     1,
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *gitBlameTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	return t.ExecuteContext(context.Background(), toolCallID, args)
+}
+
+func (t *gitBlameTool) ExecuteContext(ctx context.Context, toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	path, ok := toStringArg(args, "path")
+	if !ok || strings.TrimSpace(path) == "" {
+		return agent.ToolResult{}, fmt.Errorf("missing required argument: path")
+	}
+	spec, err := t.git.pathspec(path, accessRead)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	offset, limit := 1, defaultBlameMaxLines
+	if raw, ok := args["offset"]; ok {
+		if n, ok := toInt(raw); ok && n > 0 {
+			offset = n
+		}
+	}
+	if raw, ok := args["limit"]; ok {
+		if n, ok := toInt(raw); ok && n > 0 {
+			limit = n
+		}
+	}
+	blameArgs := []string{"blame", "--porcelain", "-L", fmt.Sprintf("%d,+%d", offset, limit)}
+	if _, ok := args["revision"]; ok {
+		rev, err := revision(args, "revision", "HEAD")
+		if err != nil {
+			return agent.ToolResult{}, err
+		}
+		blameArgs = append(blameArgs, rev)
+	}
+	out, err := t.git.run(ctx, "", append(blameArgs, "--", spec)...)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+
+	lines, commits := parseBlame(out)
+	width := 0
+	for _, line := range lines {
+		width = maxInt(width, len(commits[line.commit].author))
+	}
+	text := make([]string, 0, len(lines))
+	for _, line := range lines {
+		commit := commits[line.commit]
+		text = append(text, fmt.Sprintf("%s (%-*s %s %d) %s",
+			commit.short(), width, commit.author, blameDate(commit.date), line.line, line.content))
+	}
+	entries := make([]map[string]any, 0, len(commits))
+	for _, commit := range commits {
+		entry := commit.toMap()
+		entry["date"] = blameDate(commit.date)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i]["hash"].(string) < entries[j]["hash"].(string)
+	})
+	details := map[string]any{"path": spec, "commits": entries, "lines": len(lines)}
+	if len(lines) > 0 {
+		details["startLine"] = lines[0].line
+		details["endLine"] = lines[len(lines)-1].line
+	}
+	return t.git.result("git-blame", strings.Join(text, "\n"), "(empty file)", details), nil
+}
+
+// shortDate keeps the day of an ISO 8601 date.
+func shortDate(date string) string {
+	if len(date) >= 10 {
+		return date[:10]
+	}
+	return date
+}
+
+// blameDate formats the Unix time blame reports; uncommitted lines have none.
+func blameDate(value string) string {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return value
+	}
+	return time.Unix(seconds, 0).UTC().Format("2006-01-02")
+}
@@ -0,0 +1,274 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type gitStatus struct {
+	header   string
+	branch   string
+	detached bool
+	upstream string
+	ahead    int
+	behind   int
+	files    []gitStatusFile
+}
+
+type gitStatusFile struct {
+	index    byte
+	worktree byte
+	path     string
+	from     string
+}
+
+func (f gitStatusFile) String() string {
+	if f.from != "" {
+		return fmt.Sprintf("%c%c %s -> %s", f.index, f.worktree, f.from, f.path)
+	}
+	return fmt.Sprintf("%c%c %s", f.index, f.worktree, f.path)
+}
+
+func (s gitStatus) branchName() string {
+	if s.detached {
+		return "detached HEAD"
+	}
+	return s.branch
+}
+
+func (g *gitRunner) status(ctx context.Context, pathspec ...string) (gitStatus, error) {
+	out, err := g.run(ctx, "", append([]string{"status", "--porcelain=v1", "--branch", "-z"}, pathspec...)...)
+	if err != nil {
+		return gitStatus{}, err
+	}
+	return parseGitStatus(out), nil
+}
+
+// parseGitStatus reads `git status --porcelain=v1 --branch -z`. Renames and
+// copies are followed by a record with the original path.
+func parseGitStatus(out string) gitStatus {
+	var status gitStatus
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if strings.HasPrefix(record, "## ") {
+			status.parseHeader(strings.TrimPrefix(record, "## "))
+			continue
+		}
+		if len(record) < 4 {
+			continue
+		}
+		file := gitStatusFile{index: record[0], worktree: record[1], path: record[3:]}
+		if (file.index == 'R' || file.index == 'C') && i+1 < len(records) {
+			i++
+			file.from = records[i]
+		}
+		status.files = append(status.files, file)
+	}
+	return status
+}
+
+// parseHeader reads branch lines such as "main...origin/main [ahead 1,
+// behind 2]", "No commits yet on main" and "HEAD (no branch)".
+func (s *gitStatus) parseHeader(header string) {
+	s.header = header
+	for _, prefix := range []string{"No commits yet on ", "Initial commit on "} {
+		if strings.HasPrefix(header, prefix) {
+			s.branch = strings.TrimPrefix(header, prefix)
+			return
+		}
+	}
+	if strings.HasPrefix(header, "HEAD (no branch)") {
+		s.detached = true
+		return
+	}
+	if i := strings.Index(header, " ["); i >= 0 {
+		for _, part := range strings.Split(strings.TrimSuffix(header[i+2:], "]"), ", ") {
+			fields := strings.Fields(part)
+			if len(fields) != 2 {
+				continue
+			}
+			n, _ := strconv.Atoi(fields[1])
+			switch fields[0] {
+			case "ahead":
+				s.ahead = n
+			case "behind":
+				s.behind = n
+			}
+		}
+		header = header[:i]
+	}
+	s.branch, s.upstream, _ = strings.Cut(header, "...")
+}
+
+type gitCommit struct {
+	hash    string
+	author  string
+	email   string
+	date    string
+	subject string
+}
+
+func (c gitCommit) short() string {
+	if len(c.hash) > 7 {
+		return c.hash[:7]
+	}
+	return c.hash
+}
+
+func (c gitCommit) toMap() map[string]any {
+	return map[string]any{
+		"hash":    c.hash,
+		"author":  c.author,
+		"email":   c.email,
+		"date":    c.date,
+		"subject": c.subject,
+	}
+}
+
+// gitLogFormat separates fields with NUL and commits with a record separator.
+const gitLogFormat = "--format=%H%x00%an%x00%ae%x00%aI%x00%s%x1e"
+
+func (g *gitRunner) log(ctx context.Context, limit int, args ...string) ([]gitCommit, error) {
+	out, err := g.run(ctx, "", append([]string{"log", gitLogFormat, "-n", strconv.Itoa(limit)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseGitLog(out), nil
+}
+
+func parseGitLog(out string) []gitCommit {
+	commits := []gitCommit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimLeft(record, "\n"), "\x00")
+		if len(fields) != 5 {
+			continue
+		}
+		commits = append(commits, gitCommit{
+			hash:    fields[0],
+			author:  fields[1],
+			email:   fields[2],
+			date:    fields[3],
+			subject: fields[4],
+		})
+	}
+	return commits
+}
+
+type gitFileStat struct {
+	path      string
+	from      string
+	additions int
+	deletions int
+	binary    bool
+}
+
+// numstat runs a diff-like command with --numstat -z and parses the result.
+func (g *gitRunner) numstat(ctx context.Context, args ...string) ([]gitFileStat, error) {
+	out, err := g.run(ctx, "", append([]string{args[0], "--numstat", "-z"}, args[1:]...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseNumstat(out), nil
+}
+
+// parseNumstat reads `--numstat -z` output: "added\tdeleted\tpath" records,
+// or "added\tdeleted\t" followed by the old and new path for renames. Binary
+// files report "-" for both counts.
+func parseNumstat(out string) []gitFileStat {
+	stats := []gitFileStat{}
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		fields := strings.SplitN(strings.TrimLeft(records[i], "\n"), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		stat := gitFileStat{path: fields[2], binary: fields[0] == "-"}
+		stat.additions, _ = strconv.Atoi(fields[0])
+		stat.deletions, _ = strconv.Atoi(fields[1])
+		if stat.path == "" && i+2 < len(records) {
+			stat.from, stat.path = records[i+1], records[i+2]
+			i += 2
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+func numstatMaps(stats []gitFileStat) []map[string]any {
+	out := make([]map[string]any, 0, len(stats))
+	for _, stat := range stats {
+		entry := map[string]any{"path": stat.path, "additions": stat.additions, "deletions": stat.deletions}
+		if stat.from != "" {
+			entry["from"] = stat.from
+		}
+		if stat.binary {
+			entry["binary"] = true
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func diffStatSummary(stats []gitFileStat) string {
+	additions, deletions := 0, 0
+	for _, stat := range stats {
+		additions += stat.additions
+		deletions += stat.deletions
+	}
+	noun := "files"
+	if len(stats) == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("%d %s changed, %d insertions(+), %d deletions(-)", len(stats), noun, additions, deletions)
+}
+
+type gitBlameLine struct {
+	commit  string
+	line    int
+	content string
+}
+
+// parseBlame reads `git blame --porcelain`. Commit headers are only given
+// the first time a commit appears.
+func parseBlame(out string) ([]gitBlameLine, map[string]gitCommit) {
+	lines := []gitBlameLine{}
+	commits := map[string]gitCommit{}
+	var current gitBlameLine
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			current.content = line[1:]
+			lines = append(lines, current)
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		if len(key) == 40 || len(key) == 64 {
+			fields := strings.Fields(value)
+			if len(fields) >= 2 {
+				n, _ := strconv.Atoi(fields[1])
+				current = gitBlameLine{commit: key, line: n}
+				if _, ok := commits[key]; !ok {
+					commits[key] = gitCommit{hash: key}
+				}
+				continue
+			}
+		}
+		commit := commits[current.commit]
+		switch key {
+		case "author":
+			commit.author = value
+		case "author-mail":
+			commit.email = strings.Trim(value, "<>")
+		case "author-time":
+			commit.date = value
+		case "summary":
+			commit.subject = value
+		default:
+			continue
+		}
+		commits[current.commit] = commit
+	}
+	return lines, commits
+}
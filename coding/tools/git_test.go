@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+func initGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, files)
+	gitCmd(t, dir, "init", "--quiet", "--initial-branch=main")
+	gitCmd(t, dir, "config", "user.name", "Test")
+	gitCmd(t, dir, "config", "user.email", "test@example.com")
+	gitCmd(t, dir, "config", "commit.gpgsign", "false")
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "--quiet", "-m", "initial commit")
+	return dir
+}
+
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+func resultText(t *testing.T, result agent.ToolResult) string {
+	t.Helper()
+	text, ok := result.Content[0].(model.TextContent)
+	if !ok {
+		t.Fatalf("expected text content, got %T", result.Content[0])
+	}
+	return text.Text
+}
+
+func TestGitStatusAndDiff(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{"a.txt": "one\n", "b.txt": "keep\n"})
+	writeTree(t, dir, map[string]string{"a.txt": "two\n", "new.txt": "new\n", "b.txt": "staged\n"})
+	gitCmd(t, dir, "add", "b.txt")
+	tools := toolsByName(NewGitTools(dir))
+
+	result, err := tools["git_status"].Execute("s", map[string]any{})
+	if err != nil {
+		t.Fatalf("git_status failed: %v", err)
+	}
+	text := resultText(t, result)
+	for _, want := range []string{"## main", " M a.txt", "M  b.txt", "?? new.txt"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in status:\n%s", want, text)
+		}
+	}
+	if result.Details["branch"] != "main" || len(result.Details["files"].([]map[string]any)) != 3 {
+		t.Fatalf("unexpected status details: %#v", result.Details)
+	}
+
+	result, err = tools["git_diff"].Execute("d", map[string]any{"path": "a.txt"})
+	if err != nil {
+		t.Fatalf("git_diff failed: %v", err)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "-one\n+two") || strings.Contains(text, "b.txt") {
+		t.Fatalf("unexpected unstaged diff:\n%s", text)
+	}
+	files := result.Details["files"].([]map[string]any)
+	if len(files) != 1 || files[0]["path"] != "a.txt" || files[0]["additions"] != 1 || files[0]["deletions"] != 1 {
+		t.Fatalf("unexpected diff details: %#v", files)
+	}
+
+	result, err = tools["git_diff"].Execute("d", map[string]any{"staged": true})
+	if err != nil {
+		t.Fatalf("staged git_diff failed: %v", err)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "+staged") || strings.Contains(text, "a.txt") {
+		t.Fatalf("unexpected staged diff:\n%s", text)
+	}
+
+	if _, err := tools["git_diff"].Execute("d", map[string]any{"path": "../outside"}); err == nil || !strings.Contains(err.Error(), "escapes working directory") {
+		t.Fatalf("expected path escape error, got %v", err)
+	}
+}
+
+func TestGitCommitGuards(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{"a.txt": "one\n"})
+	tools := toolsByName(NewGitTools(dir))
+
+	if _, err := tools["git_commit"].Execute("c", map[string]any{"message": " "}); err == nil || !strings.Contains(err.Error(), "message") {
+		t.Fatalf("expected missing message error, got %v", err)
+	}
+	writeTree(t, dir, map[string]string{"a.txt": "two\n", "b.txt": "new\n"})
+	if _, err := tools["git_commit"].Execute("c", map[string]any{"message": "change"}); err == nil || !strings.Contains(err.Error(), "nothing staged") {
+		t.Fatalf("expected nothing staged error, got %v", err)
+	}
+
+	result, err := tools["git_commit"].Execute("c", map[string]any{"message": "add b", "paths": []any{"b.txt"}})
+	if err != nil {
+		t.Fatalf("git_commit failed: %v", err)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "on main: add b") || !strings.Contains(text, "1 file changed") {
+		t.Fatalf("unexpected commit output:\n%s", text)
+	}
+	if status := gitCmd(t, dir, "status", "--porcelain"); strings.TrimSpace(status) != "M a.txt" {
+		t.Fatalf("expected only b.txt to be committed, status:\n%s", status)
+	}
+	hash := result.Details["hash"].(string)
+
+	result, err = tools["git_log"].Execute("l", map[string]any{"limit": 1})
+	if err != nil {
+		t.Fatalf("git_log failed: %v", err)
+	}
+	commits := result.Details["commits"].([]map[string]any)
+	if len(commits) != 1 || commits[0]["hash"] != hash || commits[0]["subject"] != "add b" {
+		t.Fatalf("unexpected log details: %#v", commits)
+	}
+
+	result, err = tools["git_show"].Execute("s", map[string]any{"revision": hash})
+	if err != nil {
+		t.Fatalf("git_show failed: %v", err)
+	}
+	if text := resultText(t, result); !strings.Contains(text, "add b") || !strings.Contains(text, "+new") {
+		t.Fatalf("unexpected show output:\n%s", text)
+	}
+	result, err = tools["git_show"].Execute("s", map[string]any{"revision": "HEAD~1", "path": "a.txt"})
+	if err != nil {
+		t.Fatalf("git_show with path failed: %v", err)
+	}
+	if text := resultText(t, result); text != "one" {
+		t.Fatalf("expected file content at HEAD~1, got %q", text)
+	}
+	if _, err := tools["git_log"].Execute("l", map[string]any{"revision": "--output=x"}); err == nil || !strings.Contains(err.Error(), "invalid revision") {
+		t.Fatalf("expected invalid revision error, got %v", err)
+	}
+
+	gitCmd(t, dir, "commit", "--quiet", "-am", "update a")
+	writeTree(t, dir, map[string]string{".git/MERGE_HEAD": hash + "\n", "a.txt": "three\n"})
+	if _, err := tools["git_commit"].Execute("c", map[string]any{"message": "merge", "all": true}); err == nil || !strings.Contains(err.Error(), "merge is in progress") {
+		t.Fatalf("expected merge in progress error, got %v", err)
+	}
+}
+
+func TestGitBlame(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{"a.txt": "one\ntwo\nthree\n"})
+	writeTree(t, dir, map[string]string{"a.txt": "one\nTWO\nthree\n"})
+	gitCmd(t, dir, "commit", "--quiet", "-am", "shout")
+	tools := toolsByName(NewGitTools(dir))
+
+	result, err := tools["git_blame"].Execute("b", map[string]any{"path": "a.txt", "offset": 2, "limit": 2})
+	if err != nil {
+		t.Fatalf("git_blame failed: %v", err)
+	}
+	lines := strings.Split(resultText(t, result), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " 2) TWO") || !strings.HasSuffix(lines[1], " 3) three") {
+		t.Fatalf("unexpected blame output: %q", lines)
+	}
+	if result.Details["startLine"] != 2 || len(result.Details["commits"].([]map[string]any)) != 2 {
+		t.Fatalf("unexpected blame details: %#v", result.Details)
+	}
+}
+
+func TestGitContext(t *testing.T) {
+	if GitContext(t.TempDir()) != "" {
+		t.Fatal("expected no context outside a repository")
+	}
+	dir := initGitRepo(t, map[string]string{"a.txt": "one\n"})
+	writeTree(t, dir, map[string]string{"a.txt": "two\n"})
+	context := GitContext(dir)
+	for _, want := range []string{"Branch: main", "Changed files (1):\n M a.txt", "Recent commits:\n", "initial commit"} {
+		if !strings.Contains(context, want) {
+			t.Fatalf("expected %q in context:\n%s", want, context)
+		}
+	}
+}
+
+func TestParseGitStatusHeader(t *testing.T) {
+	status := parseGitStatus("## main...origin/main [ahead 2, behind 1]\x00R  new.go\x00old.go\x00")
+	if status.branch != "main" || status.upstream != "origin/main" || status.ahead != 2 || status.behind != 1 {
+		t.Fatalf("unexpected branch info: %+v", status)
+	}
+	if len(status.files) != 1 || status.files[0].String() != "R  old.go -> new.go" {
+		t.Fatalf("unexpected files: %+v", status.files)
+	}
+	if status := parseGitStatus("## No commits yet on trunk\x00"); status.branch != "trunk" {
+		t.Fatalf("expected unborn branch, got %+v", status)
+	}
+	if status := parseGitStatus("## HEAD (no branch)\x00"); !status.detached || status.branchName() != "detached HEAD" {
+		t.Fatalf("expected detached head, got %+v", status)
+	}
+}
+
+func TestParseNumstatRename(t *testing.T) {
+	stats := parseNumstat("1\t2\t\x00old.go\x00new.go\x00-\t-\timage.png\x00")
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 stats, got %+v", stats)
+	}
+	if stats[0].from != "old.go" || stats[0].path != "new.go" || stats[0].additions != 1 || stats[0].deletions != 2 {
+		t.Fatalf("unexpected rename stat: %+v", stats[0])
+	}
+	if !stats[1].binary || stats[1].path != "image.png" {
+		t.Fatalf("unexpected binary stat: %+v", stats[1])
+	}
+}
+
+func TestGitCommitAllStagesWorkingDirectoryOnly(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{"a.txt": "one\n", "sub/b.txt": "one\n"})
+	writeTree(t, dir, map[string]string{"a.txt": "two\n", "sub/b.txt": "two\n"})
+	tools := toolsByName(NewGitTools(filepath.Join(dir, "sub")))
+
+	if _, err := tools["git_commit"].Execute("c", map[string]any{"message": "update b", "all": true}); err != nil {
+		t.Fatalf("git_commit failed: %v", err)
+	}
+	if status := gitCmd(t, dir, "status", "--porcelain"); strings.TrimSpace(status) != "M a.txt" {
+		t.Fatalf("expected a.txt outside the working directory to stay unstaged, status:\n%s", status)
+	}
+}
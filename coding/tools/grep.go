@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+const (
+	defaultGrepLimit  = 100
+	grepMaxLineLength = 500
+	binarySniffLength = 8000
+	maxGrepFileSize   = 10 * 1024 * 1024
+)
+
+var errGrepLimitReached = errors.New("grep match limit reached")
+
+type grepTool struct {
+	paths     pathGuard
+	artifacts *ArtifactStore
+}
+
+func NewGrepTool(cwd string) agent.Tool {
+	return &grepTool{paths: standalonePaths(cwd), artifacts: defaultArtifacts()}
+}
+
+func (t *grepTool) Name() string {
+	return "grep"
+}
+
+func (t *grepTool) Description() string {
+	return fmt.Sprintf(
+		"Search file contents with a regular expression. Respects .gitignore. Output is limited to %d matches or %s.",
+		defaultGrepLimit, formatSize(defaultMaxBytes),
+	)
+}
+
+func (t *grepTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{"type": "string", "description": "Regular expression (RE2 syntax) to search for"},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File or directory to search (default: working directory)",
+			},
+			"glob": map[string]any{
+				"type":        "string",
+				"description": "Only search files matching this glob, e.g. '*.go' or 'src/**/*.ts'",
+			},
+			"ignoreCase": map[string]any{"type": "boolean", "description": "Case-insensitive search"},
+			"literal": map[string]any{
+				"type":        "boolean",
+				"description": "Treat pattern as a literal string instead of a regular expression",
+			},
+			"context": map[string]any{
+				"type":        "integer",
+				"description": "Lines of context to show before and after each match",
+				"minimum":     0,
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of matches (default %d)", defaultGrepLimit),
+				"minimum":     1,
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *grepTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	pattern, ok := toStringArg(args, "pattern")
+	if !ok || pattern == "" {
+		return agent.ToolResult{}, fmt.Errorf("missing required argument: pattern")
+	}
+	if literal, _ := args["literal"].(bool); literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase, _ := args["ignoreCase"].(bool); ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return agent.ToolResult{}, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	searchPath := "."
+	if raw, ok := toStringArg(args, "path"); ok && strings.TrimSpace(raw) != "" {
+		searchPath = raw
+	}
+	target, err := t.paths.resolve(searchPath, accessRead)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	root := t.paths.rootOf(target)
+
+	var include func(string) bool
+	if glob, ok := toStringArg(args, "glob"); ok && strings.TrimSpace(glob) != "" {
+		if include, err = compileGlob(glob); err != nil {
+			return agent.ToolResult{}, fmt.Errorf("invalid glob: %w", err)
+		}
+	}
+	contextLines := 0
+	if raw, ok := args["context"]; ok {
+		if n, ok := toInt(raw); ok && n > 0 {
+			contextLines = n
+		}
+	}
+	limit := defaultGrepLimit
+	if raw, ok := args["limit"]; ok {
+		if n, ok := toInt(raw); ok && n > 0 {
+			limit = n
+		}
+	}
+
+	var out strings.Builder
+	matches := 0
+	linesTruncated := false
+	searchFile := func(rel, abs string) error {
+		lines, ok := readTextLines(abs)
+		if !ok {
+			return nil
+		}
+		matched := map[int]bool{}
+		hits := []int{}
+		var limitErr error
+		for i, line := range lines {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches >= limit {
+				limitErr = errGrepLimitReached
+				break
+			}
+			matches++
+			matched[i] = true
+			hits = append(hits, i)
+		}
+
+		lastPrinted := -1
+		for _, i := range hits {
+			from := maxInt(i-contextLines, lastPrinted+1)
+			to := minInt(i+contextLines, len(lines)-1)
+			if contextLines > 0 && lastPrinted >= 0 && from > lastPrinted+1 {
+				out.WriteString("--\n")
+			}
+			for j := from; j <= to; j++ {
+				text, cut := truncateLine(lines[j], grepMaxLineLength)
+				linesTruncated = linesTruncated || cut
+				sep := "-"
+				if matched[j] {
+					sep = ":"
+				}
+				fmt.Fprintf(&out, "%s%s%d%s %s\n", rel, sep, j+1, sep, text)
+			}
+			lastPrinted = maxInt(lastPrinted, to)
+		}
+		return limitErr
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	if info.IsDir() {
+		err = walkFiles(root, target, func(rel string, d fs.DirEntry) error {
+			if d.IsDir() || !d.Type().IsRegular() {
+				return nil
+			}
+			if include != nil && !include(rel) {
+				return nil
+			}
+			return searchFile(t.paths.displayPath(root, rel), filepath.Join(root, filepath.FromSlash(rel)))
+		})
+	} else {
+		rel, relErr := filepath.Rel(root, target)
+		if relErr != nil {
+			return agent.ToolResult{}, relErr
+		}
+		err = searchFile(t.paths.displayPath(root, filepath.ToSlash(rel)), target)
+	}
+	limitReached := errors.Is(err, errGrepLimitReached)
+	if err != nil && !limitReached {
+		return agent.ToolResult{}, err
+	}
+
+	if matches == 0 {
+		return agent.ToolResult{
+			Content: []any{model.TextContent{Type: model.ContentText, Text: "No matches found"}},
+			Details: map[string]any{"matches": 0},
+		}, nil
+	}
+
+	trunc := truncateHead(strings.TrimSuffix(out.String(), "\n"), defaultMaxLines, defaultMaxBytes)
+	outputText := trunc.Content
+	details := map[string]any{"matches": matches}
+	notices := []string{}
+	if limitReached {
+		notices = append(notices, fmt.Sprintf("%d matches limit reached. Use limit=%d for more, or refine pattern", limit, limit*2))
+		details["matchLimitReached"] = limit
+	}
+	if trunc.Truncated {
+		path, notice := t.artifacts.spill("grep", strings.TrimSuffix(out.String(), "\n"))
+		notices = append(notices, fmt.Sprintf("%s limit reached. %s", formatSize(defaultMaxBytes), notice))
+		details["truncation"] = trunc.toMap()
+		details["artifact"] = path
+	}
+	if linesTruncated {
+		notices = append(notices, fmt.Sprintf("Some lines truncated to %d chars. Use read to see full lines", grepMaxLineLength))
+		details["linesTruncated"] = true
+	}
+	if len(notices) > 0 {
+		outputText += "\n\n[" + strings.Join(notices, ". ") + "]"
+	}
+
+	return agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: outputText}},
+		Details: details,
+	}, nil
+}
+
+// readTextLines returns the lines of a text file, decoding UTF-16; binary and
+// very large files are skipped.
+func readTextLines(path string) ([]string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxGrepFileSize {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	text, _, ok := decodeText(data)
+	if !ok {
+		return nil, false
+	}
+	lines := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(text)+1)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	return lines, true
+}
+
+func truncateLine(line string, maxChars int) (string, bool) {
+	runes := []rune(line)
+	if len(runes) <= maxChars {
+		return line, false
+	}
+	return string(runes[:maxChars]) + "... [truncated]", true
+}
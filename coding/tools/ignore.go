@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is one .gitignore line. base is the slash separated directory of
+// the .gitignore file relative to the walk root.
+type ignoreRule struct {
+	base     string
+	re       *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+func (m *ignoreMatcher) load(root, relDir string) {
+	file, err := os.Open(filepath.Join(root, filepath.FromSlash(relDir), ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: relDir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		m.rules = append(m.rules, rule)
+	}
+}
+
+// ignored reports whether rel (slash separated, relative to the walk root) is
+// excluded. Later rules override earlier ones, as in git.
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if rule.base != "." {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if !rule.anchored {
+			target = path.Base(target)
+		}
+		if rule.re.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp converts a glob to a regular expression: `**` matches across
+// directories, `*` and `?` stay within one path segment, and `[...]` classes
+// are kept.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// compileGlob matches against the base name when the glob has no slash and
+// against the whole relative path otherwise.
+func compileGlob(glob string) (func(rel string) bool, error) {
+	re, err := regexp.Compile("^" + globToRegexp(strings.TrimPrefix(glob, "./")) + "$")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(glob, "/") {
+		return func(rel string) bool { return re.MatchString(path.Base(rel)) }, nil
+	}
+	return func(rel string) bool { return re.MatchString(rel) }, nil
+}
+
+// walkFiles walks start (inside root) in lexical order, skipping .git and
+// anything excluded by .gitignore files between root and the visited path.
+// fn receives the slash separated path relative to root.
+func walkFiles(root, start string, fn func(rel string, d fs.DirEntry) error) error {
+	matcher := &ignoreMatcher{}
+	matcher.load(root, ".")
+	relStart, err := filepath.Rel(root, start)
+	if err != nil {
+		return err
+	}
+	relStart = filepath.ToSlash(relStart)
+	if relStart != "." {
+		parts := strings.Split(relStart, "/")
+		for i := 1; i < len(parts); i++ {
+			matcher.load(root, strings.Join(parts[:i], "/"))
+		}
+	}
+
+	return filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == start {
+				return err
+			}
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, p)
+		if relErr != nil {
+			return relErr
+		}
+		rel = filepath.ToSlash(rel)
+		if p != start {
+			if d.IsDir() && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if matcher.ignored(rel, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() && rel != "." {
+			matcher.load(root, rel)
+		}
+		return fn(rel, d)
+	})
+}
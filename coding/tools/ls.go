@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+const defaultLsLimit = 500
+
+type lsTool struct {
+	paths     pathGuard
+	artifacts *ArtifactStore
+}
+
+func NewLsTool(cwd string) agent.Tool {
+	return &lsTool{paths: standalonePaths(cwd), artifacts: defaultArtifacts()}
+}
+
+func (t *lsTool) Name() string {
+	return "ls"
+}
+
+func (t *lsTool) Description() string {
+	return fmt.Sprintf(
+		"List a directory with entry types and sizes, sorted by name and including dotfiles. Output is limited to %d entries or %s.",
+		defaultLsLimit, formatSize(defaultMaxBytes),
+	)
+}
+
+func (t *lsTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Directory to list (default: working directory)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of entries (default %d)", defaultLsLimit),
+				"minimum":     1,
+			},
+		},
+	}
+}
+
+func (t *lsTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	listPath := "."
+	if raw, ok := toStringArg(args, "path"); ok && strings.TrimSpace(raw) != "" {
+		listPath = raw
+	}
+	target, err := t.paths.resolve(listPath, accessRead)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	limit := defaultLsLimit
+	if raw, ok := args["limit"]; ok {
+		if n, ok := toInt(raw); ok && n > 0 {
+			limit = n
+		}
+	}
+
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
+	})
+
+	lines := []string{}
+	limitReached := false
+	for _, entry := range entries {
+		if len(lines) >= limit {
+			limitReached = true
+			break
+		}
+		lines = append(lines, formatLsEntry(target, entry))
+	}
+	if len(lines) == 0 {
+		return agent.ToolResult{
+			Content: []any{model.TextContent{Type: model.ContentText, Text: "(empty directory)"}},
+			Details: map[string]any{"path": listPath, "count": 0},
+		}, nil
+	}
+
+	trunc := truncateHead(strings.Join(lines, "\n"), defaultMaxLines, defaultMaxBytes)
+	outputText := trunc.Content
+	details := map[string]any{"path": listPath, "count": len(lines)}
+	notices := []string{}
+	if limitReached {
+		notices = append(notices, fmt.Sprintf("%d entries limit reached. Use limit=%d for more", limit, limit*2))
+		details["entryLimitReached"] = limit
+	}
+	if trunc.Truncated {
+		path, notice := t.artifacts.spill("ls", strings.Join(lines, "\n"))
+		notices = append(notices, fmt.Sprintf("%s limit reached. %s", formatSize(defaultMaxBytes), notice))
+		details["truncation"] = trunc.toMap()
+		details["artifact"] = path
+	}
+	if len(notices) > 0 {
+		outputText += "\n\n[" + strings.Join(notices, ". ") + "]"
+	}
+
+	return agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: outputText}},
+		Details: details,
+	}, nil
+}
+
+func formatLsEntry(dir string, entry os.DirEntry) string {
+	name := entry.Name()
+	kind, size := "file", "-"
+	switch {
+	case entry.Type()&os.ModeSymlink != 0:
+		kind = "link"
+		if target, err := os.Readlink(filepath.Join(dir, name)); err == nil {
+			name += " -> " + target
+		}
+	case entry.IsDir():
+		kind = "dir"
+		name += "/"
+	case !entry.Type().IsRegular():
+		kind = "other"
+	}
+	if kind == "file" || kind == "other" {
+		if info, err := entry.Info(); err == nil {
+			size = formatSize(int(info.Size()))
+		}
+	}
+	return fmt.Sprintf("%-5s %8s  %s", kind, size, name)
+}
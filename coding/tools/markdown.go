@@ -0,0 +1,535 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type htmlTokenKind int
+
+const (
+	htmlText htmlTokenKind = iota
+	htmlStartTag
+	htmlEndTag
+)
+
+type htmlToken struct {
+	kind        htmlTokenKind
+	name        string
+	attrs       map[string]string
+	text        string
+	selfClosing bool
+}
+
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlSkippedElements are dropped with their content: scripts and styles,
+// page chrome and interactive widgets that do not read well as text.
+var htmlSkippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "iframe": true,
+	"nav": true, "aside": true, "form": true, "button": true, "select": true, "dialog": true,
+}
+
+// tokenizeHTML splits src into text and tags. It is lenient rather than
+// spec compliant: comments, doctypes and processing instructions are
+// dropped, and the content of script, style, title and textarea is taken
+// verbatim up to the closing tag.
+func tokenizeHTML(src string) []htmlToken {
+	tokens := []htmlToken{}
+	for len(src) > 0 {
+		lt := strings.IndexByte(src, '<')
+		if lt < 0 {
+			tokens = append(tokens, htmlToken{kind: htmlText, text: src})
+			break
+		}
+		if lt > 0 {
+			tokens = append(tokens, htmlToken{kind: htmlText, text: src[:lt]})
+			src = src[lt:]
+		}
+		switch {
+		case strings.HasPrefix(src, "<!--"):
+			end := strings.Index(src[4:], "-->")
+			if end < 0 {
+				return tokens
+			}
+			src = src[4+end+3:]
+			continue
+		case strings.HasPrefix(src, "<!") || strings.HasPrefix(src, "<?"):
+			end := strings.IndexByte(src, '>')
+			if end < 0 {
+				return tokens
+			}
+			src = src[end+1:]
+			continue
+		}
+
+		closing := strings.HasPrefix(src, "</")
+		start := 1
+		if closing {
+			start = 2
+		}
+		if len(src) <= start || !isASCIILetter(src[start]) {
+			tokens = append(tokens, htmlToken{kind: htmlText, text: "<"})
+			src = src[1:]
+			continue
+		}
+		end := tagEnd(src)
+		if end < 0 {
+			return tokens
+		}
+		tag := src[start:end]
+		src = src[end+1:]
+		name, rest := splitTagName(tag)
+		if closing {
+			tokens = append(tokens, htmlToken{kind: htmlEndTag, name: name})
+			continue
+		}
+		tokens = append(tokens, htmlToken{
+			kind:        htmlStartTag,
+			name:        name,
+			attrs:       parseAttributes(rest),
+			selfClosing: strings.HasSuffix(rest, "/"),
+		})
+		if strings.HasSuffix(rest, "/") {
+			continue
+		}
+
+		switch name {
+		case "script", "style", "title", "textarea":
+			idx := indexFold(src, "</"+name)
+			if idx < 0 {
+				idx = len(src)
+			}
+			if name == "title" || name == "textarea" {
+				tokens = append(tokens, htmlToken{kind: htmlText, text: src[:idx]})
+			}
+			src = src[idx:]
+			if gt := strings.IndexByte(src, '>'); gt >= 0 {
+				src = src[gt+1:]
+			} else {
+				src = ""
+			}
+			tokens = append(tokens, htmlToken{kind: htmlEndTag, name: name})
+		}
+	}
+	return tokens
+}
+
+// tagEnd returns the index of the '>' closing the tag at the start of src,
+// skipping quoted attribute values.
+func tagEnd(src string) int {
+	var quote byte
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+func splitTagName(tag string) (string, string) {
+	i := 0
+	for i < len(tag) && !isHTMLSpace(tag[i]) && tag[i] != '/' {
+		i++
+	}
+	return strings.ToLower(tag[:i]), tag[i:]
+}
+
+func parseAttributes(s string) map[string]string {
+	attrs := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t\n\r\f/")
+		if s == "" {
+			return attrs
+		}
+		i := 0
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[:i])
+		s = strings.TrimLeft(s[i:], " \t\n\r\f")
+		value := ""
+		if strings.HasPrefix(s, "=") {
+			s = strings.TrimLeft(s[1:], " \t\n\r\f")
+			if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+				end := strings.IndexByte(s[1:], s[0])
+				if end < 0 {
+					end = len(s) - 1
+				}
+				value = s[1 : 1+end]
+				s = s[minInt(len(s), end+2):]
+			} else {
+				j := 0
+				for j < len(s) && !isHTMLSpace(s[j]) {
+					j++
+				}
+				value, s = s[:j], s[j:]
+			}
+		}
+		if name != "" {
+			attrs[name] = html.UnescapeString(value)
+		}
+	}
+}
+
+func indexFold(s, substr string) int {
+	return strings.Index(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+type markdownList struct {
+	ordered bool
+	count   int
+}
+
+// markdownWriter builds Markdown from a token stream. Block elements ask for
+// blank lines that are only written before the next content, and inline
+// constructs that need their full text (links, quotes) capture the output
+// from an offset.
+type markdownWriter struct {
+	out      []byte
+	newlines int
+	pre      int
+	lists    []markdownList
+	captures []int
+	links    []string
+	cells    int
+	rows     int
+	base     *url.URL
+}
+
+func (w *markdownWriter) block() {
+	w.newlines = 2
+}
+
+func (w *markdownWriter) line() {
+	w.newlines = maxInt(w.newlines, 1)
+}
+
+func (w *markdownWriter) captureStart() int {
+	if len(w.captures) > 0 {
+		return w.captures[len(w.captures)-1]
+	}
+	return 0
+}
+
+// flush writes pending line breaks, which are dropped at the start of the
+// output or of a capture.
+func (w *markdownWriter) flush() {
+	if len(w.out) > w.captureStart() {
+		for ; w.newlines > 0; w.newlines-- {
+			w.out = append(w.out, '\n')
+		}
+	}
+	w.newlines = 0
+}
+
+func (w *markdownWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	w.flush()
+	w.out = append(w.out, s...)
+}
+
+func (w *markdownWriter) text(s string) {
+	s = html.UnescapeString(s)
+	if w.pre > 0 {
+		// A newline right after <pre> is not part of the content.
+		if bytes.HasSuffix(w.out, []byte("```\n")) {
+			s = strings.TrimPrefix(s, "\n")
+		}
+		w.write(s)
+		return
+	}
+	s = collapseSpace(s)
+	if strings.HasPrefix(s, " ") && (w.newlines > 0 || w.atLineStart()) {
+		s = s[1:]
+	}
+	if s != "" {
+		w.write(s)
+	}
+}
+
+func (w *markdownWriter) atLineStart() bool {
+	if len(w.out) <= w.captureStart() {
+		return true
+	}
+	last := w.out[len(w.out)-1]
+	return last == '\n' || last == ' '
+}
+
+func (w *markdownWriter) push() {
+	w.flush()
+	w.captures = append(w.captures, len(w.out))
+}
+
+// pop removes and returns the output written since the matching push.
+func (w *markdownWriter) pop() string {
+	if len(w.captures) == 0 {
+		return ""
+	}
+	start := w.captures[len(w.captures)-1]
+	w.captures = w.captures[:len(w.captures)-1]
+	captured := string(w.out[start:])
+	w.out = w.out[:start]
+	return captured
+}
+
+func (w *markdownWriter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if w.base == nil || ref == "" {
+		return ref
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return w.base.ResolveReference(parsed).String()
+}
+
+func (w *markdownWriter) start(token htmlToken) {
+	switch name := token.name; name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		w.block()
+		w.write(strings.Repeat("#", int(name[1]-'0')) + " ")
+	case "p", "div", "section", "article", "main", "header", "footer", "figure", "table", "dl", "details":
+		w.block()
+	case "br":
+		if w.pre > 0 {
+			w.write("\n")
+		} else {
+			w.line()
+		}
+	case "hr":
+		w.block()
+		w.write("---")
+		w.block()
+	case "pre":
+		w.block()
+		w.write("```\n")
+		w.pre++
+	case "code", "kbd", "samp":
+		if w.pre == 0 {
+			w.write("`")
+		}
+	case "strong", "b":
+		w.write("**")
+	case "em", "i":
+		w.write("*")
+	case "a":
+		// Fragment and script links only keep their text.
+		href := strings.TrimSpace(token.attrs["href"])
+		if strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			href = ""
+		}
+		w.links = append(w.links, w.resolve(href))
+		w.push()
+	case "img":
+		if alt := collapseSpace(token.attrs["alt"]); strings.TrimSpace(alt) != "" {
+			w.write(fmt.Sprintf("![%s](%s)", strings.TrimSpace(alt), w.resolve(token.attrs["src"])))
+		}
+	case "blockquote":
+		w.block()
+		w.push()
+	case "ul", "ol":
+		if len(w.lists) == 0 {
+			w.block()
+		}
+		w.lists = append(w.lists, markdownList{ordered: name == "ol"})
+	case "li":
+		w.line()
+		marker := "- "
+		depth := len(w.lists)
+		if depth > 0 {
+			list := &w.lists[depth-1]
+			list.count++
+			if list.ordered {
+				marker = fmt.Sprintf("%d. ", list.count)
+			}
+		}
+		w.write(strings.Repeat("  ", maxInt(depth-1, 0)) + marker)
+	case "dt":
+		w.line()
+	case "dd":
+		w.line()
+		w.write(": ")
+	case "tr":
+		w.line()
+		w.write("|")
+		w.cells = 0
+	case "td", "th":
+		w.write(" ")
+	}
+}
+
+func (w *markdownWriter) end(name string) {
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "p", "div", "section", "article", "main", "header", "footer",
+		"figure", "dl", "details":
+		w.block()
+	case "table":
+		w.rows = 0
+		w.block()
+	case "pre":
+		if w.pre > 0 {
+			w.pre--
+			if w.out[len(w.out)-1] != '\n' {
+				w.write("\n")
+			}
+			w.write("```")
+			w.block()
+		}
+	case "code", "kbd", "samp":
+		if w.pre == 0 {
+			w.write("`")
+		}
+	case "strong", "b":
+		w.write("**")
+	case "em", "i":
+		w.write("*")
+	case "a":
+		if len(w.links) == 0 {
+			return
+		}
+		href := w.links[len(w.links)-1]
+		w.links = w.links[:len(w.links)-1]
+		text := strings.TrimSpace(w.pop())
+		switch {
+		case text == "":
+		case href == "":
+			w.write(text)
+		default:
+			w.write(fmt.Sprintf("[%s](%s)", text, href))
+		}
+	case "blockquote":
+		quoted := strings.TrimSpace(w.pop())
+		if quoted != "" {
+			lines := strings.Split(quoted, "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimRight("> "+line, " ")
+			}
+			w.block()
+			w.write(strings.Join(lines, "\n"))
+		}
+		w.block()
+	case "ul", "ol":
+		if len(w.lists) > 0 {
+			w.lists = w.lists[:len(w.lists)-1]
+		}
+		if len(w.lists) == 0 {
+			w.block()
+		} else {
+			w.line()
+		}
+	case "td", "th":
+		w.write(" |")
+		w.cells++
+	case "tr":
+		w.rows++
+		if w.rows == 1 && w.cells > 0 {
+			w.line()
+			w.write("|" + strings.Repeat(" --- |", w.cells))
+		}
+		w.line()
+	}
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// htmlToMarkdown converts an HTML document to Markdown and returns it with
+// the document title. Relative links are resolved against base.
+func htmlToMarkdown(src string, base *url.URL) (string, string) {
+	w := &markdownWriter{base: base}
+	title := ""
+	skipping := ""
+	skipDepth := 0
+	inTitle := false
+	for _, token := range tokenizeHTML(src) {
+		if skipping != "" {
+			switch {
+			case token.kind == htmlStartTag && token.name == skipping:
+				skipDepth++
+			case token.kind == htmlEndTag && token.name == skipping:
+				skipDepth--
+				if skipDepth == 0 {
+					skipping = ""
+				}
+			}
+			continue
+		}
+		switch token.kind {
+		case htmlText:
+			if inTitle {
+				title += token.text
+			} else {
+				w.text(token.text)
+			}
+		case htmlStartTag:
+			switch {
+			case token.name == "title":
+				inTitle = true
+			case token.name == "base" && token.attrs["href"] != "":
+				if ref, err := url.Parse(token.attrs["href"]); err == nil {
+					w.base = w.resolveBase(ref)
+				}
+			case htmlSkippedElements[token.name] && !token.selfClosing:
+				skipping, skipDepth = token.name, 1
+			default:
+				w.start(token)
+			}
+		case htmlEndTag:
+			if token.name == "title" {
+				inTitle = false
+				continue
+			}
+			w.end(token.name)
+		}
+	}
+	for len(w.captures) > 0 {
+		text := w.pop()
+		w.write(text)
+	}
+
+	lines := strings.Split(string(w.out), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	markdown := blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(markdown), strings.TrimSpace(collapseSpace(html.UnescapeString(title)))
+}
+
+func (w *markdownWriter) resolveBase(ref *url.URL) *url.URL {
+	if w.base == nil {
+		return ref
+	}
+	return w.base.ResolveReference(ref)
+}
+
+var htmlWhitespace = regexp.MustCompile(`[ \t\n\r\f]+`)
+
+func collapseSpace(s string) string {
+	return htmlWhitespace.ReplaceAllString(s, " ")
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/")
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "headings and paragraphs",
+			html: "<h2>Title</h2>\n<p>First   line<br>second</p><p>Next</p>",
+			want: "## Title\n\nFirst line\nsecond\n\nNext",
+		},
+		{
+			name: "relative links and images",
+			html: `<p><a href="guide.html">Guide</a> <a href="#top">Top</a> <img src="/logo.png" alt="Logo"></p>`,
+			want: "[Guide](https://example.com/docs/guide.html) Top ![Logo](https://example.com/logo.png)",
+		},
+		{
+			name: "code blocks keep whitespace",
+			html: "<p>Run <code>go test</code>:</p><pre><code>\nfunc main() {\n\tx := 1 &lt; 2\n}\n</code></pre>",
+			want: "Run `go test`:\n\n```\nfunc main() {\n\tx := 1 < 2\n}\n```",
+		},
+		{
+			name: "nested and ordered lists",
+			html: "<ol><li>one<ul><li>inner</li></ul></li><li>two</li></ol>",
+			want: "1. one\n  - inner\n2. two",
+		},
+		{
+			name: "blockquotes",
+			html: "<blockquote><p>quoted</p><p>more</p></blockquote>",
+			want: "> quoted\n>\n> more",
+		},
+		{
+			name: "tables",
+			html: "<table><tr><th>a</th><th>b</th></tr><tr><td>1</td><td>2</td></tr></table>",
+			want: "| a | b |\n| --- | --- |\n| 1 | 2 |",
+		},
+		{
+			name: "skipped elements",
+			html: `<nav><ul><li>menu</li></ul></nav><svg/><p>kept</p><aside>ad<aside>nested</aside>more ad</aside>` +
+				`<form><input name="q"><button>Go</button></form><!-- comment --><script>if (a < b) {}</script>`,
+			want: "kept",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := htmlToMarkdown(tt.html, base)
+			if got != tt.want {
+				t.Fatalf("unexpected markdown:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
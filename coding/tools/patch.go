@@ -0,0 +1,403 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchAdd    = "add"
+	patchUpdate = "update"
+	patchDelete = "delete"
+)
+
+type patchFile struct {
+	Action  string
+	Path    string
+	MoveTo  string
+	Content string
+	Hunks   []patchHunk
+}
+
+type patchHunk struct {
+	// Anchor is the text after "@@" in the Codex format; the hunk is searched
+	// for after the first line matching it.
+	Anchor string
+	// OldStart is the 1-based start line from a unified diff header, 0 if
+	// unknown.
+	OldStart int
+	Lines    []patchLine
+	// AtEOF anchors the hunk to the end of the file.
+	AtEOF bool
+}
+
+type patchLine struct {
+	Kind byte
+	Text string
+}
+
+func (h patchHunk) oldLines() []string {
+	out := []string{}
+	for _, line := range h.Lines {
+		if line.Kind != '+' {
+			out = append(out, line.Text)
+		}
+	}
+	return out
+}
+
+func (h patchHunk) newLines() []string {
+	out := []string{}
+	for _, line := range h.Lines {
+		if line.Kind != '-' {
+			out = append(out, line.Text)
+		}
+	}
+	return out
+}
+
+func parsePatch(text string) ([]patchFile, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("patch is empty")
+	}
+	var files []patchFile
+	var err error
+	if strings.TrimSpace(lines[0]) == "*** Begin Patch" {
+		files, err = parseCodexPatch(lines)
+	} else {
+		files, err = parseUnifiedPatch(lines)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("patch contains no file changes")
+	}
+	return files, nil
+}
+
+func parseCodexPatch(lines []string) ([]patchFile, error) {
+	files := []patchFile{}
+	i := 1
+	ended := false
+	for i < len(lines) && !ended {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "*** End Patch":
+			ended = true
+			i++
+		case strings.HasPrefix(line, "*** Add File: "):
+			file := patchFile{Action: patchAdd, Path: strings.TrimSpace(strings.TrimPrefix(line, "*** Add File: "))}
+			i++
+			content := []string{}
+			for i < len(lines) && !strings.HasPrefix(lines[i], "*** ") {
+				if !strings.HasPrefix(lines[i], "+") {
+					return nil, fmt.Errorf("line %d: added file lines must start with '+'", i+1)
+				}
+				content = append(content, lines[i][1:])
+				i++
+			}
+			file.Content = strings.Join(content, "\n")
+			if len(content) > 0 {
+				file.Content += "\n"
+			}
+			files = append(files, file)
+		case strings.HasPrefix(line, "*** Delete File: "):
+			files = append(files, patchFile{Action: patchDelete, Path: strings.TrimSpace(strings.TrimPrefix(line, "*** Delete File: "))})
+			i++
+		case strings.HasPrefix(line, "*** Update File: "):
+			file := patchFile{Action: patchUpdate, Path: strings.TrimSpace(strings.TrimPrefix(line, "*** Update File: "))}
+			i++
+			if i < len(lines) && strings.HasPrefix(lines[i], "*** Move to: ") {
+				file.MoveTo = strings.TrimSpace(strings.TrimPrefix(lines[i], "*** Move to: "))
+				i++
+			}
+			var hunk *patchHunk
+			for i < len(lines) {
+				current := lines[i]
+				if strings.HasPrefix(current, "*** End of File") {
+					if hunk == nil {
+						return nil, fmt.Errorf("line %d: end of file marker outside a hunk", i+1)
+					}
+					hunk.AtEOF = true
+					i++
+					continue
+				}
+				if strings.HasPrefix(current, "*** ") {
+					break
+				}
+				if strings.HasPrefix(current, "@@") {
+					file.Hunks = append(file.Hunks, patchHunk{Anchor: strings.TrimSpace(strings.TrimPrefix(current, "@@"))})
+					hunk = &file.Hunks[len(file.Hunks)-1]
+					i++
+					continue
+				}
+				if hunk == nil {
+					file.Hunks = append(file.Hunks, patchHunk{})
+					hunk = &file.Hunks[len(file.Hunks)-1]
+				}
+				kind, body, err := parseHunkLine(current, i)
+				if err != nil {
+					return nil, err
+				}
+				hunk.Lines = append(hunk.Lines, patchLine{Kind: kind, Text: body})
+				i++
+			}
+			if len(file.Hunks) == 0 && file.MoveTo == "" {
+				return nil, fmt.Errorf("update of %s has no hunks", file.Path)
+			}
+			files = append(files, file)
+		case strings.TrimSpace(line) == "":
+			i++
+		default:
+			return nil, fmt.Errorf("line %d: unexpected line %q", i+1, line)
+		}
+	}
+	if !ended {
+		return nil, fmt.Errorf("patch is missing *** End Patch")
+	}
+	return files, nil
+}
+
+func parseHunkLine(line string, index int) (byte, string, error) {
+	if line == "" {
+		// Editors and models often drop the space of empty context lines.
+		return ' ', "", nil
+	}
+	switch line[0] {
+	case ' ', '-', '+':
+		return line[0], line[1:], nil
+	}
+	return 0, "", fmt.Errorf("line %d: hunk lines must start with ' ', '-' or '+': %q", index+1, line)
+}
+
+var unifiedHunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+func parseUnifiedPatch(lines []string) ([]patchFile, error) {
+	files := []patchFile{}
+	var file *patchFile
+	renameFrom := ""
+	renameTo := ""
+	flush := func() {
+		if file != nil {
+			files = append(files, *file)
+			file = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			renameFrom, renameTo = "", ""
+		case strings.HasPrefix(line, "rename from "):
+			renameFrom = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			renameTo = strings.TrimPrefix(line, "rename to ")
+			if renameFrom != "" && (i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "--- ")) {
+				// A pure rename has no ---/+++ headers or hunks.
+				files = append(files, patchFile{Action: patchUpdate, Path: renameFrom, MoveTo: renameTo})
+				renameFrom, renameTo = "", ""
+			}
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			flush()
+			oldPath := unifiedPath(strings.TrimPrefix(line, "--- "))
+			newPath := unifiedPath(strings.TrimPrefix(lines[i+1], "+++ "))
+			i++
+			switch {
+			case oldPath == "" && newPath == "":
+				return nil, fmt.Errorf("line %d: both paths are /dev/null", i)
+			case oldPath == "":
+				file = &patchFile{Action: patchAdd, Path: newPath}
+			case newPath == "":
+				file = &patchFile{Action: patchDelete, Path: oldPath}
+			default:
+				file = &patchFile{Action: patchUpdate, Path: oldPath}
+				if newPath != oldPath {
+					file.MoveTo = newPath
+				}
+			}
+			if renameFrom != "" && renameTo != "" {
+				file.Path, file.MoveTo = renameFrom, renameTo
+			}
+		case strings.HasPrefix(line, "@@"):
+			if file == nil {
+				return nil, fmt.Errorf("line %d: hunk without file header", i+1)
+			}
+			match := unifiedHunkHeader.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: invalid hunk header %q", i+1, line)
+			}
+			oldStart, _ := strconv.Atoi(match[1])
+			oldCount, newCount := 1, 1
+			if match[2] != "" {
+				oldCount, _ = strconv.Atoi(match[2])
+			}
+			if match[4] != "" {
+				newCount, _ = strconv.Atoi(match[4])
+			}
+			hunk := patchHunk{OldStart: oldStart}
+			if oldCount == 0 {
+				// An empty old range is numbered by the line before it.
+				hunk.OldStart = oldStart + 1
+			}
+			seenOld, seenNew := 0, 0
+			for i+1 < len(lines) && (seenOld < oldCount || seenNew < newCount) {
+				next := lines[i+1]
+				if strings.HasPrefix(next, `\ `) {
+					i++
+					continue
+				}
+				kind, body, err := parseHunkLine(next, i+1)
+				if err != nil {
+					return nil, err
+				}
+				hunk.Lines = append(hunk.Lines, patchLine{Kind: kind, Text: body})
+				if kind != '+' {
+					seenOld++
+				}
+				if kind != '-' {
+					seenNew++
+				}
+				i++
+			}
+			if seenOld != oldCount || seenNew != newCount {
+				return nil, fmt.Errorf("line %d: hunk is shorter than its header %q", i+1, line)
+			}
+			file.Hunks = append(file.Hunks, hunk)
+		}
+	}
+	flush()
+
+	for i := range files {
+		if files[i].Action != patchAdd {
+			continue
+		}
+		content := []string{}
+		for _, hunk := range files[i].Hunks {
+			content = append(content, hunk.newLines()...)
+		}
+		files[i].Content = strings.Join(content, "\n")
+		if len(content) > 0 {
+			files[i].Content += "\n"
+		}
+		files[i].Hunks = nil
+	}
+	return files, nil
+}
+
+func unifiedPath(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if tab := strings.IndexByte(raw, '\t'); tab >= 0 {
+		raw = raw[:tab]
+	}
+	if raw == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(raw, "a/") || strings.HasPrefix(raw, "b/") {
+		return raw[2:]
+	}
+	return raw
+}
+
+// applyHunks applies hunks in order to content. Each hunk must match after the
+// previous one; matching tolerates trailing and then surrounding whitespace.
+func applyHunks(content string, hunks []patchHunk) (string, error) {
+	hadTrailingNewline := strings.HasSuffix(content, "\n")
+	lines := splitDiffLines(content)
+	cursor := 0
+	// delta is how far earlier hunks moved the lines; OldStart counts lines
+	// of the original file.
+	delta := 0
+	for n, hunk := range hunks {
+		if hunk.Anchor != "" {
+			idx := seekLines(lines, []string{hunk.Anchor}, cursor, false)
+			if idx < 0 {
+				return "", fmt.Errorf("hunk %d: could not find context %q", n+1, hunk.Anchor)
+			}
+			cursor = idx + 1
+		}
+		old := hunk.oldLines()
+		replacement := hunk.newLines()
+
+		var idx int
+		start := hunk.OldStart - 1 + delta
+		switch {
+		case len(old) == 0 && hunk.OldStart > 0:
+			idx = minInt(maxInt(start, cursor), len(lines))
+		case len(old) == 0:
+			idx = len(lines)
+		default:
+			if hunk.OldStart > 0 && start >= cursor && matchesAt(lines, old, start, exactLine) {
+				idx = start
+			} else {
+				idx = seekLines(lines, old, cursor, hunk.AtEOF)
+			}
+			if idx < 0 {
+				return "", fmt.Errorf("hunk %d: could not find lines to replace:\n%s", n+1, strings.Join(old, "\n"))
+			}
+		}
+
+		next := make([]string, 0, len(lines)-len(old)+len(replacement))
+		next = append(next, lines[:idx]...)
+		next = append(next, replacement...)
+		next = append(next, lines[idx+len(old):]...)
+		lines = next
+		cursor = idx + len(replacement)
+		delta += len(replacement) - len(old)
+	}
+	out := strings.Join(lines, "\n")
+	if len(lines) > 0 && (hadTrailingNewline || content == "") {
+		out += "\n"
+	}
+	return out, nil
+}
+
+var lineComparers = []func(a, b string) bool{
+	exactLine,
+	func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") },
+	func(a, b string) bool { return normalizeLine(a) == normalizeLine(b) },
+}
+
+func exactLine(a, b string) bool {
+	return a == b
+}
+
+// seekLines finds needle in lines at or after start, trying stricter
+// comparisons first. With atEOF the match must end at the last line.
+func seekLines(lines, needle []string, start int, atEOF bool) int {
+	for _, equal := range lineComparers {
+		if atEOF {
+			idx := len(lines) - len(needle)
+			if idx >= start && matchesAt(lines, needle, idx, equal) {
+				return idx
+			}
+			continue
+		}
+		for idx := start; idx+len(needle) <= len(lines); idx++ {
+			if matchesAt(lines, needle, idx, equal) {
+				return idx
+			}
+		}
+	}
+	return -1
+}
+
+func matchesAt(lines, needle []string, idx int, equal func(a, b string) bool) bool {
+	if idx < 0 || idx+len(needle) > len(lines) {
+		return false
+	}
+	for i, line := range needle {
+		if !equal(lines[idx+i], line) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const maxSymlinkDepth = 40
+
+type pathAccess int
+
+const (
+	accessRead pathAccess = iota
+	accessWrite
+)
+
+// pathGuard confines the file tools to the working directory. Read access is
+// also allowed inside readOnlyRoots. Symlinks are resolved before checking,
+// so a link inside the working directory cannot point the tools elsewhere.
+type pathGuard struct {
+	cwd           string
+	readOnlyRoots []string
+}
+
+func newPathGuard(cwd string, readOnlyRoots []string) pathGuard {
+	guard := pathGuard{cwd: absPath(defaultCWD(cwd))}
+	for _, root := range readOnlyRoots {
+		if strings.TrimSpace(root) == "" {
+			continue
+		}
+		guard.readOnlyRoots = append(guard.readOnlyRoots, absPath(root))
+	}
+	return guard
+}
+
+// resolve returns the absolute, lexically cleaned path for input. The
+// returned path is not symlink resolved so tool output stays in the caller's
+// terms.
+func (g pathGuard) resolve(input string, access pathAccess) (string, error) {
+	target := input
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(g.cwd, target)
+	}
+	target = filepath.Clean(target)
+
+	resolved, err := realPath(target, 0)
+	if err != nil {
+		return "", err
+	}
+	if within(g.cwd, resolved) {
+		return target, nil
+	}
+	if access == accessRead {
+		for _, root := range g.readOnlyRoots {
+			if within(root, resolved) {
+				return target, nil
+			}
+		}
+	} else {
+		for _, root := range g.readOnlyRoots {
+			if within(root, resolved) {
+				return "", fmt.Errorf("path is read-only: %s", input)
+			}
+		}
+	}
+	return "", fmt.Errorf("path escapes working directory: %s", input)
+}
+
+// rootOf returns the root a resolved path belongs to, preferring the working
+// directory, so directory walks can apply that root's ignore files.
+func (g pathGuard) rootOf(target string) string {
+	if lexicallyWithin(g.cwd, target) {
+		return g.cwd
+	}
+	for _, root := range g.readOnlyRoots {
+		if lexicallyWithin(root, target) {
+			return root
+		}
+	}
+	return g.cwd
+}
+
+// displayPath renders a path found under root: relative for the working
+// directory, absolute for read-only roots.
+func (g pathGuard) displayPath(root, rel string) string {
+	if root == g.cwd {
+		return rel
+	}
+	return filepath.ToSlash(filepath.Join(root, filepath.FromSlash(rel)))
+}
+
+func within(root, resolved string) bool {
+	realRoot, err := realPath(root, 0)
+	if err != nil {
+		return false
+	}
+	return lexicallyWithin(realRoot, resolved)
+}
+
+func lexicallyWithin(root, target string) bool {
+	sep := string(os.PathSeparator)
+	return target == root || strings.HasPrefix(target, strings.TrimSuffix(root, sep)+sep)
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// realPath resolves symlinks in the longest existing prefix of path and
+// appends the missing remainder. Dangling links are followed by hand so that
+// creating a file through one is checked against its destination.
+func realPath(path string, depth int) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return resolved, err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	realParent, err := realPath(parent, depth)
+	if err != nil {
+		return "", err
+	}
+	joined := filepath.Join(realParent, filepath.Base(path))
+	info, err := os.Lstat(joined)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return joined, nil
+	}
+	if depth >= maxSymlinkDepth {
+		return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+	}
+	link, err := os.Readlink(joined)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(realParent, link)
+	}
+	return realPath(link, depth+1)
+}
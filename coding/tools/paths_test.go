@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathGuardRejectsSymlinkEscapes(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	writeTree(t, dir, map[string]string{"inside.txt": "inside\n"})
+	writeTree(t, outside, map[string]string{"secret.txt": "secret\n"})
+	if err := os.Symlink(outside, filepath.Join(dir, "out")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing.txt"), filepath.Join(dir, "dangling.txt")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	if err := os.Symlink("inside.txt", filepath.Join(dir, "alias.txt")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+
+	tools := map[string]map[string]any{
+		"read":  {"path": "out/secret.txt"},
+		"write": {"path": "out/new.txt", "content": "x"},
+		"edit":  {"path": "out/secret.txt", "oldText": "secret", "newText": "leaked"},
+		"ls":    {"path": "out"},
+		"grep":  {"pattern": "secret", "path": "out"},
+		"find":  {"pattern": "*", "path": "out"},
+	}
+	for _, tool := range NewCodingTools(dir) {
+		args, ok := tools[tool.Name()]
+		if !ok {
+			continue
+		}
+		if _, err := tool.Execute("t", args); err == nil || !strings.Contains(err.Error(), "path escapes working directory") {
+			t.Fatalf("%s: expected escape error, got %v", tool.Name(), err)
+		}
+	}
+
+	if _, err := NewWriteFileTool(dir).Execute("t", map[string]any{"path": "dangling.txt", "content": "x"}); err == nil {
+		t.Fatal("expected write through dangling symlink to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "missing.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file created outside the working directory: %v", err)
+	}
+
+	result, err := NewReadFileTool(dir).Execute("t", map[string]any{"path": "alias.txt"})
+	if err != nil {
+		t.Fatalf("read through internal symlink failed: %v", err)
+	}
+	if !strings.Contains(result.Text(), "inside") {
+		t.Fatalf("unexpected read output: %q", result.Text())
+	}
+}
+
+func TestReadOnlyRoots(t *testing.T) {
+	dir := t.TempDir()
+	shared := t.TempDir()
+	writeTree(t, dir, map[string]string{"main.go": "package main\n"})
+	writeTree(t, shared, map[string]string{"lib/util.go": "package lib\n\nfunc Helper() {}\n"})
+
+	byName := map[string]int{}
+	tools := NewCodingToolsWithOptions(dir, Options{ReadOnlyRoots: []string{shared}})
+	for i, tool := range tools {
+		byName[tool.Name()] = i
+	}
+	target := filepath.Join(shared, "lib", "util.go")
+
+	result, err := tools[byName["read"]].Execute("t", map[string]any{"path": target})
+	if err != nil || !strings.Contains(result.Text(), "func Helper") {
+		t.Fatalf("read from read-only root failed: %v %q", err, result.Text())
+	}
+	result, err = tools[byName["grep"]].Execute("t", map[string]any{"pattern": "Helper", "path": shared})
+	if err != nil {
+		t.Fatalf("grep in read-only root failed: %v", err)
+	}
+	if want := filepath.ToSlash(target) + ":3: func Helper() {}"; result.Text() != want {
+		t.Fatalf("unexpected grep output: %q", result.Text())
+	}
+
+	_, err = tools[byName["write"]].Execute("t", map[string]any{"path": target, "content": "x"})
+	if err == nil || !strings.Contains(err.Error(), "path is read-only") {
+		t.Fatalf("expected read-only error, got %v", err)
+	}
+	_, err = tools[byName["edit"]].Execute("t", map[string]any{"path": target, "oldText": "Helper", "newText": "Other"})
+	if err == nil || !strings.Contains(err.Error(), "path is read-only") {
+		t.Fatalf("expected read-only error, got %v", err)
+	}
+	if _, err := NewReadFileTool(dir).Execute("t", map[string]any{"path": target}); err == nil {
+		t.Fatal("expected read outside the working directory to fail without read-only roots")
+	}
+}
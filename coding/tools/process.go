@@ -0,0 +1,335 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/model"
+)
+
+// maxProcessBuffer bounds the unread output kept per background process; the
+// oldest bytes are dropped first.
+const maxProcessBuffer = 1 << 20
+
+// maxFinishedProcesses bounds how many exited processes, with their unread
+// output, are kept; the oldest are forgotten when a new process starts.
+const maxFinishedProcesses = 16
+
+// processTable tracks the background processes started by one bash tool.
+type processTable struct {
+	mu     sync.Mutex
+	nextID int
+	procs  map[string]*backgroundProcess
+	closed bool
+}
+
+type backgroundProcess struct {
+	id        string
+	command   string
+	cwd       string
+	pid       int
+	startedAt time.Time
+	kill      func()
+	done      chan struct{}
+
+	mu       sync.Mutex
+	unread   []byte
+	dropped  int
+	exitCode int
+}
+
+func newProcessTable() *processTable {
+	return &processTable{procs: map[string]*backgroundProcess{}}
+}
+
+func (t *processTable) start(executor Executor, command, dir string) (*backgroundProcess, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, fmt.Errorf("process table is closed")
+	}
+	cmd, err := executor.Command(context.Background(), command, dir)
+	if err != nil {
+		return nil, err
+	}
+	setProcessGroup(cmd)
+	cmd.WaitDelay = bashWaitDelay
+	t.evictFinished()
+	t.nextID++
+	proc := &backgroundProcess{
+		id:        fmt.Sprintf("p%d", t.nextID),
+		command:   command,
+		cwd:       dir,
+		startedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	cmd.Stdout = proc
+	cmd.Stderr = proc
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	proc.pid = cmd.Process.Pid
+	proc.kill = func() { signalProcessGroup(cmd, false) }
+	go func() {
+		err := cmd.Wait()
+		proc.mu.Lock()
+		proc.exitCode = exitCodeOf(err)
+		if err != nil && proc.exitCode == 0 {
+			proc.exitCode = -1
+		}
+		proc.mu.Unlock()
+		close(proc.done)
+	}()
+	t.procs[proc.id] = proc
+	return proc, nil
+}
+
+// evictFinished drops the oldest exited processes beyond
+// maxFinishedProcesses. The caller holds t.mu.
+func (t *processTable) evictFinished() {
+	finished := []*backgroundProcess{}
+	for _, proc := range t.procs {
+		if proc.exited() {
+			finished = append(finished, proc)
+		}
+	}
+	if len(finished) <= maxFinishedProcesses {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].startedAt.Before(finished[j].startedAt) })
+	for _, proc := range finished[:len(finished)-maxFinishedProcesses] {
+		delete(t.procs, proc.id)
+	}
+}
+
+func (t *processTable) get(id string) (*backgroundProcess, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	proc, ok := t.procs[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown process: %s", id)
+	}
+	return proc, nil
+}
+
+// Close kills every process that is still running.
+func (t *processTable) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	procs := make([]*backgroundProcess, 0, len(t.procs))
+	for _, proc := range t.procs {
+		procs = append(procs, proc)
+	}
+	t.mu.Unlock()
+	for _, proc := range procs {
+		proc.stop()
+	}
+	return nil
+}
+
+func (p *backgroundProcess) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unread = append(p.unread, data...)
+	if over := len(p.unread) - maxProcessBuffer; over > 0 {
+		p.unread = append([]byte(nil), p.unread[over:]...)
+		p.dropped += over
+	}
+	return len(data), nil
+}
+
+// poll returns the output written since the last poll.
+func (p *backgroundProcess) poll() (output string, dropped int, running bool, exitCode int) {
+	select {
+	case <-p.done:
+	default:
+		running = true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	output, dropped = string(p.unread), p.dropped
+	p.unread, p.dropped = nil, 0
+	return output, dropped, running, p.exitCode
+}
+
+func (p *backgroundProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *backgroundProcess) stop() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+	p.kill()
+	<-p.done
+	return true
+}
+
+func (p *backgroundProcess) status() string {
+	select {
+	case <-p.done:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return fmt.Sprintf("exited with code %d", p.exitCode)
+	default:
+		return "running"
+	}
+}
+
+// NewProcessTools returns the process_output and process_kill tools for the
+// background processes started by bash, which must come from this package.
+func NewProcessTools(bash agent.Tool) []agent.Tool {
+	tool, ok := bash.(*bashTool)
+	if !ok {
+		return nil
+	}
+	return []agent.Tool{
+		&processOutputTool{processes: tool.processes},
+		&processKillTool{processes: tool.processes},
+	}
+}
+
+type processOutputTool struct {
+	processes *processTable
+}
+
+func (t *processOutputTool) Name() string {
+	return "process_output"
+}
+
+func (t *processOutputTool) Description() string {
+	return fmt.Sprintf(
+		"Read the output a background process wrote since the last call. Omit id to list processes. "+
+			"Output is truncated to the last %d lines or %s.",
+		defaultMaxLines, formatSize(defaultMaxBytes),
+	)
+}
+
+func (t *processOutputTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Process id returned by bash with background=true",
+			},
+		},
+	}
+}
+
+func (t *processOutputTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	id, _ := toStringArg(args, "id")
+	if strings.TrimSpace(id) == "" {
+		return t.list(), nil
+	}
+	proc, err := t.processes.get(id)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	output, dropped, running, exitCode := proc.poll()
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	trunc := truncateTail(output, defaultMaxLines, defaultMaxBytes)
+	text := trunc.Content
+	if strings.TrimSpace(text) == "" {
+		text = "(no new output)"
+	}
+	if trunc.Truncated || dropped > 0 {
+		text = fmt.Sprintf("[Earlier output omitted; showing the last %d lines]\n", trunc.OutputLines) + text
+	}
+	text += fmt.Sprintf("\n\n[Process %s %s]", proc.id, proc.status())
+
+	details := map[string]any{"id": proc.id, "running": running}
+	if !running {
+		details["exitCode"] = exitCode
+	}
+	if trunc.Truncated {
+		details["truncation"] = trunc.toMap()
+	}
+	if dropped > 0 {
+		details["droppedBytes"] = dropped
+	}
+	return agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: text}},
+		Details: details,
+	}, nil
+}
+
+func (t *processOutputTool) list() agent.ToolResult {
+	t.processes.mu.Lock()
+	procs := make([]*backgroundProcess, 0, len(t.processes.procs))
+	for _, proc := range t.processes.procs {
+		procs = append(procs, proc)
+	}
+	t.processes.mu.Unlock()
+	if len(procs) == 0 {
+		return agent.ToolResult{
+			Content: []any{model.TextContent{Type: model.ContentText, Text: "No background processes"}},
+			Details: map[string]any{"count": 0},
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].startedAt.Before(procs[j].startedAt) })
+	lines := make([]string, 0, len(procs))
+	for _, proc := range procs {
+		lines = append(lines, fmt.Sprintf("%s  %s  %s", proc.id, proc.status(), proc.command))
+	}
+	return agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: strings.Join(lines, "\n")}},
+		Details: map[string]any{"count": len(procs)},
+	}
+}
+
+type processKillTool struct {
+	processes *processTable
+}
+
+func (t *processKillTool) Name() string {
+	return "process_kill"
+}
+
+func (t *processKillTool) Description() string {
+	return "Stop a background process started by bash, together with its children."
+}
+
+func (t *processKillTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Process id returned by bash with background=true",
+			},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *processKillTool) Execute(toolCallID string, args map[string]any) (agent.ToolResult, error) {
+	id, ok := toStringArg(args, "id")
+	if !ok || strings.TrimSpace(id) == "" {
+		return agent.ToolResult{}, fmt.Errorf("missing required argument: id")
+	}
+	proc, err := t.processes.get(id)
+	if err != nil {
+		return agent.ToolResult{}, err
+	}
+	text := fmt.Sprintf("Killed process %s", proc.id)
+	if !proc.stop() {
+		text = fmt.Sprintf("Process %s already %s", proc.id, proc.status())
+	}
+	return agent.ToolResult{
+		Content: []any{model.TextContent{Type: model.ContentText, Text: text}},
+		Details: map[string]any{"id": proc.id},
+	}, nil
+}
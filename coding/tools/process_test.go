@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zahlmann/phi/agent"
+)
+
+func processTools(t *testing.T, dir string) (agent.Tool, agent.Tool, agent.Tool) {
+	t.Helper()
+	bash := NewBashTool(dir, 10*time.Second)
+	t.Cleanup(func() { bash.(io.Closer).Close() })
+	companions := NewProcessTools(bash)
+	if len(companions) != 2 {
+		t.Fatalf("expected process tools, got %d", len(companions))
+	}
+	return bash, companions[0], companions[1]
+}
+
+func pollUntil(t *testing.T, output agent.Tool, id string, done func(text string) bool) string {
+	t.Helper()
+	var seen strings.Builder
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		result, err := output.Execute("o", map[string]any{"id": id})
+		if err != nil {
+			t.Fatalf("process_output failed: %v", err)
+		}
+		seen.WriteString(result.Text())
+		if done(seen.String()) {
+			return seen.String()
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("timed out polling process output:\n%s", seen.String())
+	return ""
+}
+
+func TestBackgroundProcessOutputAndKill(t *testing.T) {
+	bash, output, kill := processTools(t, t.TempDir())
+
+	result, err := bash.Execute("b", map[string]any{
+		"command":    "echo ready; while true; do sleep 0.05; done",
+		"background": true,
+	})
+	if err != nil {
+		t.Fatalf("background start failed: %v", err)
+	}
+	id, _ := result.Details["id"].(string)
+	if id != "p1" || !strings.Contains(result.Text(), "Started background process p1") {
+		t.Fatalf("unexpected start result: %q %#v", result.Text(), result.Details)
+	}
+
+	pollUntil(t, output, id, func(text string) bool { return strings.Contains(text, "ready") })
+	result, err = output.Execute("o", map[string]any{"id": id})
+	if err != nil {
+		t.Fatalf("process_output failed: %v", err)
+	}
+	if !strings.HasPrefix(result.Text(), "(no new output)") || result.Details["running"] != true {
+		t.Fatalf("expected no new output from a running process, got %q %#v", result.Text(), result.Details)
+	}
+
+	list, err := output.Execute("o", map[string]any{})
+	if err != nil || !strings.Contains(list.Text(), "p1  running  echo ready") {
+		t.Fatalf("unexpected process list: %v %q", err, list.Text())
+	}
+
+	result, err = kill.Execute("k", map[string]any{"id": id})
+	if err != nil || result.Text() != "Killed process p1" {
+		t.Fatalf("unexpected kill result: %v %q", err, result.Text())
+	}
+	result, err = kill.Execute("k", map[string]any{"id": id})
+	if err != nil || !strings.HasPrefix(result.Text(), "Process p1 already exited") {
+		t.Fatalf("unexpected second kill result: %v %q", err, result.Text())
+	}
+	if _, err := kill.Execute("k", map[string]any{"id": "p9"}); err == nil || !strings.Contains(err.Error(), "unknown process") {
+		t.Fatalf("expected unknown process error, got %v", err)
+	}
+}
+
+func TestBackgroundProcessExitAndTruncation(t *testing.T) {
+	bash, output, _ := processTools(t, t.TempDir())
+
+	result, err := bash.Execute("b", map[string]any{
+		"command":    "for i in $(seq 1 3000); do echo line-$i; done; exit 4",
+		"background": true,
+	})
+	if err != nil {
+		t.Fatalf("background start failed: %v", err)
+	}
+	id := result.Details["id"].(string)
+	// Listing does not consume output, so wait for the exit first and read once.
+	pollUntil(t, output, "", func(text string) bool { return strings.Contains(text, "exited with code 4") })
+	result, err = output.Execute("o", map[string]any{"id": id})
+	if err != nil {
+		t.Fatalf("process_output failed: %v", err)
+	}
+	text := result.Text()
+	if result.Details["exitCode"] != 4 || result.Details["truncation"] == nil {
+		t.Fatalf("unexpected details: %#v", result.Details)
+	}
+	if !strings.Contains(text, "Earlier output omitted") || !strings.Contains(text, "line-3000") || strings.Contains(text, "line-1\n") {
+		t.Fatalf("expected tail-truncated output, got:\n%s", text[:minInt(len(text), 400)])
+	}
+}
+
+func TestCloseKillsBackgroundProcesses(t *testing.T) {
+	bash, output, _ := processTools(t, t.TempDir())
+	result, err := bash.Execute("b", map[string]any{"command": "sleep 30", "background": true})
+	if err != nil {
+		t.Fatalf("background start failed: %v", err)
+	}
+	if err := bash.(io.Closer).Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	status, err := output.Execute("o", map[string]any{"id": result.Details["id"]})
+	if err != nil || status.Details["running"] != false {
+		t.Fatalf("expected process to be stopped, got %v %#v", err, status.Details)
+	}
+	if _, err := bash.Execute("b", map[string]any{"command": "true", "background": true}); err == nil {
+		t.Fatal("expected background start to fail after close")
+	}
+}
+
+func TestFinishedBackgroundProcessesAreEvicted(t *testing.T) {
+	bash, output, _ := processTools(t, t.TempDir())
+	start := func() string {
+		t.Helper()
+		result, err := bash.Execute("b", map[string]any{"command": "echo done", "background": true})
+		if err != nil {
+			t.Fatalf("background start failed: %v", err)
+		}
+		return result.Details["id"].(string)
+	}
+	for i := 0; i < maxFinishedProcesses+2; i++ {
+		id := start()
+		pollUntil(t, output, "", func(text string) bool { return strings.Contains(text, id+"  exited") })
+	}
+	start()
+
+	for _, id := range []string{"p1", "p2"} {
+		if _, err := output.Execute("o", map[string]any{"id": id}); err == nil || !strings.Contains(err.Error(), "unknown process") {
+			t.Fatalf("expected %s to be evicted, got %v", id, err)
+		}
+	}
+	if result, err := output.Execute("o", map[string]any{"id": "p3"}); err != nil || !strings.Contains(result.Text(), "done") {
+		t.Fatalf("expected p3 output to be kept, got %v", err)
+	}
+	list, err := output.Execute("o", map[string]any{})
+	if err != nil || list.Details["count"] != maxFinishedProcesses+1 {
+		t.Fatalf("unexpected process list: %v %#v", err, list.Details)
+	}
+}
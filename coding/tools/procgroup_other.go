@@ -0,0 +1,21 @@
+//go:build !unix
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalProcessGroup(cmd *exec.Cmd, interrupt bool) {
+	if cmd.Process == nil {
+		return
+	}
+	if interrupt {
+		_ = cmd.Process.Signal(os.Interrupt)
+		return
+	}
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package tools
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcessGroup interrupts or kills the shell and everything it started.
+func signalProcessGroup(cmd *exec.Cmd, interrupt bool) {
+	if cmd.Process == nil {
+		return
+	}
+	sig := syscall.SIGKILL
+	if interrupt {
+		sig = syscall.SIGINT
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, sig)
+}
@@ -1,22 +1,30 @@
 package tools
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/zahlmann/phi/agent"
 	"github.com/zahlmann/phi/ai/model"
 )
 
+// readFoldWidth is the line width used when an overlong line is spilled.
+const readFoldWidth = 1024
+
 type readFileTool struct {
-	cwd string
+	paths     pathGuard
+	artifacts *ArtifactStore
+	files     *fileTracker
 }
 
 func NewReadFileTool(cwd string) agent.Tool {
-	return &readFileTool{cwd: defaultCWD(cwd)}
+	return &readFileTool{paths: standalonePaths(cwd), artifacts: defaultArtifacts()}
 }
 
 func (t *readFileTool) Name() string {
@@ -24,7 +32,8 @@ func (t *readFileTool) Name() string {
 }
 
 func (t *readFileTool) Description() string {
-	return "Read a file path relative to the working directory."
+	return "Read a file path relative to the working directory. UTF-16 text is decoded, text is extracted from " +
+		"simple PDFs, binary files return a hex preview and directories return a listing."
 }
 
 func (t *readFileTool) Parameters() map[string]any {
@@ -35,14 +44,21 @@ func (t *readFileTool) Parameters() map[string]any {
 			"offset": map[string]any{
 				"type":        "integer",
 				"description": "Line number to start reading from (1-indexed)",
+				"minimum":     1,
 			},
 			"limit": map[string]any{
 				"type":        "integer",
 				"description": "Maximum number of lines to read",
+				"minimum":     1,
 			},
 			"max_bytes": map[string]any{
 				"type":        "integer",
 				"description": "Optional maximum bytes to return",
+				"minimum":     1,
+			},
+			"line_numbers": map[string]any{
+				"type":        "boolean",
+				"description": "Prefix each line with its line number",
 			},
 		},
 		"required": []string{"path"},
@@ -55,31 +71,37 @@ func (t *readFileTool) Execute(toolCallID string, args map[string]any) (agent.To
 		return agent.ToolResult{}, fmt.Errorf("missing required argument: path")
 	}
 
-	target, err := resolveSafePath(t.cwd, path)
+	target, err := t.paths.resolve(path, accessRead)
 	if err != nil {
 		return agent.ToolResult{}, err
 	}
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		lister := &lsTool{paths: t.paths, artifacts: t.artifacts}
+		return lister.Execute(toolCallID, map[string]any{"path": path})
+	}
 
 	if mimeType := detectImageMimeType(target); mimeType != "" {
 		data, err := os.ReadFile(target)
 		if err != nil {
 			return agent.ToolResult{}, err
 		}
+		t.files.record(target, data)
 		return agent.ToolResult{
-			Content: []model.TextContent{
-				{
+			Content: []any{
+				model.TextContent{
 					Type: model.ContentText,
 					Text: fmt.Sprintf("Read image file [%s]", mimeType),
 				},
+				model.ImageContent{
+					Type:     model.ContentImage,
+					MIMEType: mimeType,
+					Data:     base64.StdEncoding.EncodeToString(data),
+				},
 			},
 			Details: map[string]any{
 				"path":     path,
 				"mimeType": mimeType,
-				"image": map[string]any{
-					"type":     string(model.ContentImage),
-					"mimeType": mimeType,
-					"data":     base64.StdEncoding.EncodeToString(data),
-				},
+				"size":     len(data),
 			},
 		}, nil
 	}
@@ -88,6 +110,25 @@ func (t *readFileTool) Execute(toolCallID string, args map[string]any) (agent.To
 	if err != nil {
 		return agent.ToolResult{}, err
 	}
+	t.files.record(target, data)
+	details := map[string]any{"path": path}
+	var text string
+	isText := true
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		// PDFs without recoverable text fall back to the binary preview.
+		text = extractPDFText(data)
+		isText = text != ""
+		details["format"] = "pdf"
+	} else {
+		var encoding string
+		text, encoding, isText = decodeText(data)
+		if isText && encoding != encodingUTF8 {
+			details["encoding"] = encoding
+		}
+	}
+	if !isText {
+		return binaryFileResult(path, data), nil
+	}
 
 	maxBytes := defaultMaxBytes
 	if raw, ok := args["max_bytes"]; ok {
@@ -108,7 +149,7 @@ func (t *readFileTool) Execute(toolCallID string, args map[string]any) (agent.To
 		}
 	}
 
-	textContent := strings.ReplaceAll(string(data), "\r\n", "\n")
+	textContent := strings.ReplaceAll(text, "\r\n", "\n")
 	textContent = strings.ReplaceAll(textContent, "\r", "\n")
 	allLines := strings.Split(textContent, "\n")
 	totalFileLines := len(allLines)
@@ -125,24 +166,28 @@ func (t *readFileTool) Execute(toolCallID string, args map[string]any) (agent.To
 		selected = allLines[startIdx:endIdx]
 		userLimitedLines = endIdx - startIdx
 	}
+	if lineNumbers, _ := args["line_numbers"].(bool); lineNumbers {
+		selected = numberLines(selected, startLine)
+	}
 	selectedContent := strings.Join(selected, "\n")
 
 	trunc := truncateHead(selectedContent, defaultMaxLines, maxBytes)
 	outputText := trunc.Content
-	details := map[string]any{"path": path}
 
 	switch {
 	case trunc.FirstLineExceedsLimit:
+		// A single line cannot be paged with offset, so spill it in pieces.
+		artifact, notice := t.artifacts.spill("read", foldLine(allLines[startIdx], readFoldWidth))
 		outputText = fmt.Sprintf(
-			"[Line %d is %s, exceeds %s limit. Use bash: sed -n '%dp' %s | head -c %d]",
+			"[Line %d is %s, exceeds %s limit. Split into %s lines. %s]",
 			startLine,
 			formatSize(byteLen(allLines[startIdx])),
 			formatSize(maxBytes),
-			startLine,
-			path,
-			maxBytes,
+			formatSize(readFoldWidth),
+			notice,
 		)
 		details["truncation"] = trunc.toMap()
+		details["artifact"] = artifact
 	case trunc.Truncated:
 		endLine := startLine + trunc.OutputLines - 1
 		nextOffset := endLine + 1
@@ -169,8 +214,8 @@ func (t *readFileTool) Execute(toolCallID string, args map[string]any) (agent.To
 	}
 
 	return agent.ToolResult{
-		Content: []model.TextContent{
-			{
+		Content: []any{
+			model.TextContent{
 				Type: model.ContentText,
 				Text: outputText,
 			},
@@ -179,6 +224,54 @@ func (t *readFileTool) Execute(toolCallID string, args map[string]any) (agent.To
 	}, nil
 }
 
+func numberLines(lines []string, first int) []string {
+	width := len(strconv.Itoa(first + len(lines) - 1))
+	numbered := make([]string, len(lines))
+	for i, line := range lines {
+		numbered[i] = fmt.Sprintf("%*d\t%s", width, first+i, line)
+	}
+	return numbered
+}
+
+func binaryFileResult(path string, data []byte) agent.ToolResult {
+	return agent.ToolResult{
+		Content: []any{
+			model.TextContent{
+				Type: model.ContentText,
+				Text: fmt.Sprintf(
+					"[Binary file, %s. First %d bytes:]\n%s",
+					formatSize(len(data)), minInt(len(data), hexPreviewBytes), hexPreview(data),
+				),
+			},
+		},
+		Details: map[string]any{
+			"path":   path,
+			"binary": true,
+			"size":   len(data),
+		},
+	}
+}
+
+// foldLine splits line into pieces of at most width bytes without breaking
+// UTF-8 sequences.
+func foldLine(line string, width int) string {
+	var out strings.Builder
+	for len(line) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = width
+		}
+		out.WriteString(line[:cut])
+		out.WriteByte('\n')
+		line = line[cut:]
+	}
+	out.WriteString(line)
+	return out.String()
+}
+
 func detectImageMimeType(path string) string {
 	switch strings.ToLower(filepath.Ext(path)) {
 	case ".jpg", ".jpeg":
@@ -0,0 +1,88 @@
+//go:build linux
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// sandboxSetup runs as pid 1 of the new namespaces. It makes every mount
+// read-only except the working directory ($1) and exits 125 if one cannot
+// be remounted. It gives the sandbox a private /tmp and /proc, applies the
+// limits and then runs the command ($2).
+const sandboxSetup = `set -e
+cwd=$1
+mount --make-rprivate /
+mount -t tmpfs -o mode=1777 tmpfs /tmp
+# "." still names the host directory when the new /tmp hides its path.
+mkdir -p "$cwd" 2>/dev/null || true
+mount --no-canonicalize --bind . "$cwd"
+cd "$cwd"
+while read -r _ mnt _ opts _; do
+  # /proc/self/mounts escapes spaces and other special bytes as \ooo; %b
+  # reads \0ooo as exactly three octal digits.
+  mnt=$(printf '%b' "${mnt//\\/\\0}")
+  case "$mnt" in
+    "$cwd"|"$cwd"/*|/tmp|/tmp/*|/proc|/proc/*|/dev|/dev/*|/sys|/sys/*) continue ;;
+  esac
+  # Flags locked by the outer namespace must be kept or the remount fails.
+  flags=remount,bind,ro
+  for opt in nosuid nodev noexec noatime nodiratime relatime; do
+    case ",$opts," in *",$opt,"*) flags=$flags,$opt ;; esac
+  done
+  if ! mount -o "$flags" "$mnt" 2>/dev/null; then
+    echo "sandbox: cannot make $mnt read-only" >&2
+    exit 125
+  fi
+done < /proc/self/mounts
+mount -t proc proc /proc
+set +e
+`
+
+type sandboxExecutor struct {
+	options SandboxOptions
+}
+
+// NewSandboxExecutor returns an Executor that runs each command in fresh
+// user, mount, pid and (unless AllowNetwork is set) network namespaces. The
+// filesystem is read-only apart from the working directory and a private
+// /tmp. It needs unprivileged user namespaces and util-linux mount.
+func NewSandboxExecutor(options SandboxOptions) (Executor, error) {
+	if _, err := exec.LookPath("mount"); err != nil {
+		return nil, fmt.Errorf("sandbox executor needs mount: %w", err)
+	}
+	return &sandboxExecutor{options: options}, nil
+}
+
+func (e *sandboxExecutor) Command(ctx context.Context, script, dir string) (*exec.Cmd, error) {
+	setup := sandboxSetup
+	if seconds := e.options.CPUTime.Seconds(); seconds > 0 {
+		setup += fmt.Sprintf("ulimit -t %d || exit 125\n", int64(seconds+0.999))
+	}
+	if e.options.MemoryBytes > 0 {
+		setup += fmt.Sprintf("ulimit -v %d || exit 125\n", (e.options.MemoryBytes+1023)/1024)
+	}
+	run := `exec bash -lc "$2"`
+	if e.options.Timeout > 0 {
+		run = `exec timeout --signal=KILL ` + strconv.FormatFloat(e.options.Timeout.Seconds(), 'f', 3, 64) + ` bash -lc "$2"`
+	}
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", setup+run, "phi-sandbox", dir, script)
+	cmd.Dir = dir
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID)
+	if !e.options.AllowNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:                 flags,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+	return cmd, nil
+}
@@ -0,0 +1,124 @@
+//go:build linux
+
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSandbox(t *testing.T, options SandboxOptions) Executor {
+	t.Helper()
+	executor, err := NewSandboxExecutor(options)
+	if err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	cmd, err := executor.Command(context.Background(), "true", t.TempDir())
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("sandbox unavailable: %v %s", err, output)
+	}
+	return executor
+}
+
+func TestSandboxExecutorConfinesWrites(t *testing.T) {
+	executor := newTestSandbox(t, SandboxOptions{})
+	dir := t.TempDir()
+	outside := t.TempDir()
+	tool := NewBashToolWithExecutor(dir, 0, executor)
+
+	script := "echo inside > inside.txt && cat inside.txt; " +
+		"echo leaked > " + filepath.Join(outside, "leaked.txt") + "; " +
+		"echo home > $HOME/.phi-sandbox-test; " +
+		"touch /tmp/scratch && echo tmp-ok; " +
+		"echo net=$(grep -c : /proc/net/dev); " +
+		"echo pids=$(ls /proc | grep -c '^[0-9]')"
+	result, _ := tool.Execute("t", map[string]any{"command": script})
+	output := result.Text()
+	if !strings.Contains(output, "inside\n") {
+		t.Fatalf("write in working directory failed:\n%s", output)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "inside.txt")); err != nil || string(data) != "inside\n" {
+		t.Fatalf("working directory write not visible on host: %v %q", err, data)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "leaked.txt")); !os.IsNotExist(err) {
+		t.Fatalf("sandbox wrote outside the working directory: %v", err)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if _, err := os.Stat(filepath.Join(home, ".phi-sandbox-test")); !os.IsNotExist(err) {
+			os.Remove(filepath.Join(home, ".phi-sandbox-test"))
+			t.Fatal("sandbox wrote to the home directory")
+		}
+	}
+	if !strings.Contains(output, "Read-only file system") || !strings.Contains(output, "tmp-ok") {
+		t.Fatalf("unexpected sandbox output:\n%s", output)
+	}
+	// /proc/net/dev lists only the loopback interface in a fresh namespace.
+	if !strings.Contains(output, "net=1\n") {
+		t.Fatalf("expected only loopback networking:\n%s", output)
+	}
+	if !strings.Contains(output, "pids=3") && !strings.Contains(output, "pids=4") {
+		t.Fatalf("expected a private pid namespace:\n%s", output)
+	}
+}
+
+func TestSandboxExecutorLimits(t *testing.T) {
+	executor := newTestSandbox(t, SandboxOptions{
+		AllowNetwork: true,
+		CPUTime:      5 * time.Second,
+		MemoryBytes:  512 << 20,
+		Timeout:      3 * time.Second,
+	})
+	tool := NewBashToolWithExecutor(t.TempDir(), 0, executor)
+
+	result, err := tool.Execute("t", map[string]any{"command": "echo cpu=$(ulimit -t) mem=$(ulimit -v)"})
+	if err != nil {
+		t.Fatalf("limits command failed: %v", err)
+	}
+	if !strings.Contains(result.Text(), "cpu=5 mem=524288") {
+		t.Fatalf("unexpected limits: %q", result.Text())
+	}
+
+	start := time.Now()
+	if _, err := tool.Execute("t", map[string]any{"command": "sleep 30"}); err == nil {
+		t.Fatal("expected the sandbox timeout to kill the command")
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Fatalf("sandbox timeout not enforced, took %s", elapsed)
+	}
+}
+
+func TestSandboxExecutorRemountsBindMountsReadOnly(t *testing.T) {
+	executor := newTestSandbox(t, SandboxOptions{})
+	if os.Geteuid() != 0 {
+		t.Skip("creating a bind mount needs root")
+	}
+	// The space is escaped as \040 in /proc/self/mounts. The mount must be
+	// outside /tmp, which the sandbox replaces.
+	target, err := os.MkdirTemp(".", "sandbox mount ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ = filepath.Abs(target)
+	t.Cleanup(func() { os.RemoveAll(target) })
+	if output, err := exec.Command("mount", "--bind", t.TempDir(), target).CombinedOutput(); err != nil {
+		t.Skipf("cannot create bind mount: %v %s", err, output)
+	}
+	t.Cleanup(func() { exec.Command("umount", target).Run() })
+
+	tool := NewBashToolWithExecutor(t.TempDir(), 0, executor)
+	result, _ := tool.Execute("t", map[string]any{"command": "touch '" + target + "/leaked'"})
+	if !strings.Contains(result.Text(), "Read-only file system") {
+		t.Fatalf("expected the bind mount to be read-only:\n%s", result.Text())
+	}
+	if _, err := os.Stat(filepath.Join(target, "leaked")); !os.IsNotExist(err) {
+		t.Fatalf("sandbox wrote to a bind mount: %v", err)
+	}
+}
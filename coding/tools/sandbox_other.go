@@ -0,0 +1,9 @@
+//go:build !linux
+
+package tools
+
+import "errors"
+
+func NewSandboxExecutor(options SandboxOptions) (Executor, error) {
+	return nil, errors.New("sandbox executor is only supported on linux")
+}
@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+}
+
+func TestGrepTool(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":       "build/\n*.log\n!keep.log\n",
+		"main.go":          "package main\n\nfunc main() {\n\t// TODO: start\n}\n",
+		"pkg/util.go":      "package pkg\n// todo lower\nfunc Util() {}\n",
+		"pkg/util_test.go": "package pkg\n// TODO: test\n",
+		"build/out.go":     "// TODO: generated\n",
+		"debug.log":        "TODO in log\n",
+		"keep.log":         "TODO kept\n",
+		"bin.dat":          "TODO\x00binary",
+		"sub/.gitignore":   "ignored.txt\n",
+		"sub/ignored.txt":  "TODO nested ignore\n",
+		"sub/visible.txt":  "TODO visible\n",
+	})
+	tool := NewGrepTool(dir)
+
+	result, err := tool.Execute("g1", map[string]any{"pattern": "TODO"})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"keep.log:1: TODO kept",
+		"main.go:4: \t// TODO: start",
+		"pkg/util_test.go:2: // TODO: test",
+		"sub/visible.txt:1: TODO visible",
+	}, "\n")
+	if got := result.Text(); got != want {
+		t.Fatalf("unexpected grep output:\n%s", got)
+	}
+
+	result, err = tool.Execute("g2", map[string]any{"pattern": "todo", "ignoreCase": true, "glob": "*.go", "path": "pkg"})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if got := result.Text(); got != "pkg/util.go:2: // todo lower\npkg/util_test.go:2: // TODO: test" {
+		t.Fatalf("unexpected filtered output:\n%s", got)
+	}
+
+	result, err = tool.Execute("g3", map[string]any{"pattern": "TODO", "path": "main.go", "context": 1})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if got := result.Text(); got != "main.go-3- func main() {\nmain.go:4: \t// TODO: start\nmain.go-5- }" {
+		t.Fatalf("unexpected context output:\n%s", got)
+	}
+
+	result, err = tool.Execute("g4", map[string]any{"pattern": "TODO", "limit": 1})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if !strings.Contains(result.Text(), "[1 matches limit reached. Use limit=2 for more, or refine pattern]") {
+		t.Fatalf("expected limit notice, got:\n%s", result.Text())
+	}
+
+	result, err = tool.Execute("g5", map[string]any{"pattern": "func (", "literal": true})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if got := result.Text(); got != "No matches found" {
+		t.Fatalf("unexpected literal output: %q", got)
+	}
+
+	if _, err := tool.Execute("g6", map[string]any{"pattern": "x", "path": "../"}); err == nil {
+		t.Fatal("expected path escape error")
+	}
+}
+
+func TestFindTool(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":           "node_modules/\n",
+		"main.go":              "",
+		"cmd/app/main.go":      "",
+		"cmd/app/main_test.go": "",
+		"node_modules/x.go":    "",
+		"README.md":            "",
+	})
+	tool := NewFindTool(dir)
+
+	result, err := tool.Execute("f1", map[string]any{"pattern": "*.go"})
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if got := result.Text(); got != "cmd/app/main.go\ncmd/app/main_test.go\nmain.go" {
+		t.Fatalf("unexpected find output:\n%s", got)
+	}
+
+	result, err = tool.Execute("f2", map[string]any{"pattern": "app/**/*_test.go", "path": "cmd"})
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if got := result.Text(); got != "cmd/app/main_test.go" {
+		t.Fatalf("unexpected scoped find output:\n%s", got)
+	}
+
+	result, err = tool.Execute("f3", map[string]any{"pattern": "*", "limit": 2})
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if !strings.Contains(result.Text(), "2 results limit reached") {
+		t.Fatalf("expected limit notice, got:\n%s", result.Text())
+	}
+}
+
+func TestLsTool(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"b.txt":       "hello",
+		".env":        "X=1",
+		"src/main.go": "",
+	})
+	if err := os.Symlink("b.txt", filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+
+	result, err := NewLsTool(dir).Execute("l1", map[string]any{})
+	if err != nil {
+		t.Fatalf("ls failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"file        3B  .env",
+		"file        5B  b.txt",
+		"link         -  link.txt -> b.txt",
+		"dir          -  src/",
+	}, "\n")
+	if got := result.Text(); got != want {
+		t.Fatalf("unexpected ls output:\n%s", got)
+	}
+
+	if _, err := NewLsTool(dir).Execute("l2", map[string]any{"path": "/"}); err == nil {
+		t.Fatal("expected path escape error")
+	}
+}
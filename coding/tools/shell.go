@@ -0,0 +1,232 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shellInterruptGrace is how long an interrupted command may take to return
+// to the prompt before the whole shell is killed and restarted.
+const shellInterruptGrace = 2 * time.Second
+
+// persistentShell keeps one bash process alive across commands so that cd,
+// exported variables and activated virtualenvs carry over. Each command is
+// followed by a sentinel line carrying its exit code and the new cwd.
+type persistentShell struct {
+	mu       sync.Mutex
+	dir      string
+	executor Executor
+	cwd      string
+	proc     *shellProcess
+	closed   bool
+}
+
+type shellProcess struct {
+	stdin    io.WriteCloser
+	lines    chan string
+	sentinel string
+	done     chan struct{}
+	waitErr  error
+	signal   func(interrupt bool)
+}
+
+type shellResult struct {
+	output      string
+	exitCode    int
+	cwd         string
+	previousCwd string
+	exited      bool
+}
+
+func newPersistentShell(dir string, executor Executor) *persistentShell {
+	return &persistentShell{dir: dir, executor: executor, cwd: dir}
+}
+
+// run executes command, passing each output line to onOutput if it is set.
+func (s *persistentShell) run(ctx context.Context, command string, onOutput func(line string)) (shellResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return shellResult{}, errors.New("shell is closed")
+	}
+	if s.proc == nil {
+		proc, err := startShell(s.executor, s.dir)
+		if err != nil {
+			return shellResult{}, err
+		}
+		s.proc = proc
+		s.cwd = s.dir
+		// Drop whatever the login profile printed before the first command.
+		if _, err := s.execute(context.Background(), ":", nil); err != nil {
+			return shellResult{}, err
+		}
+		if s.proc == nil {
+			return shellResult{}, errors.New("shell exited during startup")
+		}
+	}
+	return s.execute(ctx, command, onOutput)
+}
+
+func (s *persistentShell) execute(ctx context.Context, command string, onOutput func(line string)) (shellResult, error) {
+	proc := s.proc
+	result := shellResult{cwd: s.cwd, previousCwd: s.cwd}
+
+	if _, err := io.WriteString(proc.stdin, proc.script(command)); err != nil {
+		s.stop()
+		return result, fmt.Errorf("shell exited: %w", err)
+	}
+
+	var out strings.Builder
+	var grace <-chan time.Time
+	cancelled := ctx.Done()
+	for {
+		select {
+		case line, ok := <-proc.lines:
+			if !ok {
+				<-proc.done
+				result.output = out.String()
+				result.exitCode = exitCodeOf(proc.waitErr)
+				result.exited = true
+				s.proc = nil
+				return result, nil
+			}
+			if code, cwd, ok := proc.parseSentinel(line); ok {
+				result.output = strings.TrimSuffix(out.String(), "\n")
+				result.exitCode = code
+				result.cwd = cwd
+				s.cwd = cwd
+				return result, nil
+			}
+			out.WriteString(line)
+			if onOutput != nil {
+				onOutput(line)
+			}
+		case <-cancelled:
+			cancelled = nil
+			proc.signal(true)
+			grace = time.After(shellInterruptGrace)
+		case <-grace:
+			s.stop()
+			result.output = out.String()
+			result.exited = true
+			return result, nil
+		}
+	}
+}
+
+func (s *persistentShell) currentDir() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cwd
+}
+
+// Close kills the shell. Later commands fail.
+func (s *persistentShell) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.stop()
+	return nil
+}
+
+func (s *persistentShell) stop() {
+	if s.proc == nil {
+		return
+	}
+	s.proc.stdin.Close()
+	s.proc.signal(false)
+	<-s.proc.done
+	s.proc = nil
+}
+
+func startShell(executor Executor, dir string) (*shellProcess, error) {
+	cmd, err := executor.Command(context.Background(), "exec bash --noprofile --norc", dir)
+	if err != nil {
+		return nil, err
+	}
+	setProcessGroup(cmd)
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, err
+	}
+	writer.Close()
+
+	token := make([]byte, 8)
+	_, _ = rand.Read(token)
+	proc := &shellProcess{
+		stdin:    stdin,
+		lines:    make(chan string, 64),
+		sentinel: "__PHI_DONE_" + hex.EncodeToString(token),
+		done:     make(chan struct{}),
+		signal:   func(interrupt bool) { signalProcessGroup(cmd, interrupt) },
+	}
+	go func() {
+		defer close(proc.lines)
+		buffered := bufio.NewReader(reader)
+		for {
+			line, err := buffered.ReadString('\n')
+			if line != "" {
+				proc.lines <- line
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		proc.waitErr = cmd.Wait()
+		reader.Close()
+		close(proc.done)
+	}()
+	return proc, nil
+}
+
+// script wraps command so that it cannot read the protocol from stdin, an
+// interrupt returns to the prompt instead of killing the shell, and its exit
+// status and cwd are reported on a sentinel line.
+func (p *shellProcess) script(command string) string {
+	delimiter := p.sentinel + "_CMD"
+	return "trap 'return 130 2>/dev/null' INT\n" +
+		"__phi_run() { eval \"$1\"; }\n" +
+		"IFS= read -r -d '' __phi_cmd <<'" + delimiter + "'\n" +
+		command + "\n" +
+		delimiter + "\n" +
+		"__phi_run \"$__phi_cmd\" </dev/null\n" +
+		"printf '\\n" + p.sentinel + " %d %s\\n' \"$?\" \"$PWD\"\n"
+}
+
+func (p *shellProcess) parseSentinel(line string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), p.sentinel+" ")
+	if !ok {
+		return 0, "", false
+	}
+	codeText, cwd, _ := strings.Cut(rest, " ")
+	code, err := strconv.Atoi(codeText)
+	if err != nil {
+		return 0, "", false
+	}
+	return code, cwd, true
+}
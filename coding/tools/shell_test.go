@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPersistentBashToolKeepsState(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewPersistentBashTool(dir, 10*time.Second, nil)
+	defer tool.(io.Closer).Close()
+
+	result, err := tool.Execute("b1", map[string]any{"command": "cd sub && export PHI_TEST_VAR=kept"})
+	if err != nil {
+		t.Fatalf("first command failed: %v", err)
+	}
+	subDir := filepath.Join(dir, "sub")
+	if result.Details["cwd"] != subDir || result.Details["previousCwd"] != dir {
+		t.Fatalf("expected cwd change in details, got %#v", result.Details)
+	}
+
+	result, err = tool.Execute("b2", map[string]any{"command": "echo \"$PHI_TEST_VAR $(basename \"$PWD\")\"; cat"})
+	if err != nil {
+		t.Fatalf("second command failed: %v", err)
+	}
+	if strings.TrimSpace(result.Text()) != "kept sub" {
+		t.Fatalf("state did not persist: %q", result.Text())
+	}
+	if _, ok := result.Details["previousCwd"]; ok {
+		t.Fatalf("unexpected cwd change: %#v", result.Details)
+	}
+
+	result, err = tool.Execute("b3", map[string]any{"command": "echo boom; false"})
+	if err == nil || !strings.Contains(err.Error(), "Command exited with code 1") {
+		t.Fatalf("expected exit code error, got %v", err)
+	}
+	if result.Details["exitCode"] != 1 {
+		t.Fatalf("expected exitCode in details, got %#v", result.Details)
+	}
+}
+
+func TestPersistentBashToolTimeoutInterruptsCommand(t *testing.T) {
+	tool := NewPersistentBashTool(t.TempDir(), 0, nil)
+	defer tool.(io.Closer).Close()
+
+	if _, err := tool.Execute("b1", map[string]any{"command": "PHI_TEST_VAR=survived"}); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	start := time.Now()
+	_, err := tool.Execute("b2", map[string]any{"command": "sleep 30; echo after", "timeout": 0.2})
+	if err == nil || !strings.Contains(err.Error(), "command timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("timeout did not interrupt the command")
+	}
+	result, err := tool.Execute("b3", map[string]any{"command": "echo $PHI_TEST_VAR"})
+	if err != nil || strings.TrimSpace(result.Text()) != "survived" {
+		t.Fatalf("shell did not survive the timeout: %v %q", err, result.Text())
+	}
+}
+
+func TestPersistentBashToolRestartsAfterExit(t *testing.T) {
+	tool := NewPersistentBashTool(t.TempDir(), 10*time.Second, nil)
+
+	result, err := tool.Execute("b1", map[string]any{"command": "export PHI_TEST_VAR=gone; exit 3"})
+	if err == nil || !strings.Contains(err.Error(), "Command exited with code 3") {
+		t.Fatalf("expected exit code error, got %v", err)
+	}
+	if result.Details["shellExited"] != true {
+		t.Fatalf("expected shellExited in details, got %#v", result.Details)
+	}
+	result, err = tool.Execute("b2", map[string]any{"command": "echo \"[$PHI_TEST_VAR]\""})
+	if err != nil || strings.TrimSpace(result.Text()) != "[]" {
+		t.Fatalf("expected a fresh shell, got %v %q", err, result.Text())
+	}
+
+	if err := tool.(io.Closer).Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := tool.Execute("b3", map[string]any{"command": "true"}); err == nil {
+		t.Fatal("expected commands to fail after close")
+	}
+}
@@ -7,11 +7,81 @@ const (
 	defaultMaxBytes = 50 * 1024
 )
 
+type Options struct {
+	// ReadOnlyRoots are extra directories that read, grep, find and ls may
+	// access. Writes stay confined to the working directory.
+	ReadOnlyRoots []string
+	// Executor runs bash commands; nil runs them on the host.
+	Executor Executor
+	// PersistentShell runs bash commands in one long-lived shell; close the
+	// tools (AgentSession.Close does) to stop it.
+	PersistentShell bool
+	// Artifacts keeps the full output of truncated results; nil creates a
+	// store with the default retention for this toolset, which closing the
+	// bash tool removes.
+	Artifacts *ArtifactStore
+	// FileChecks guards write and edit against unread and stale files;
+	// strict by default.
+	FileChecks FileCheckPolicy
+	// DiffContext is the number of unchanged lines around each change in the
+	// diffs write, edit and apply_patch return; 0 uses 3 and a negative value
+	// shows changed lines only.
+	DiffContext int
+	// Checkpoints records file contents before write, edit and apply_patch
+	// change them so the changes can be reverted; nil disables checkpoints.
+	Checkpoints *CheckpointStore
+	// Git adds the git_* tools, which run the git CLI in the working
+	// directory.
+	Git bool
+	// Fetch adds the fetch tool with these limits; nil leaves it out.
+	Fetch *FetchOptions
+}
+
 func NewCodingTools(cwd string) []agent.Tool {
-	return []agent.Tool{
-		NewWriteFileTool(cwd),
-		NewReadFileTool(cwd),
-		NewEditTool(cwd),
-		NewBashTool(cwd, 0),
+	return NewCodingToolsWithOptions(cwd, Options{})
+}
+
+func NewCodingToolsWithOptions(cwd string, options Options) []agent.Tool {
+	artifacts := options.Artifacts
+	if artifacts == nil {
+		artifacts = NewArtifactStore(ArtifactOptions{})
+	}
+	// Spilled output is readable like any other file.
+	paths := newPathGuard(cwd, append(append([]string{}, options.ReadOnlyRoots...), artifacts.Dir()))
+	bash := NewBashToolWithExecutor(cwd, 0, options.Executor)
+	if options.PersistentShell {
+		bash = NewPersistentBashTool(cwd, 0, options.Executor)
 	}
+	bash.(*bashTool).artifacts = artifacts
+	bash.(*bashTool).ownsArtifacts = options.Artifacts == nil
+	files := newFileTracker(options.FileChecks)
+	checkpoints := options.Checkpoints
+	if checkpoints != nil {
+		// Reverted files are back to a state the model has seen.
+		checkpoints.files = files
+	}
+	tools := append([]agent.Tool{
+		&writeFileTool{paths: paths, files: files, checkpoints: checkpoints, diffContext: options.DiffContext},
+		&readFileTool{paths: paths, artifacts: artifacts, files: files},
+		&editTool{paths: paths, files: files, checkpoints: checkpoints, diffContext: options.DiffContext},
+		&applyPatchTool{paths: paths, files: files, checkpoints: checkpoints, diffContext: options.DiffContext},
+		bash,
+		&grepTool{paths: paths, artifacts: artifacts},
+		&findTool{paths: paths, artifacts: artifacts},
+		&lsTool{paths: paths, artifacts: artifacts},
+	}, NewProcessTools(bash)...)
+	if options.Git {
+		tools = append(tools, newGitTools(&gitRunner{paths: paths, artifacts: artifacts})...)
+	}
+	if options.Fetch != nil {
+		tools = append(tools, NewFetchTool(*options.Fetch))
+	}
+	return tools
+}
+
+// standalonePaths confines tools built by the individual constructors, which
+// share the default artifact store. They have no file tracker since each
+// constructor makes an independent tool.
+func standalonePaths(cwd string) pathGuard {
+	return newPathGuard(cwd, []string{defaultArtifacts().Dir()})
 }
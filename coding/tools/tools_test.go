@@ -1,11 +1,17 @@
 package tools
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/zahlmann/phi/agent"
+	"github.com/zahlmann/phi/ai/jsonschema"
+	"github.com/zahlmann/phi/ai/model"
 )
 
 func TestCodingToolsContainMinimalSet(t *testing.T) {
@@ -14,10 +20,10 @@ func TestCodingToolsContainMinimalSet(t *testing.T) {
 	for _, tool := range toolset {
 		names[tool.Name()] = true
 	}
-	if len(toolset) != 4 {
-		t.Fatalf("expected exactly 4 tools, got %d", len(toolset))
+	if len(toolset) != 10 {
+		t.Fatalf("expected exactly 10 tools, got %d", len(toolset))
 	}
-	for _, required := range []string{"read", "write", "edit", "bash"} {
+	for _, required := range []string{"read", "write", "edit", "apply_patch", "bash", "grep", "find", "ls", "process_output", "process_kill"} {
 		if !names[required] {
 			t.Fatalf("missing required tool: %s", required)
 		}
@@ -36,7 +42,7 @@ func TestWriteAndReadFileTools(t *testing.T) {
 	if err != nil {
 		t.Fatalf("write failed: %v", err)
 	}
-	if len(writeResult.Content) == 0 || !strings.Contains(writeResult.Content[0].Text, "Successfully wrote") {
+	if len(writeResult.Content) == 0 || !strings.Contains(writeResult.Text(), "Successfully wrote") {
 		t.Fatalf("unexpected write output: %#v", writeResult.Content)
 	}
 
@@ -55,8 +61,8 @@ func TestWriteAndReadFileTools(t *testing.T) {
 	if len(result.Content) == 0 {
 		t.Fatal("expected read content")
 	}
-	if !strings.Contains(result.Content[0].Text, "print('hello')") {
-		t.Fatalf("unexpected tool output: %q", result.Content[0].Text)
+	if !strings.Contains(result.Text(), "print('hello')") {
+		t.Fatalf("unexpected tool output: %q", result.Text())
 	}
 	if result.Details == nil || result.Details["path"] != "hello.py" {
 		t.Fatalf("expected path detail, got %#v", result.Details)
@@ -94,7 +100,7 @@ func TestReadToolPagingAndBounds(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read failed: %v", err)
 	}
-	text := result.Content[0].Text
+	text := result.Text()
 	if !strings.Contains(text, "line2\nline3") {
 		t.Fatalf("unexpected paged read output: %q", text)
 	}
@@ -123,15 +129,18 @@ func TestReadToolImagePayload(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read image failed: %v", err)
 	}
-	if len(result.Content) == 0 || !strings.Contains(result.Content[0].Text, "Read image file [image/png]") {
+	if len(result.Content) == 0 || !strings.Contains(result.Text(), "Read image file [image/png]") {
 		t.Fatalf("unexpected image output: %#v", result.Content)
 	}
 	if result.Details["mimeType"] != "image/png" {
 		t.Fatalf("unexpected mime type details: %#v", result.Details)
 	}
-	image, ok := result.Details["image"].(map[string]any)
-	if !ok || strings.TrimSpace(image["data"].(string)) == "" {
-		t.Fatalf("expected base64 image details, got %#v", result.Details["image"])
+	if len(result.Content) != 2 {
+		t.Fatalf("expected text and image content, got %#v", result.Content)
+	}
+	image, ok := result.Content[1].(model.ImageContent)
+	if !ok || image.MIMEType != "image/png" || strings.TrimSpace(image.Data) == "" {
+		t.Fatalf("expected base64 image content, got %#v", result.Content[1])
 	}
 }
 
@@ -153,8 +162,8 @@ func TestReadToolFirstLineExceedsMaxBytes(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read failed: %v", err)
 	}
-	if !strings.Contains(result.Content[0].Text, "exceeds 8B limit") {
-		t.Fatalf("expected max-bytes warning, got %q", result.Content[0].Text)
+	if !strings.Contains(result.Text(), "exceeds 8B limit") {
+		t.Fatalf("expected max-bytes warning, got %q", result.Text())
 	}
 }
 
@@ -190,8 +199,8 @@ func TestEditTool(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read failed: %v", err)
 	}
-	if !strings.Contains(readResult.Content[0].Text, "hello world") {
-		t.Fatalf("unexpected content after edit: %q", readResult.Content[0].Text)
+	if !strings.Contains(readResult.Text(), "hello world") {
+		t.Fatalf("unexpected content after edit: %q", readResult.Text())
 	}
 }
 
@@ -236,8 +245,8 @@ func TestBashTool(t *testing.T) {
 	if len(result.Content) == 0 {
 		t.Fatal("expected bash output")
 	}
-	if strings.TrimSpace(result.Content[0].Text) != "test-output" {
-		t.Fatalf("unexpected bash output: %q", result.Content[0].Text)
+	if strings.TrimSpace(result.Text()) != "test-output" {
+		t.Fatalf("unexpected bash output: %q", result.Text())
 	}
 }
 
@@ -249,8 +258,8 @@ func TestBashToolReturnsExitCodeError(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "Command exited with code 7") {
 		t.Fatalf("expected exit code error, got %v", err)
 	}
-	if !strings.Contains(result.Content[0].Text, "boom") {
-		t.Fatalf("expected command output in result, got %q", result.Content[0].Text)
+	if !strings.Contains(result.Text(), "boom") {
+		t.Fatalf("expected command output in result, got %q", result.Text())
 	}
 }
 
@@ -263,6 +272,38 @@ func TestBashToolTimeout(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "command timed out") {
 		t.Fatalf("expected timeout error, got %v", err)
 	}
+	// A zero timeout means the tool default and passes argument validation.
+	if err := jsonschema.Validate(bashTool.Parameters(), map[string]any{"command": "true", "timeout": 0}); err != nil {
+		t.Fatalf("expected zero timeout to be valid, got %v", err)
+	}
+}
+
+func TestBashToolStreamsOutput(t *testing.T) {
+	bashTool := NewBashTool(t.TempDir(), 10*time.Second).(agent.StreamingTool)
+	var mu sync.Mutex
+	deltas := map[string]string{}
+	lastTail := ""
+	result, err := bashTool.ExecuteStreaming(context.Background(), "s", map[string]any{
+		"command": "echo out-1; echo err-1 >&2; echo out-2",
+	}, func(partial agent.ToolResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		stream, _ := partial.Details["stream"].(string)
+		delta, _ := partial.Details["delta"].(string)
+		deltas[stream] += delta
+		lastTail = partial.Text()
+	})
+	if err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+	if !strings.HasSuffix(deltas["stdout"], "out-1\nout-2\n") || !strings.HasSuffix(deltas["stderr"], "err-1\n") {
+		t.Fatalf("unexpected streamed output: %#v", deltas)
+	}
+	for _, line := range []string{"out-1", "err-1", "out-2"} {
+		if !strings.Contains(lastTail, line) || !strings.Contains(result.Text(), line) {
+			t.Fatalf("missing %q in tail %q or result %q", line, lastTail, result.Text())
+		}
+	}
 }
 
 func TestBashToolTruncationSavesFullOutput(t *testing.T) {
@@ -275,7 +316,7 @@ func TestBashToolTruncationSavesFullOutput(t *testing.T) {
 	if err != nil {
 		t.Fatalf("bash failed: %v", err)
 	}
-	text := result.Content[0].Text
+	text := result.Text()
 	if !strings.Contains(text, "Showing lines") {
 		t.Fatalf("expected truncation notice, got: %q", text)
 	}
@@ -288,3 +329,160 @@ func TestBashToolTruncationSavesFullOutput(t *testing.T) {
 		t.Fatalf("expected full output file to exist at %s: %v", fullPath, err)
 	}
 }
+
+func TestEditToolMultipleEditsAreAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.txt")
+	if err := os.WriteFile(path, []byte("name=a\nport=1\nhost=x\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	tool := NewEditTool(dir)
+
+	_, err := tool.Execute("e1", map[string]any{
+		"path": "config.txt",
+		"edits": []any{
+			map[string]any{"oldText": "name=a", "newText": "name=b"},
+			map[string]any{"oldText": "missing", "newText": "x"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "edit 2: could not find exact text") {
+		t.Fatalf("expected failing second edit, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "name=a\nport=1\nhost=x\n" {
+		t.Fatalf("file changed after failed multi-edit: %q", data)
+	}
+
+	result, err := tool.Execute("e2", map[string]any{
+		"path": "config.txt",
+		"edits": []any{
+			map[string]any{"oldText": "name=a", "newText": "name=b"},
+			map[string]any{"oldText": "port=1", "newText": "port=2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("multi-edit failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "name=b\nport=2\nhost=x\n" {
+		t.Fatalf("unexpected content: %q", data)
+	}
+	if !strings.Contains(result.Text(), "applied 2 edits") {
+		t.Fatalf("unexpected summary: %q", result.Text())
+	}
+}
+
+func TestEditToolReplaceAll(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("foo bar foo\r\nfoo\r\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	result, err := NewEditTool(dir).Execute("e", map[string]any{
+		"path":       "a.txt",
+		"oldText":    "foo",
+		"newText":    "baz",
+		"replaceAll": true,
+	})
+	if err != nil {
+		t.Fatalf("replaceAll failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "baz bar baz\r\nbaz\r\n" {
+		t.Fatalf("unexpected content: %q", data)
+	}
+	edits := result.Details["edits"].([]map[string]any)
+	if edits[0]["replacements"] != 3 || edits[0]["strategy"] != "exact" {
+		t.Fatalf("unexpected edit details: %#v", edits)
+	}
+}
+
+func TestFindQuoteInsensitiveMapsOffsets(t *testing.T) {
+	content := "é “a” — ü\n“a” — x “a”—\n"
+	spans := findQuoteInsensitive(content, `"a" -`)
+	got := []string{}
+	for _, span := range spans {
+		got = append(got, content[span.start:span.end])
+	}
+	if strings.Join(got, "|") != "“a” —|“a” —" {
+		t.Fatalf("unexpected matches: %q", got)
+	}
+	if spans := findQuoteInsensitive(content, `"a"-`); len(spans) != 1 || content[spans[0].start:spans[0].end] != "“a”—" {
+		t.Fatalf("unexpected match at end of line: %#v", spans)
+	}
+}
+
+func TestEditToolFuzzyMatching(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		oldText  string
+		newText  string
+		want     string
+		strategy string
+	}{
+		{
+			name:     "unicode quotes",
+			content:  "msg := “hello” – world\n",
+			oldText:  `msg := "hello" - world`,
+			newText:  `msg := "goodbye"`,
+			want:     "msg := \"goodbye\"\n",
+			strategy: "quotes",
+		},
+		{
+			name:     "indentation",
+			content:  "func f() {\n\t\tif x {\n\t\t\treturn 1\n\t\t}\n}\n",
+			oldText:  "if x {\n    return 1\n}",
+			newText:  "if x {\n    return 2\n}",
+			want:     "func f() {\n\t\tif x {\n\t\t\treturn 2\n\t\t}\n}\n",
+			strategy: "whitespace",
+		},
+		{
+			name:     "trailing whitespace",
+			content:  "a  \nb\t\nc\n",
+			oldText:  "a\nb",
+			newText:  "a\nB",
+			want:     "a\nB\nc\n",
+			strategy: "whitespace",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "f.txt")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+			result, err := NewEditTool(dir).Execute("e", map[string]any{
+				"path":    "f.txt",
+				"oldText": tc.oldText,
+				"newText": tc.newText,
+			})
+			if err != nil {
+				t.Fatalf("fuzzy edit failed: %v", err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != tc.want {
+				t.Fatalf("unexpected content: %q", data)
+			}
+			if result.Details["usedFuzzyMatch"] != true {
+				t.Fatalf("expected fuzzy match flag, got %#v", result.Details)
+			}
+			if got := result.Details["edits"].([]map[string]any)[0]["strategy"]; got != tc.strategy {
+				t.Fatalf("expected strategy %s, got %v", tc.strategy, got)
+			}
+		})
+	}
+}
+
+func TestEditToolRefusesAmbiguousFuzzyMatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte("  x = 1\n\tx = 1\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	_, err := NewEditTool(dir).Execute("e", map[string]any{
+		"path":       "f.txt",
+		"oldText":    "x  =  1",
+		"newText":    "x = 2",
+		"replaceAll": true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous fuzzy match error, got %v", err)
+	}
+}
@@ -11,11 +11,14 @@ import (
 )
 
 type writeFileTool struct {
-	cwd string
+	paths       pathGuard
+	files       *fileTracker
+	checkpoints *CheckpointStore
+	diffContext int
 }
 
 func NewWriteFileTool(cwd string) agent.Tool {
-	return &writeFileTool{cwd: defaultCWD(cwd)}
+	return &writeFileTool{paths: standalonePaths(cwd)}
 }
 
 func (t *writeFileTool) Name() string {
@@ -53,27 +56,42 @@ func (t *writeFileTool) Execute(toolCallID string, args map[string]any) (agent.T
 		return agent.ToolResult{}, fmt.Errorf("missing required argument: content")
 	}
 
-	target, err := resolveSafePath(t.cwd, path)
+	target, err := t.paths.resolve(path, accessWrite)
 	if err != nil {
 		return agent.ToolResult{}, err
 	}
+	previous, readErr := os.ReadFile(target)
+	existed := readErr == nil
+	if existed {
+		if err := t.files.check(target, path, previous, true); err != nil {
+			return agent.ToolResult{}, err
+		}
+	}
+	if err := t.checkpoints.capture(toolCallID, target); err != nil {
+		return agent.ToolResult{}, err
+	}
 	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
 		return agent.ToolResult{}, err
 	}
 	if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
 		return agent.ToolResult{}, err
 	}
+	t.files.record(target, []byte(content))
 
+	details := map[string]any{}
+	if existed {
+		details = generateDiff(path, string(previous), content, contextLines(t.diffContext)).toMap()
+	}
+	details["path"] = path
+	details["size"] = len(content)
+	details["overwritten"] = existed
 	return agent.ToolResult{
-		Content: []model.TextContent{
-			{
+		Content: []any{
+			model.TextContent{
 				Type: model.ContentText,
 				Text: fmt.Sprintf("Successfully wrote %d bytes to %s", len(content), path),
 			},
 		},
-		Details: map[string]any{
-			"path": path,
-			"size": len(content),
-		},
+		Details: details,
 	}, nil
 }